@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// pipelineActions 数据管道控制子命令
+var pipelineActions = map[string]string{
+	"status":        http.MethodGet,
+	"pause-forward": http.MethodPost,
+	"pause-ingest":  http.MethodPost,
+	"resume":        http.MethodPost,
+	"drain":         http.MethodPost,
+}
+
+var (
+	pipelineURL     string
+	pipelineUser    string
+	pipelineWait    bool
+	pipelineTimeout time.Duration
+)
+
+// pipelineCmd 通过REST接口控制运行中服务的数据管道
+var pipelineCmd = &cobra.Command{
+	Use:       "pipeline [status|pause-forward|pause-ingest|resume|drain]",
+	Short:     "show or change pipeline state of a running arc-consumer",
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"status", "pause-forward", "pause-ingest", "resume", "drain"},
+	RunE:      runPipeline,
+}
+
+func init() {
+	pipelineCmd.Flags().StringVar(&pipelineURL, "url", "http://127.0.0.1:8082/api/data/v1/consumer", "api root of arc-consumer")
+	pipelineCmd.Flags().StringVar(&pipelineUser, "user", "", "operator name recorded in audit log")
+	pipelineCmd.Flags().BoolVar(&pipelineWait, "wait", false, "with drain, wait until pipeline stopped")
+	pipelineCmd.Flags().DurationVar(&pipelineTimeout, "timeout", 5*time.Minute, "max time to wait for drain")
+	rootCmd.AddCommand(pipelineCmd)
+}
+
+// pipelineResponse 数据管道接口返回
+type pipelineResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+func runPipeline(cmd *cobra.Command, args []string) error {
+	action := args[0]
+	path := "/pipeline"
+	if action != "status" {
+		path += "/" + action
+	}
+	body, err := pipelineRequest(pipelineActions[action], path)
+	if err != nil {
+		return err
+	}
+	fmt.Println(body)
+	if action != "drain" || !pipelineWait {
+		return nil
+	}
+
+	// 等待排空完成
+	deadline := time.Now().Add(pipelineTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		body, err = pipelineRequest(http.MethodGet, "/pipeline")
+		if err != nil {
+			return err
+		}
+		var status struct {
+			State string `json:"state"`
+		}
+		if err = json.Unmarshal([]byte(body), &status); err != nil {
+			return err
+		}
+		if status.State == "stopped" {
+			fmt.Println(body)
+			return nil
+		}
+	}
+	return fmt.Errorf("pipeline not drained in %s", pipelineTimeout)
+}
+
+// pipelineRequest 调用数据管道接口，返回data字段
+func pipelineRequest(method, path string) (string, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(pipelineURL, "/")+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if pipelineUser != "" {
+		req.Header.Set("X-User", pipelineUser)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var r pipelineResponse
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return "", fmt.Errorf("%s: %s", resp.Status, raw)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", resp.Status, r.Msg)
+	}
+	return string(r.Data), nil
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// headerUser 调用方用户名，由网关在认证后设置
+const headerUser = "X-User"
+
+// anonymous 无法确认调用方时的操作人
+const anonymous = "anonymous"
+
+// operator 调用方标识，只信任可信网关设置的用户头
+// 其他来源的用户头和Basic认证用户名未经校验，只作为声明的用户名返回
+// @param c echo.Context 请求上下文
+// @return user string 已认证的用户名，无法确认时为anonymous
+// @return claimed string 未经校验的声明用户名，没有时为空
+func (s *Server) operator(c echo.Context) (user, claimed string) {
+	r := c.Request()
+	claimed = r.Header.Get(headerUser)
+	if claimed != "" && s.config != nil {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if s.config.trusted(net.ParseIP(host)) {
+			return claimed, ""
+		}
+	}
+	if claimed == "" {
+		claimed, _, _ = r.BasicAuth()
+	}
+	return anonymous, claimed
+}
+
+// audit 记录控制接口调用的审计日志，未经校验的用户名记录为 claimed_operator
+// @param c echo.Context 请求上下文
+// @param action string 操作
+// @param keysAndValues ...interface{} 操作参数
+func (s *Server) audit(c echo.Context, action string, keysAndValues ...interface{}) {
+	user, claimed := s.operator(c)
+	kv := []interface{}{
+		"action", action,
+		"operator", user,
+	}
+	if claimed != "" {
+		kv = append(kv, "claimed_operator", claimed)
+	}
+	kv = append(kv,
+		"ip", c.RealIP(),
+		"user_agent", c.Request().UserAgent(),
+	)
+	s.logger.Infow("audit", append(kv, keysAndValues...)...)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestOperator(t *testing.T) {
+	s := &Server{config: &Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5"}}}
+	e := echo.New()
+	cases := []struct {
+		name    string
+		remote  string
+		user    string
+		basic   string
+		want    string
+		claimed string
+	}{
+		{"gateway", "10.1.2.3:5000", "alice", "", "alice", ""},
+		{"gateway ip", "192.168.1.5:5000", "alice", "", "alice", ""},
+		{"untrusted header", "192.168.1.6:5000", "alice", "", anonymous, "alice"},
+		{"basic auth", "10.1.2.3:5000", "", "bob", anonymous, "bob"},
+		{"nobody", "10.1.2.3:5000", "", "", anonymous, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/registry/94C96000C248/approve", nil)
+			r.RemoteAddr = c.remote
+			if c.user != "" {
+				r.Header.Set(headerUser, c.user)
+			}
+			if c.basic != "" {
+				r.SetBasicAuth(c.basic, "secret")
+			}
+			user, claimed := s.operator(e.NewContext(r, httptest.NewRecorder()))
+			if user != c.want || claimed != c.claimed {
+				t.Fatalf("got %q/%q, want %q/%q", user, claimed, c.want, c.claimed)
+			}
+		})
+	}
+	if err := (&Config{TrustedProxies: []string{"gateway"}}).Validate(); err == nil {
+		t.Fatal("invalid trusted proxy should be rejected")
+	}
+}
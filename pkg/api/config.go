@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyLiveOrigins    = "api.live_origins"
+	keyTrustedProxies = "api.trusted_proxies"
+)
+
+// 配置默认值
+var defaultConfig = Config{
+	LiveOrigins:    []string{},
+	TrustedProxies: []string{},
+}
+
+// Config - 接口配置
+type Config struct {
+	// LiveOrigins 实时波形websocket允许的跨站来源，如 "https://ops.example.com"，"*" 允许全部
+	// 同源和不带Origin的非浏览器客户端总是允许
+	LiveOrigins []string `toml:"live_origins" json:"live_origins"`
+	// TrustedProxies 可信网关地址(IP或CIDR)，只有来自可信网关的 X-User 用户头作为已认证的操作人
+	TrustedProxies []string `toml:"trusted_proxies" json:"trusted_proxies"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyLiveOrigins, defaultConfig.LiveOrigins)
+	viper.SetDefault(keyTrustedProxies, defaultConfig.TrustedProxies)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		LiveOrigins:    viper.GetStringSlice(keyLiveOrigins),
+		TrustedProxies: viper.GetStringSlice(keyTrustedProxies),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	for _, origin := range c.LiveOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s invalid origin %q, want scheme://host[:port]", keyLiveOrigins, origin)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, err := parseNetwork(proxy); err != nil {
+			return fmt.Errorf("%s: %v", keyTrustedProxies, err)
+		}
+	}
+	return nil
+}
+
+// trusted 地址是否为可信网关
+func (c *Config) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range c.TrustedProxies {
+		if n, err := parseNetwork(proxy); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork 解析IP或CIDR
+func parseNetwork(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip or cidr %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// EventsSinkType 事件推送转发目标类型
+const EventsSinkType = "sse"
+
+const (
+	// eventsHistory 保留的历史事件数，用于 Last-Event-ID 续传
+	eventsHistory = 1024
+	// eventsSendBuffer 每个客户端待发送事件数，满时丢弃
+	eventsSendBuffer = 256
+	// eventsKeepalive 空闲时发送注释行，防止代理断开
+	eventsKeepalive = 15 * time.Second
+)
+
+// sseEvent 已编码的事件
+type sseEvent struct {
+	id   uint64
+	typ  string
+	data []byte
+}
+
+// eventsClient SSE客户端
+type eventsClient struct {
+	types   map[string]struct{} // 为空不过滤
+	send    chan *sseEvent
+	dropped *atomic.Int64
+}
+
+// match 事件类型过滤
+func (c *eventsClient) match(typ string) bool {
+	if len(c.types) == 0 {
+		return true
+	}
+	_, ok := c.types[typ]
+	return ok
+}
+
+// Events - 通过Server-Sent Events推送电弧事件、传感器上下线和grpc连接状态变化
+// 作为转发目标接收数据包和事件，保留有限历史供断线续传
+type Events struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []*sseEvent // 环形缓存
+	next    int
+	clients map[*eventsClient]struct{}
+	logger  logging.ILogger
+	dropped *atomic.Int64
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewEvents - 创建事件推送
+// @param logger logging.ILogger 日志
+// @return *Events 事件推送
+func NewEvents(logger logging.ILogger) *Events {
+	if logger == nil {
+		logger = new(logging.NoopLogger)
+	}
+	e := &Events{
+		history: make([]*sseEvent, 0, eventsHistory),
+		clients: map[*eventsClient]struct{}{},
+		logger:  logger,
+		dropped: atomic.NewInt64(0),
+		done:    make(chan struct{}),
+	}
+	return e
+}
+
+// Publish - 发布事件，不阻塞
+func (e *Events) Publish(ev *event.Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		e.logger.Warnw("marshal event", "err", err, "type", ev.Type)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.publish(ev.Type, data)
+}
+
+// publish 调用方持有锁
+func (e *Events) publish(typ string, data []byte) {
+	e.seq++
+	se := &sseEvent{id: e.seq, typ: typ, data: data}
+	if len(e.history) < eventsHistory {
+		e.history = append(e.history, se)
+	} else {
+		e.history[e.next] = se
+		e.next = (e.next + 1) % eventsHistory
+	}
+	for c := range e.clients {
+		if !c.match(typ) {
+			continue
+		}
+		select {
+		case c.send <- se:
+		default:
+			c.dropped.Inc()
+			e.dropped.Inc()
+		}
+	}
+}
+
+// since 返回编号大于id且匹配类型的历史事件，调用方持有锁
+func (e *Events) since(id uint64, c *eventsClient) []*sseEvent {
+	var list []*sseEvent
+	for i := 0; i < len(e.history); i++ {
+		se := e.history[(e.next+i)%len(e.history)]
+		if se.id > id && c.match(se.typ) {
+			list = append(list, se)
+		}
+	}
+	return list
+}
+
+// Write - 数据包不推送，传感器上下线由 liveness 检测后作为事件转发
+func (e *Events) Write(f *sink.Frame) error {
+	return nil
+}
+
+// WriteEvent - 转发电弧等事件
+func (e *Events) WriteEvent(ev *event.Event) error {
+	e.Publish(ev)
+	return nil
+}
+
+// Flush -
+func (e *Events) Flush() error {
+	return nil
+}
+
+// Close - 断开全部客户端
+func (e *Events) Close() error {
+	e.once.Do(func() {
+		close(e.done)
+	})
+	return nil
+}
+
+// Health - 推送始终可用
+func (e *Events) Health() sink.Health {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return sink.Health{Healthy: true, Message: fmt.Sprintf("clients %d, dropped %d", len(e.clients), e.dropped.Load())}
+}
+
+// subscribe 注册客户端并返回需要补发的历史事件，在同一把锁内完成，保证不漏发
+func (e *Events) subscribe(c *eventsClient, lastID uint64, resume bool) []*sseEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clients[c] = struct{}{}
+	if !resume {
+		return nil
+	}
+	return e.since(lastID, c)
+}
+
+// unsubscribe 客户端断开
+func (e *Events) unsubscribe(c *eventsClient) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.clients, c)
+}
+
+// setupEvents - 事件推送接口
+func (s *Server) setupEvents(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Events", base+"/events")
+
+	g.GET("", s.getEvents).
+		AddParamQuery("", "types", "comma separated event types: arc, sensor_online, sensor_stale, sensor_offline, grpc_state", false).
+		AddParamQuery("", "last_event_id", "resume after this event id, same as Last-Event-ID header", false).
+		SetResponseContentType("text/event-stream").
+		SetOperationId("getEvents").
+		SetSummary("server-sent events of arc events, sensor status and grpc connection state")
+}
+
+// getEvents - SSE事件流
+func (s *Server) getEvents(c echo.Context) error {
+	if s.events == nil {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  "event stream is disabled",
+		})
+	}
+
+	client := &eventsClient{
+		types:   map[string]struct{}{},
+		send:    make(chan *sseEvent, eventsSendBuffer),
+		dropped: atomic.NewInt64(0),
+	}
+	if v := c.QueryParam("types"); v != "" {
+		for _, typ := range strings.Split(v, ",") {
+			if typ = strings.TrimSpace(typ); typ != "" {
+				client.types[typ] = struct{}{}
+			}
+		}
+	}
+	lastID := c.Request().Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.QueryParam("last_event_id")
+	}
+	var last uint64
+	resume := lastID != ""
+	if resume {
+		var err error
+		if last, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+				Code: http.StatusBadRequest,
+				Msg:  "invalid last event id",
+			})
+		}
+	}
+
+	backlog := s.events.subscribe(client, last, resume)
+	defer s.events.unsubscribe(client)
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, se := range backlog {
+		if err := writeSSE(w, se); err != nil {
+			return nil
+		}
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(eventsKeepalive)
+	defer ticker.Stop()
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.events.done:
+			return nil
+		case se := <-client.send:
+			if err := writeSSE(w, se); err != nil {
+				return nil
+			}
+			w.Flush()
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+	}
+}
+
+// writeSSE 按SSE格式写出一条事件
+func writeSSE(w *echo.Response, se *sseEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", se.id, se.typ, se.data)
+	return err
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// readSSE 读取n条事件，返回 id 和 event 字段
+func readSSE(t *testing.T, r *bufio.Reader, n int) [][2]string {
+	var list [][2]string
+	var cur [2]string
+	for len(list) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			cur[0] = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur[1] = strings.TrimPrefix(line, "event: ")
+		case line == "" && cur[0] != "":
+			list = append(list, cur)
+			cur = [2]string{}
+		}
+	}
+	return list
+}
+
+func TestEvents(t *testing.T) {
+	events := NewEvents(nil)
+	defer events.Close()
+	e := echo.New()
+	s := &Server{events: events}
+	e.GET("/events", s.getEvents)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	// 上线、电弧、离线，编号 1..3，数据包不产生事件
+	if err := events.Write(&sink.Frame{ID: 1, SID: "94C96000C248"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{event.TypeSensorOnline, event.TypeArc, event.TypeSensorOffline} {
+		if err := events.WriteEvent(&event.Event{Type: typ, ID: 1, SID: "94C96000C248"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?types=arc,sensor_offline", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %s", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	got := readSSE(t, r, 2)
+	if got[0] != [2]string{"2", "arc"} || got[1] != [2]string{"3", "sensor_offline"} {
+		t.Fatalf("unexpected replay %v", got)
+	}
+
+	// 注册后的实时事件，不匹配的类型被过滤
+	events.Publish(&event.Event{Type: event.TypeGrpcState})
+	events.Publish(&event.Event{Type: event.TypeArc})
+	if got := readSSE(t, r, 1); got[0] != [2]string{"5", "arc"} {
+		t.Fatalf("unexpected live event %v", got)
+	}
+
+	resp2, err := http.Get(srv.URL + "/events?last_event_id=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status %d", resp2.StatusCode)
+	}
+}
+
+func TestEventsHistory(t *testing.T) {
+	events := NewEvents(nil)
+	defer events.Close()
+	for i := 0; i < eventsHistory+10; i++ {
+		events.Publish(&event.Event{Type: event.TypeArc})
+	}
+	list := events.since(0, &eventsClient{})
+	if len(list) != eventsHistory || list[0].id != 11 || list[len(list)-1].id != eventsHistory+10 {
+		t.Fatalf("unexpected history %d %d", len(list), list[0].id)
+	}
+	if list = events.since(eventsHistory+8, &eventsClient{}); len(list) != 2 {
+		t.Fatalf("unexpected resume %d", len(list))
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+)
+
+// setupGrpc - grpc连接池接口
+func (s *Server) setupGrpc(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Grpc", base+"/grpc")
+
+	g.GET("/pools", s.getPools).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []grpc.PoolStatus{}}, nil).
+		SetOperationId("getGrpcPools").
+		SetSummary("get state, last error and reconnect count of each grpc pool")
+
+	g.GET("/compression", s.getCompression).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []grpc.CompressionStat{}}, nil).
+		SetOperationId("getGrpcCompression").
+		SetSummary("get compression ratio of frames sent to arc-storage per sensor")
+
+	g.POST("/reconnect", s.reconnectGrpc).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []grpc.PoolStatus{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusConflict, "", utils.ResponseV2{}, nil).
+		SetOperationId("reconnectGrpc").
+		SetSummary("reload grpc config, rebuild every pool on next write and resume sending, resumes a forward-paused pipeline")
+
+	g.POST("/disconnect", s.disconnectGrpc).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []grpc.PoolStatus{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusConflict, "", utils.ResponseV2{}, nil).
+		SetOperationId("disconnectGrpc").
+		SetSummary("pause pipeline forwarding and disconnect arc-storage, frames stay buffered in shard queues until reconnect")
+
+	g.PUT("/server", s.setGrpcServer).
+		AddParamBody(GrpcServerRequest{}, "body", "new arc-storage address", true).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []grpc.PoolStatus{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("setGrpcServer").
+		SetSummary("change arc-storage address at runtime, every pool reconnects on next write")
+}
+
+// GrpcServerRequest - 修改arc-storage地址请求
+type GrpcServerRequest struct {
+	Server string `json:"server" example:"arc-storage:8080"`
+}
+
+// grpcDisabled - 未开启grpc转发
+func grpcDisabled(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, utils.ResponseV2{
+		Code: http.StatusNotFound,
+		Msg:  "grpc not enabled",
+	})
+}
+
+// getPools - 获取grpc连接池状态
+func (s *Server) getPools(c echo.Context) error {
+	pools := []grpc.PoolStatus{}
+	if s.grpc != nil {
+		pools = s.grpc.Status()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: pools,
+	})
+}
+
+// getCompression - 获取每个传感器的压缩统计
+func (s *Server) getCompression(c echo.Context) error {
+	stats := []grpc.CompressionStat{}
+	if s.grpc != nil {
+		stats = s.grpc.Compression()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: stats,
+	})
+}
+
+// reconnectGrpc - 重新加载配置并重建连接，数据管道暂停转发时恢复转发
+func (s *Server) reconnectGrpc(c echo.Context) error {
+	if s.grpc == nil {
+		return grpcDisabled(c)
+	}
+	s.audit(c, "grpc.reconnect")
+	// 恢复转发时由管道状态回调重连
+	if s.simulate != nil && s.simulate.State() == simulate.StateForwardPaused {
+		if err := s.simulate.Resume(); err != nil {
+			return pipelineError(c, err)
+		}
+		return s.getPools(c)
+	}
+	s.grpc.ReConnect()
+	return s.getPools(c)
+}
+
+// disconnectGrpc - 暂停数据管道转发后断开，数据包缓存在管道中不丢失
+func (s *Server) disconnectGrpc(c echo.Context) error {
+	if s.grpc == nil {
+		return grpcDisabled(c)
+	}
+	s.audit(c, "grpc.disconnect")
+	// 暂停转发时由管道状态回调断开
+	if s.simulate != nil && s.simulate.State().Forwarding() {
+		if err := s.simulate.PauseForward(); err != nil {
+			return pipelineError(c, err)
+		}
+		return s.getPools(c)
+	}
+	s.grpc.Disconnect()
+	return s.getPools(c)
+}
+
+// setGrpcServer - 修改arc-storage地址
+func (s *Server) setGrpcServer(c echo.Context) error {
+	if s.grpc == nil {
+		return grpcDisabled(c)
+	}
+	req := new(GrpcServerRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  err.Error(),
+		})
+	}
+	s.audit(c, "grpc.set_server", "server", req.Server)
+	if err := s.grpc.SetServer(req.Server); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  err.Error(),
+		})
+	}
+	return s.getPools(c)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/labstack/echo/v4"
+
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+)
+
+func TestDisconnectGrpc(t *testing.T) {
+	grpc.SetDefaultConfig()
+	c := grpc.GetConfig()
+	c.Enable = true
+	g := grpc.New(grpc.WithConfig(c))
+	g.ReConnect()
+	// 管道状态回调断开和重连，与组件一致
+	h, err := simulate.New(
+		simulate.WithConfig(&simulate.Config{GoroutineCount: 1}),
+		simulate.WithStateListener(func(from, to simulate.State) {
+			switch {
+			case from.Forwarding() && !to.Forwarding():
+				g.Disconnect()
+			case !from.Forwarding() && to.Forwarding():
+				g.ReConnect()
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &Config{}, logger: new(logging.NoopLogger), grpc: g, simulate: h}
+	e := echo.New()
+	e.POST("/grpc/disconnect", s.disconnectGrpc)
+	e.POST("/grpc/reconnect", s.reconnectGrpc)
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+
+	// 断开时暂停管道转发，写入返回错误而不是静默丢弃
+	if code := post("/grpc/disconnect"); code != http.StatusOK {
+		t.Fatalf("disconnect status %d", code)
+	}
+	if h.State() != simulate.StateForwardPaused {
+		t.Fatalf("pipeline state %s, want forward_paused", h.State())
+	}
+	if err := g.Write(0, "0", nil); err != grpc.ErrDisconnected {
+		t.Fatalf("write after disconnect: %v", err)
+	}
+	if code := post("/grpc/disconnect"); code != http.StatusOK {
+		t.Fatalf("repeated disconnect status %d", code)
+	}
+
+	// 重连时恢复转发
+	if code := post("/grpc/reconnect"); code != http.StatusOK || h.State() != simulate.StateRunning {
+		t.Fatalf("reconnect status %d, state %s", code, h.State())
+	}
+
+	// 排空中不允许断开
+	if err := h.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	if code := post("/grpc/disconnect"); code != http.StatusConflict {
+		t.Fatalf("disconnect while draining status %d, want %d", code, http.StatusConflict)
+	}
+}
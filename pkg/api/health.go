@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/health"
+)
+
+// setupHealth - 存活与就绪检查接口，供Kubernetes探针和负载均衡使用，不带路由前缀
+func (s *Server) setupHealth(root echoswagger.ApiRoot) {
+	g := root.Group("Health", "")
+
+	g.GET("/healthz", s.getLiveness).
+		AddResponse(http.StatusOK, "", health.Report{}, nil).
+		AddResponse(http.StatusServiceUnavailable, "", health.Report{}, nil).
+		SetOperationId("getLiveness").
+		SetSummary("liveness check with a breakdown of each check")
+
+	g.GET("/readyz", s.getReadiness).
+		AddResponse(http.StatusOK, "", health.Report{}, nil).
+		AddResponse(http.StatusServiceUnavailable, "", health.Report{}, nil).
+		SetOperationId("getReadiness").
+		SetSummary("readiness check: receive service, shard saturation, arc-storage connection and gossip cluster")
+}
+
+// getLiveness - 存活检查
+func (s *Server) getLiveness(c echo.Context) error {
+	return reportJSON(c, s.checker().Liveness())
+}
+
+// getReadiness - 就绪检查
+func (s *Server) getReadiness(c echo.Context) error {
+	return reportJSON(c, s.checker().Readiness())
+}
+
+// checker 未配置检查时全部通过
+func (s *Server) checker() *health.Checker {
+	if s.health == nil {
+		return health.New()
+	}
+	return s.health
+}
+
+// reportJSON 检查通过返回200，否则返回503
+func reportJSON(c echo.Context, r *health.Report) error {
+	code := http.StatusOK
+	if !r.Healthy() {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, r)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+)
+
+// setupLimit - 接收限流接口
+func (s *Server) setupLimit(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Limit", base+"/limits")
+
+	g.GET("", s.getLimits).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []limit.SensorStatus{}}, nil).
+		SetOperationId("getLimits").
+		SetSummary("list sensors that exceeded their rate limit with dropped frame count")
+
+	g.POST("/:sid/unblock", s.unblockLimit).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("unblockLimit").
+		SetSummary("accept frames again from a sensor blocked by the block or disconnect policy")
+}
+
+// getLimits - 获取超限传感器
+func (s *Server) getLimits(c echo.Context) error {
+	list := []limit.SensorStatus{}
+	if s.limiter != nil {
+		list = s.limiter.Status()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
+
+// unblockLimit - 解除传感器拒绝
+func (s *Server) unblockLimit(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	s.audit(c, "limit.unblock", "sensor", sid)
+	if s.limiter == nil || !s.limiter.Unblock(sid) {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  fmt.Sprintf("sensor %s not blocked", sid),
+		})
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+	})
+}
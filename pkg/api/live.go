@@ -0,0 +1,372 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kiga-hub/arc/logging"
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// LiveSinkType 实时波形推送转发目标类型
+const LiveSinkType = "websocket"
+
+const (
+	// liveSendBuffer 每个客户端待发送消息数，满时丢弃新数据
+	liveSendBuffer = 64
+	// liveWriteTimeout 单条消息写超时
+	liveWriteTimeout = 5 * time.Second
+	// livePingInterval 心跳间隔
+	livePingInterval = 30 * time.Second
+	// liveMaxDecimate 最大抽取倍数
+	liveMaxDecimate = 1024
+)
+
+// LiveRequest - 客户端订阅消息
+type LiveRequest struct {
+	// Action subscribe 追加订阅, unsubscribe 取消订阅
+	Action string   `json:"action"`
+	SIDs   []string `json:"sids"`
+	// Decimate 每N个采样时刻保留一个，0不修改
+	Decimate int `json:"decimate,omitempty"`
+}
+
+// LiveFrame - 推送的波形数据
+type LiveFrame struct {
+	SID        string  `json:"sid"`
+	Timestamp  int64   `json:"timestamp"`   // Frame时间戳(毫秒)
+	SampleRate int     `json:"sample_rate"` // 抽取后的采样率
+	Channels   int     `json:"channels"`
+	Data       []int32 `json:"data"` // 多通道交错存放
+}
+
+// LiveStatus - 实时推送客户端状态
+type LiveStatus struct {
+	Addr     string   `json:"addr"`
+	SIDs     []string `json:"sids"`
+	Decimate int      `json:"decimate"`
+	Sent     int64    `json:"sent"`
+	Dropped  int64    `json:"dropped"`
+}
+
+// liveClient websocket客户端
+type liveClient struct {
+	conn     *websocket.Conn
+	send     chan []byte
+	decimate *atomic.Int64
+	sids     map[string]struct{} // 由Live.mu保护
+	sent     *atomic.Int64
+	dropped  *atomic.Int64
+}
+
+// Live - 将SegmentArc样本实时推送给订阅的websocket客户端，作为转发目标接收数据包
+type Live struct {
+	mu      sync.RWMutex
+	clients map[*liveClient]struct{}
+	index   map[string]map[*liveClient]struct{} // 传感器 -> 订阅客户端
+	sample  *sample.Config
+	logger  logging.ILogger
+	dropped *atomic.Int64
+}
+
+// NewLive - 创建实时波形推送
+// @param s *sample.Config 采样格式
+// @param logger logging.ILogger 日志
+// @return *Live 实时推送
+func NewLive(s *sample.Config, logger logging.ILogger) *Live {
+	if logger == nil {
+		logger = new(logging.NoopLogger)
+	}
+	return &Live{
+		clients: map[*liveClient]struct{}{},
+		index:   map[string]map[*liveClient]struct{}{},
+		sample:  s,
+		logger:  logger,
+		dropped: atomic.NewInt64(0),
+	}
+}
+
+// Write - 推送给订阅该传感器的客户端，客户端发送缓存满时丢弃，不阻塞数据处理协程
+func (l *Live) Write(f *sink.Frame) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	subs := l.index[strings.ToUpper(f.SID)]
+	if len(subs) == 0 {
+		return nil
+	}
+
+	samples := sample.Decode(f.Arc, l.sample.BitsPerSample)
+	messages := map[int64][]byte{} // 相同抽取倍数共用消息
+	for c := range subs {
+		decimate := c.decimate.Load()
+		msg, ok := messages[decimate]
+		if !ok {
+			data, err := json.Marshal(&LiveFrame{
+				SID:        f.SID,
+				Timestamp:  f.Timestamp,
+				SampleRate: meta.Rate(f.Meta, l.sample.SampleRate) / int(decimate),
+				Channels:   l.sample.Channels,
+				Data:       decimateSamples(samples, l.sample.Channels, int(decimate)),
+			})
+			if err != nil {
+				return err
+			}
+			msg = data
+			messages[decimate] = msg
+		}
+		select {
+		case c.send <- msg:
+		default:
+			c.dropped.Inc()
+			l.dropped.Inc()
+		}
+	}
+	return nil
+}
+
+// decimateSamples 每decimate个采样时刻保留一个，保留全部通道
+func decimateSamples(samples []int32, channels, decimate int) []int32 {
+	if decimate <= 1 {
+		return samples
+	}
+	out := make([]int32, 0, len(samples)/decimate+channels)
+	for i := 0; i+channels <= len(samples); i += decimate * channels {
+		out = append(out, samples[i:i+channels]...)
+	}
+	return out
+}
+
+// Flush -
+func (l *Live) Flush() error {
+	return nil
+}
+
+// Close - 断开全部客户端
+func (l *Live) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c := range l.clients {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// Health - 推送始终可用
+func (l *Live) Health() sink.Health {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return sink.Health{Healthy: true, Message: fmt.Sprintf("clients %d, dropped %d", len(l.clients), l.dropped.Load())}
+}
+
+// Status - 客户端状态
+func (l *Live) Status() []LiveStatus {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	list := make([]LiveStatus, 0, len(l.clients))
+	for c := range l.clients {
+		st := LiveStatus{
+			Addr:     c.conn.RemoteAddr().String(),
+			SIDs:     []string{},
+			Decimate: int(c.decimate.Load()),
+			Sent:     c.sent.Load(),
+			Dropped:  c.dropped.Load(),
+		}
+		for sid := range c.sids {
+			st.SIDs = append(st.SIDs, sid)
+		}
+		list = append(list, st)
+	}
+	return list
+}
+
+// subscribe 修改客户端订阅
+func (l *Live) subscribe(c *liveClient, req *LiveRequest) {
+	if req.Decimate > 0 {
+		if req.Decimate > liveMaxDecimate {
+			req.Decimate = liveMaxDecimate
+		}
+		c.decimate.Store(int64(req.Decimate))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sid := range req.SIDs {
+		sid = strings.ToUpper(strings.TrimSpace(sid))
+		if sid == "" {
+			continue
+		}
+		if req.Action == "unsubscribe" {
+			delete(c.sids, sid)
+			delete(l.index[sid], c)
+			if len(l.index[sid]) == 0 {
+				delete(l.index, sid)
+			}
+			continue
+		}
+		c.sids[sid] = struct{}{}
+		if l.index[sid] == nil {
+			l.index[sid] = map[*liveClient]struct{}{}
+		}
+		l.index[sid][c] = struct{}{}
+	}
+}
+
+// remove 客户端断开
+func (l *Live) remove(c *liveClient) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for sid := range c.sids {
+		delete(l.index[sid], c)
+		if len(l.index[sid]) == 0 {
+			delete(l.index, sid)
+		}
+	}
+	delete(l.clients, c)
+}
+
+// serve 处理websocket连接，读协程处理订阅消息，写协程发送数据和心跳
+func (l *Live) serve(conn *websocket.Conn, sids []string, decimate int) {
+	c := &liveClient{
+		conn:     conn,
+		send:     make(chan []byte, liveSendBuffer),
+		decimate: atomic.NewInt64(1),
+		sids:     map[string]struct{}{},
+		sent:     atomic.NewInt64(0),
+		dropped:  atomic.NewInt64(0),
+	}
+	l.mu.Lock()
+	l.clients[c] = struct{}{}
+	l.mu.Unlock()
+	l.subscribe(c, &LiveRequest{Action: "subscribe", SIDs: sids, Decimate: decimate})
+
+	done := make(chan struct{})
+	go l.writeLoop(c, done)
+	defer func() {
+		l.remove(c)
+		close(done)
+		conn.Close()
+	}()
+	for {
+		req := &LiveRequest{}
+		if err := conn.ReadJSON(req); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				l.logger.Debugw("live client closed", "addr", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		l.subscribe(c, req)
+	}
+}
+
+// writeLoop 发送数据和心跳，写失败时关闭连接使读协程退出
+func (l *Live) writeLoop(c *liveClient, done chan struct{}) {
+	ticker := time.NewTicker(livePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.conn.Close()
+				return
+			}
+			c.sent.Inc()
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout)); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// setupLive - 实时波形推送接口
+func (s *Server) setupLive(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Live", base+"/live")
+
+	g.GET("/ws", s.getLiveWebsocket).
+		AddParamQuery("", "sids", "comma separated sensor ids to subscribe", false).
+		AddParamQuery(0, "decimate", "keep one of every N sample instants for display", false).
+		SetOperationId("getLiveWebsocket").
+		SetSummary("websocket of live SegmentArc samples, send {\"action\":\"subscribe\",\"sids\":[...],\"decimate\":N} to change subscription")
+
+	g.GET("/clients", s.getLiveClients).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []LiveStatus{}}, nil).
+		SetOperationId("getLiveClients").
+		SetSummary("get subscriptions and sent/dropped counters of websocket clients")
+}
+
+// getLiveWebsocket - 升级为websocket连接
+func (s *Server) getLiveWebsocket(c echo.Context) error {
+	if s.live == nil {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  "live streaming is disabled",
+		})
+	}
+	var sids []string
+	if v := c.QueryParam("sids"); v != "" {
+		sids = strings.Split(v, ",")
+	}
+	decimate, _ := strconv.Atoi(c.QueryParam("decimate"))
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 64 * 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	s.live.serve(conn, sids, decimate)
+	return nil
+}
+
+// checkOrigin 浏览器跨站请求只允许配置的来源，防止其他网站借用户浏览器订阅波形
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.config.LiveOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// getLiveClients - 获取实时推送客户端状态
+func (s *Server) getLiveClients(c echo.Context) error {
+	list := []LiveStatus{}
+	if s.live != nil {
+		list = s.live.Status()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+func TestDecimateSamples(t *testing.T) {
+	samples := []int32{1, 10, 2, 20, 3, 30, 4, 40, 5, 50}
+	got := decimateSamples(samples, 2, 2)
+	want := []int32{1, 10, 3, 30, 5, 50}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLive(t *testing.T) {
+	live := NewLive(&sample.Config{SampleRate: 1000, BitsPerSample: 16, Channels: 1}, nil)
+	e := echo.New()
+	s := &Server{live: live}
+	e.GET("/live/ws", s.getLiveWebsocket)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/live/ws?sids=94c96000c248&decimate=2"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 等待服务端注册订阅
+	deadline := time.Now().Add(2 * time.Second)
+	for len(live.Status()) == 0 || len(live.Status()[0].SIDs) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client not subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	arc, _ := sample.Encode([]int32{1, 2, 3, 4}, 16)
+	if err := live.Write(&sink.Frame{SID: "94C96000C249", Arc: arc}); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.Write(&sink.Frame{SID: "94C96000C248", Timestamp: 7, Arc: arc}); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msg := &LiveFrame{}
+	if err := conn.ReadJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.SID != "94C96000C248" || msg.Timestamp != 7 || msg.SampleRate != 500 ||
+		len(msg.Data) != 2 || msg.Data[0] != 1 || msg.Data[1] != 3 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	// 取消订阅后不再推送
+	if err := conn.WriteJSON(&LiveRequest{Action: "unsubscribe", SIDs: []string{"94C96000C248"}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for len(live.Status()[0].SIDs) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client not unsubscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLiveDrop(t *testing.T) {
+	live := NewLive(&sample.Config{SampleRate: 1000, BitsPerSample: 16, Channels: 1}, nil)
+	c := &liveClient{
+		send:     make(chan []byte, 1),
+		decimate: atomic.NewInt64(1),
+		sids:     map[string]struct{}{},
+		sent:     atomic.NewInt64(0),
+		dropped:  atomic.NewInt64(0),
+	}
+	live.clients[c] = struct{}{}
+	live.subscribe(c, &LiveRequest{Action: "subscribe", SIDs: []string{"94C96000C248"}})
+
+	// 客户端不读取时不阻塞写入
+	for i := 0; i < 3; i++ {
+		if err := live.Write(&sink.Frame{SID: "94C96000C248", Arc: []byte{0, 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.dropped.Load() != 2 || !strings.Contains(live.Health().Message, "dropped 2") {
+		t.Fatalf("dropped %d, health %+v", c.dropped.Load(), live.Health())
+	}
+}
+
+func TestLiveOrigin(t *testing.T) {
+	s := &Server{config: &Config{LiveOrigins: []string{"https://ops.example.com"}}}
+	cases := []struct {
+		origin string
+		host   string
+		want   bool
+	}{
+		{"", "consumer:8082", true},
+		{"http://consumer:8082", "consumer:8082", true},
+		{"http://CONSUMER:8082", "consumer:8082", true},
+		{"http://consumer:8083", "consumer:8082", false},
+		{"https://evil.example.com", "consumer:8082", false},
+		{"https://ops.example.com", "consumer:8082", true},
+		{"http://ops.example.com", "consumer:8082", false},
+		{"://bad", "consumer:8082", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "http://"+c.host+"/live/ws", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if got := s.checkOrigin(r); got != c.want {
+			t.Fatalf("origin %q host %q: got %v, want %v", c.origin, c.host, got, c.want)
+		}
+	}
+
+	s.config.LiveOrigins = []string{"*"}
+	r := httptest.NewRequest("GET", "http://consumer:8082/live/ws", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	if !s.checkOrigin(r) {
+		t.Fatal("wildcard should allow any origin")
+	}
+	if err := (&Config{LiveOrigins: []string{"ops.example.com"}}).Validate(); err == nil {
+		t.Fatal("origin without scheme should be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+)
+
+// setupLiveness - 传感器在线检测接口，与 /healthz 服务存活检查无关
+func (s *Server) setupLiveness(root echoswagger.ApiRoot, base string) {
+	g := root.Group("SensorLiveness", base+"/liveness")
+
+	g.GET("", s.getSensorLiveness).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: liveness.Summary{}}, nil).
+		SetOperationId("getSensorLiveness").
+		SetSummary("count sensors by state and list sensors expected to report that are offline")
+
+	g.GET("/sensors", s.listSensorLiveness).
+		AddParamQuery("", "state", "filter by state: online, stale, offline", false).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []liveness.SensorStatus{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		SetOperationId("listSensorLiveness").
+		SetSummary("list sensor liveness with last seen time and expected interval")
+}
+
+// getSensorLiveness - 在线状态汇总
+func (s *Server) getSensorLiveness(c echo.Context) error {
+	summary := &liveness.Summary{Sensors: []liveness.SensorStatus{}}
+	if s.liveness != nil {
+		summary = s.liveness.Summary()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: summary,
+	})
+}
+
+// listSensorLiveness - 传感器在线状态
+func (s *Server) listSensorLiveness(c echo.Context) error {
+	state := liveness.State(c.QueryParam("state"))
+	switch state {
+	case "", liveness.StateOnline, liveness.StateStale, liveness.StateOffline:
+	default:
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  fmt.Sprintf("invalid state %q", state),
+		})
+	}
+	list := []liveness.SensorStatus{}
+	if s.liveness != nil {
+		list = s.liveness.Sensors(state)
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+)
+
+// MetaImportResult - 元数据导入结果
+type MetaImportResult struct {
+	Imported int  `json:"imported"`
+	Replaced bool `json:"replaced"`
+}
+
+// setupMeta - 传感器元数据接口
+func (s *Server) setupMeta(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Metadata", base+"/metadata")
+
+	g.GET("", s.getMetaList).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []meta.Metadata{}}, nil).
+		SetOperationId("getMetadataList").
+		SetSummary("list metadata of all sensors")
+
+	g.GET("/:sid", s.getMeta).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: meta.Metadata{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("getMetadata").
+		SetSummary("get site, cabinet, phase, sample rate override and tags of a sensor")
+
+	g.PUT("/:sid", s.putMeta).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddParamBody(meta.Metadata{}, "body", "metadata, sid in body is ignored", true).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: meta.Metadata{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		SetOperationId("putMetadata").
+		SetSummary("set metadata of a sensor, replacing the existing one")
+
+	g.DELETE("/:sid", s.deleteMeta).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("deleteMetadata").
+		SetSummary("delete metadata of a sensor")
+
+	g.POST("/import", s.importMeta).
+		AddParamQuery(false, "replace", "delete metadata of sensors not in the import", false).
+		AddParamBody("", "body", "csv with header sid,site,cabinet,phase,sample_rate,<tag>... (Content-Type text/csv) or json array", true).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: MetaImportResult{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		SetRequestContentType("text/csv", echo.MIMEApplicationJSON).
+		SetOperationId("importMetadata").
+		SetSummary("import metadata of many sensors from csv or json, all or nothing")
+}
+
+// metaError 元数据接口错误码
+func metaError(c echo.Context, err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, meta.ErrInvalid):
+		code = http.StatusBadRequest
+	case errors.Is(err, meta.ErrNotFound):
+		code = http.StatusNotFound
+	}
+	return c.JSON(code, utils.ResponseV2{
+		Code: code,
+		Msg:  err.Error(),
+	})
+}
+
+// getMetaList - 获取全部元数据
+func (s *Server) getMetaList(c echo.Context) error {
+	list := []meta.Metadata{}
+	if s.meta != nil {
+		list = s.meta.List()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
+
+// getMeta - 获取传感器元数据
+func (s *Server) getMeta(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	if m := s.meta.Get(sid); m != nil {
+		return c.JSON(http.StatusOK, utils.ResponseV2{
+			Code: http.StatusOK,
+			Data: m,
+		})
+	}
+	return metaError(c, fmt.Errorf("%w: %s", meta.ErrNotFound, sid))
+}
+
+// putMeta - 设置传感器元数据
+func (s *Server) putMeta(c echo.Context) error {
+	if s.meta == nil {
+		return metaError(c, errors.New("metadata store not available"))
+	}
+	var m meta.Metadata
+	if err := c.Bind(&m); err != nil {
+		return metaError(c, fmt.Errorf("%w: %v", meta.ErrInvalid, err))
+	}
+	m.SID = c.Param("sid")
+	s.audit(c, "metadata.put", "sensor", m.SID, "site", m.Site, "cabinet", m.Cabinet, "phase", m.Phase)
+	saved, err := s.meta.Put(m)
+	if err != nil {
+		return metaError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: saved,
+	})
+}
+
+// deleteMeta - 删除传感器元数据
+func (s *Server) deleteMeta(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	if s.meta == nil {
+		return metaError(c, fmt.Errorf("%w: %s", meta.ErrNotFound, sid))
+	}
+	s.audit(c, "metadata.delete", "sensor", sid)
+	if err := s.meta.Delete(sid); err != nil {
+		return metaError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+	})
+}
+
+// importMeta - 批量导入元数据，按Content-Type解析CSV或JSON
+func (s *Server) importMeta(c echo.Context) error {
+	if s.meta == nil {
+		return metaError(c, errors.New("metadata store not available"))
+	}
+	replace := c.QueryParam("replace") == "true"
+	parse := meta.ParseJSON
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), "text/csv") {
+		parse = meta.ParseCSV
+	}
+	list, err := parse(c.Request().Body)
+	if err != nil {
+		return metaError(c, err)
+	}
+	s.audit(c, "metadata.import", "sensors", len(list), "replace", replace)
+	n, err := s.meta.Import(list, replace)
+	if err != nil {
+		return metaError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: MetaImportResult{Imported: n, Replaced: replace},
+	})
+}
@@ -1,7 +1,17 @@
 package api
 
 import (
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/health"
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+	"github.com/kiga-hub/arc-consumer/pkg/ring"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
 	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
 	"github.com/kiga-hub/arc/logging"
 	microComponent "github.com/kiga-hub/arc/micro/component"
 )
@@ -17,9 +27,19 @@ func loadOptions(options ...Option) *Server {
 	if opts.logger == nil {
 		opts.logger = new(logging.NoopLogger)
 	}
+	if opts.config == nil {
+		opts.config = GetConfig()
+	}
 	return opts
 }
 
+// WithConfig - 接口配置
+func WithConfig(c *Config) Option {
+	return func(opts *Server) {
+		opts.config = c
+	}
+}
+
 // WithSimulate -
 func WithSimulate(s simulate.Handler) Option {
 	return func(opts *Server) {
@@ -27,6 +47,91 @@ func WithSimulate(s simulate.Handler) Option {
 	}
 }
 
+// WithGrpc -
+func WithGrpc(g grpc.Handler) Option {
+	return func(opts *Server) {
+		opts.grpc = g
+	}
+}
+
+// WithSink -
+func WithSink(h sink.Handler) Option {
+	return func(opts *Server) {
+		opts.sinks = h
+	}
+}
+
+// WithSample - SegmentArc采样格式
+func WithSample(c *sample.Config) Option {
+	return func(opts *Server) {
+		opts.sample = c
+	}
+}
+
+// WithLive - 实时波形推送
+func WithLive(l *Live) Option {
+	return func(opts *Server) {
+		opts.live = l
+	}
+}
+
+// WithEvents - 事件推送
+func WithEvents(e *Events) Option {
+	return func(opts *Server) {
+		opts.events = e
+	}
+}
+
+// WithRecent - 最近数据缓存
+func WithRecent(b *ring.Buffer) Option {
+	return func(opts *Server) {
+		opts.recent = b
+	}
+}
+
+// WithRegistry - 传感器登记
+func WithRegistry(r *registry.Registry) Option {
+	return func(opts *Server) {
+		opts.registry = r
+	}
+}
+
+// WithMeta - 传感器元数据
+func WithMeta(m *meta.Store) Option {
+	return func(opts *Server) {
+		opts.meta = m
+	}
+}
+
+// WithLimiter - 接收限流
+func WithLimiter(l *limit.Limiter) Option {
+	return func(opts *Server) {
+		opts.limiter = l
+	}
+}
+
+// WithReload - 配置热更新，load 为接口触发时的配置加载
+func WithReload(r *reload.Reloader, load reload.Loader) Option {
+	return func(opts *Server) {
+		opts.reloader = r
+		opts.reloadLoader = load
+	}
+}
+
+// WithLiveness - 传感器在线检测
+func WithLiveness(t *liveness.Tracker) Option {
+	return func(opts *Server) {
+		opts.liveness = t
+	}
+}
+
+// WithHealth - 存活与就绪检查
+func WithHealth(h *health.Checker) Option {
+	return func(opts *Server) {
+		opts.health = h
+	}
+}
+
 // WithGossipKVCache -
 func WithGossipKVCache(g *microComponent.GossipKVCacheComponent) Option {
 	return func(opts *Server) {
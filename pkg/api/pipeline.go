@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+)
+
+// PipelineStatus - 数据管道状态
+type PipelineStatus struct {
+	State  string                 `json:"state"`
+	Shards []simulate.ShardStatus `json:"shards"`
+}
+
+// setupPipeline - 数据管道控制接口
+func (s *Server) setupPipeline(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Pipeline", base+"/pipeline")
+
+	g.GET("", s.getPipeline).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: PipelineStatus{}}, nil).
+		SetOperationId("getPipeline").
+		SetSummary("get pipeline state and queue depth of each shard")
+
+	actions := []struct {
+		path    string
+		id      string
+		summary string
+		fn      func(simulate.Handler) error
+	}{
+		{"/pause-forward", "pauseForward", "keep accepting and buffering frames in shard queues, stop processing and disconnect grpc",
+			simulate.Handler.PauseForward},
+		{"/pause-ingest", "pauseIngest", "drop new frames, keep processing and forwarding buffered frames",
+			simulate.Handler.PauseIngest},
+		{"/resume", "resumePipeline", "reconnect grpc and resume accepting and forwarding",
+			simulate.Handler.Resume},
+		{"/drain", "drainPipeline", "reject new frames, process all buffered frames, flush sinks and stop",
+			simulate.Handler.Drain},
+	}
+	for _, a := range actions {
+		g.POST(a.path, s.pipelineAction(a.id, a.fn)).
+			AddResponse(http.StatusOK, "", utils.ResponseV2{Data: PipelineStatus{}}, nil).
+			AddResponse(http.StatusConflict, "", utils.ResponseV2{}, nil).
+			SetOperationId(a.id).
+			SetSummary(a.summary)
+	}
+}
+
+// getPipeline - 获取数据管道状态
+func (s *Server) getPipeline(c echo.Context) error {
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: s.pipelineStatus(),
+	})
+}
+
+// pipelineAction - 切换数据管道状态
+func (s *Server) pipelineAction(action string, fn func(simulate.Handler) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.simulate == nil {
+			return c.JSON(http.StatusNotFound, utils.ResponseV2{
+				Code: http.StatusNotFound,
+				Msg:  "pipeline not available",
+			})
+		}
+		s.audit(c, "pipeline."+action, "state", s.simulate.State().String())
+		if err := fn(s.simulate); err != nil {
+			return pipelineError(c, err)
+		}
+		return s.getPipeline(c)
+	}
+}
+
+// pipelineError - 当前状态不允许切换时返回409
+func pipelineError(c echo.Context, err error) error {
+	code := http.StatusInternalServerError
+	if errors.Is(err, simulate.ErrTransition) {
+		code = http.StatusConflict
+	}
+	return c.JSON(code, utils.ResponseV2{
+		Code: code,
+		Msg:  err.Error(),
+	})
+}
+
+// pipelineStatus 数据管道状态
+func (s *Server) pipelineStatus() PipelineStatus {
+	status := PipelineStatus{Shards: []simulate.ShardStatus{}}
+	if s.simulate != nil {
+		status.State = s.simulate.State().String()
+		status.Shards = s.simulate.Shards()
+	}
+	return status
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/ring"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+)
+
+const (
+	// RecentFormatJSON 每个数据包解码后的样本
+	RecentFormatJSON = "json"
+	// RecentFormatRaw 原始二进制Frame依次拼接
+	RecentFormatRaw = "raw"
+	// RecentFormatBinary SegmentArc样本依次拼接，大端有符号整数，多通道交错
+	RecentFormatBinary = "binary"
+)
+
+// RecentFrame - 最近数据包样本
+type RecentFrame struct {
+	Timestamp int64   `json:"timestamp"` // Frame时间戳(毫秒)
+	Data      []int32 `json:"data"`      // 多通道交错存放
+}
+
+// RecentData - 最近数据查询结果
+type RecentData struct {
+	SID        string        `json:"sid"`
+	SampleRate int           `json:"sample_rate"`
+	Channels   int           `json:"channels"`
+	Frames     []RecentFrame `json:"frames"`
+}
+
+// setupRecent - 最近数据查询接口
+func (s *Server) setupRecent(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Recent", base+"/recent")
+
+	g.GET("", s.getRecentSensors).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []ring.SensorStatus{}}, nil).
+		SetOperationId("getRecentSensors").
+		SetSummary("list buffered time range and size of each sensor in the recent-data buffer")
+
+	g.GET("/:sid", s.getRecent).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddParamQuery(int64(0), "from", "start timestamp in milliseconds, default earliest buffered", false).
+		AddParamQuery(int64(0), "to", "end timestamp in milliseconds, default latest buffered", false).
+		AddParamQuery("", "format", "json (decoded samples), raw (frames) or binary (SegmentArc samples), default json", false).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: RecentData{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetResponseContentType(echo.MIMEApplicationJSON, echo.MIMEOctetStream).
+		SetOperationId("getRecent").
+		SetSummary("query buffered frames of a sensor in a time range without going to arc-storage")
+}
+
+// getRecentSensors - 获取各传感器缓存状态
+func (s *Server) getRecentSensors(c echo.Context) error {
+	list := []ring.SensorStatus{}
+	if s.recent != nil {
+		list = s.recent.Status()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
+
+// getRecent - 查询传感器最近数据
+func (s *Server) getRecent(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	from, errFrom := queryInt64(c, "from", 0)
+	to, errTo := queryInt64(c, "to", math.MaxInt64)
+	format := c.QueryParam("format")
+	if format == "" {
+		format = RecentFormatJSON
+	}
+	if errFrom != nil || errTo != nil || from > to {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  "invalid time range",
+		})
+	}
+	switch format {
+	case RecentFormatJSON, RecentFormatRaw, RecentFormatBinary:
+	default:
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  fmt.Sprintf("unsupported format %q", format),
+		})
+	}
+	if s.recent == nil {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  "recent-data buffer disabled",
+		})
+	}
+
+	frames := s.recent.Range(sid, from, to)
+	if len(frames) == 0 {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  fmt.Sprintf("no buffered data of %s", sid),
+		})
+	}
+
+	switch format {
+	case RecentFormatRaw, RecentFormatBinary:
+		var data []byte
+		for _, f := range frames {
+			if format == RecentFormatRaw {
+				data = append(data, f.Data...)
+			} else {
+				data = append(data, f.Arc...)
+			}
+		}
+		h := c.Response().Header()
+		h.Set("X-Arc-From", strconv.FormatInt(frames[0].Timestamp, 10))
+		h.Set("X-Arc-To", strconv.FormatInt(frames[len(frames)-1].Timestamp, 10))
+		h.Set("X-Arc-Frames", strconv.Itoa(len(frames)))
+		if format == RecentFormatBinary && s.sample != nil {
+			h.Set("X-Arc-Sample-Rate", strconv.Itoa(meta.Rate(frames[len(frames)-1].Meta, s.sample.SampleRate)))
+			h.Set("X-Arc-Sample-Bits", strconv.Itoa(s.sample.BitsPerSample))
+			h.Set("X-Arc-Channels", strconv.Itoa(s.sample.Channels))
+		}
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, data)
+	}
+
+	bits := 16
+	result := RecentData{SID: sid, Frames: make([]RecentFrame, 0, len(frames))}
+	if s.sample != nil {
+		bits = s.sample.BitsPerSample
+		result.SampleRate = meta.Rate(frames[len(frames)-1].Meta, s.sample.SampleRate)
+		result.Channels = s.sample.Channels
+	}
+	for _, f := range frames {
+		result.Frames = append(result.Frames, RecentFrame{
+			Timestamp: f.Timestamp,
+			Data:      sample.Decode(f.Arc, bits),
+		})
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: result,
+	})
+}
+
+// queryInt64 读取整数查询参数，未设置时返回默认值
+func queryInt64(c echo.Context, name string, def int64) (int64, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+)
+
+// RegistryStatus - 传感器登记策略与登记信息
+type RegistryStatus struct {
+	Policy  string           `json:"policy"`
+	Allow   []string         `json:"allow"`
+	Deny    []string         `json:"deny"`
+	Store   string           `json:"store"`
+	Sensors []registry.Entry `json:"sensors"`
+}
+
+// setupRegistry - 传感器登记接口
+func (s *Server) setupRegistry(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Registry", base+"/registry")
+
+	g.GET("", s.getRegistry).
+		AddParamQuery("", "status", "filter by status allowed/denied/pending, default all", false).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: RegistryStatus{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("getRegistry").
+		SetSummary("get registry policy, allow/deny lists and registered sensors")
+
+	g.POST("/:sid/approve", s.registryAction("approve", (*registry.Registry).Approve)).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: registry.Entry{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusConflict, "", utils.ResponseV2{}, nil).
+		SetOperationId("approveSensor").
+		SetSummary("allow a pending or denied sensor, frames are accepted from the next one")
+
+	g.POST("/:sid/deny", s.registryAction("deny", (*registry.Registry).Deny)).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: registry.Entry{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		SetOperationId("denySensor").
+		SetSummary("deny a sensor, its frames are dropped")
+
+	g.DELETE("/:sid", s.deleteRegistry).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("deleteRegistry").
+		SetSummary("forget a sensor, it is registered again by policy on the next frame")
+}
+
+// registryDisabled 未开启传感器登记
+func registryDisabled(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, utils.ResponseV2{
+		Code: http.StatusNotFound,
+		Msg:  "sensor registry disabled",
+	})
+}
+
+// registryError 登记接口错误码
+func registryError(c echo.Context, err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, registry.ErrInvalidSID):
+		code = http.StatusBadRequest
+	case errors.Is(err, registry.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, registry.ErrDenyRule):
+		code = http.StatusConflict
+	}
+	return c.JSON(code, utils.ResponseV2{
+		Code: code,
+		Msg:  err.Error(),
+	})
+}
+
+// getRegistry - 获取登记信息
+func (s *Server) getRegistry(c echo.Context) error {
+	if s.registry == nil {
+		return registryDisabled(c)
+	}
+	cfg := s.registry.Config()
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: RegistryStatus{
+			Policy:  cfg.Policy,
+			Allow:   cfg.Allow,
+			Deny:    cfg.Deny,
+			Store:   cfg.Store,
+			Sensors: s.registry.Entries(registry.Status(c.QueryParam("status"))),
+		},
+	})
+}
+
+// registryAction - 审批或拒绝传感器
+func (s *Server) registryAction(action string, fn func(*registry.Registry, string, string) (registry.Entry, error)) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.registry == nil {
+			return registryDisabled(c)
+		}
+		s.audit(c, "registry."+action, "sensor", c.Param("sid"))
+		user, _ := s.operator(c)
+		e, err := fn(s.registry, c.Param("sid"), user)
+		if err != nil {
+			return registryError(c, err)
+		}
+		return c.JSON(http.StatusOK, utils.ResponseV2{
+			Code: http.StatusOK,
+			Data: e,
+		})
+	}
+}
+
+// deleteRegistry - 删除登记信息
+func (s *Server) deleteRegistry(c echo.Context) error {
+	if s.registry == nil {
+		return registryDisabled(c)
+	}
+	s.audit(c, "registry.delete", "sensor", c.Param("sid"))
+	if err := s.registry.Remove(c.Param("sid")); err != nil {
+		return registryError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+)
+
+// setupReload - 配置热更新接口
+func (s *Server) setupReload(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Config", base+"/config")
+
+	g.GET("/reload", s.getReload).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: reload.Result{}}, nil).
+		SetOperationId("getReload").
+		SetSummary("result of the last config reload triggered by nacos, file change or api")
+
+	g.POST("/reload", s.postReload).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: reload.Result{}}, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{Data: reload.Result{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("postReload").
+		SetSummary("re-read the config file, validate and apply crc check, goroutine count, grpc, sinks and feature extraction; keep current config on failure")
+}
+
+// getReload - 最近一次热更新结果
+func (s *Server) getReload(c echo.Context) error {
+	var last *reload.Result
+	if s.reloader != nil {
+		last = s.reloader.Last()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: last,
+	})
+}
+
+// postReload - 重新加载配置文件
+func (s *Server) postReload(c echo.Context) error {
+	s.audit(c, "config.reload")
+	if s.reloader == nil {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  "config reload is disabled",
+		})
+	}
+	result := s.reloader.Reload(reload.SourceAPI, s.reloadLoader)
+	if result.Error != "" {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  result.Error,
+			Data: result,
+		})
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: result,
+	})
+}
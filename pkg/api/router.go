@@ -8,4 +8,18 @@ import (
 // @param root echoswagger.ApiRoot API接口
 // @param base string 路由前缀
 func (s *Server) Setup(root echoswagger.ApiRoot, base string) {
+	s.setupHealth(root)
+	s.setupSensor(root, base)
+	s.setupPipeline(root, base)
+	s.setupGrpc(root, base)
+	s.setupSink(root, base)
+	s.setupWave(root, base)
+	s.setupLive(root, base)
+	s.setupRecent(root, base)
+	s.setupRegistry(root, base)
+	s.setupMeta(root, base)
+	s.setupLimit(root, base)
+	s.setupLiveness(root, base)
+	s.setupReload(root, base)
+	s.setupEvents(root, base)
 }
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+)
+
+// setupSensor - 传感器清单接口
+func (s *Server) setupSensor(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Sensor", base+"/sensors")
+
+	g.GET("", s.getSensors).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []simulate.SensorStatus{}}, nil).
+		SetOperationId("getSensors").
+		SetSummary("list all sensors seen with counters, last frame timestamp and current data rate")
+
+	g.GET("/:sid", s.getSensor).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: simulate.SensorStatus{}}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		SetOperationId("getSensor").
+		SetSummary("get counters, last frame timestamp and current data rate of a sensor")
+}
+
+// getSensors - 获取全部已接收传感器
+func (s *Server) getSensors(c echo.Context) error {
+	list := []simulate.SensorStatus{}
+	if s.simulate != nil {
+		list = s.simulate.Sensors()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
+
+// getSensor - 获取单个传感器
+func (s *Server) getSensor(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	if s.simulate != nil {
+		if status, ok := s.simulate.Sensor(sid); ok {
+			return c.JSON(http.StatusOK, utils.ResponseV2{
+				Code: http.StatusOK,
+				Data: status,
+			})
+		}
+	}
+	return c.JSON(http.StatusNotFound, utils.ResponseV2{
+		Code: http.StatusNotFound,
+		Msg:  fmt.Sprintf("sensor %s not seen", sid),
+	})
+}
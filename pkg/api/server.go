@@ -1,7 +1,17 @@
 package api
 
 import (
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/health"
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+	"github.com/kiga-hub/arc-consumer/pkg/ring"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
 	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
 	"github.com/kiga-hub/arc/logging"
 	microComponent "github.com/kiga-hub/arc/micro/component"
 	"github.com/kiga-hub/arc/micro/conf"
@@ -16,7 +26,21 @@ type Handler interface {
 // Server - api处理器
 type Server struct {
 	logger          logging.ILogger
+	config          *Config
 	simulate        simulate.Handler
+	grpc            grpc.Handler
+	sinks           sink.Handler
+	sample          *sample.Config
+	live            *Live
+	events          *Events
+	health          *health.Checker
+	recent          *ring.Buffer
+	registry        *registry.Registry
+	meta            *meta.Store
+	limiter         *limit.Limiter
+	liveness        *liveness.Tracker
+	reloader        *reload.Reloader
+	reloadLoader    reload.Loader
 	gossipKVCache   *microComponent.GossipKVCacheComponent
 	selfServiceName string
 }
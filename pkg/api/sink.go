@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// setupSink - 转发目标接口
+func (s *Server) setupSink(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Sink", base+"/sink")
+
+	g.GET("", s.getSinks).
+		AddResponse(http.StatusOK, "", utils.ResponseV2{Data: []sink.Status{}}, nil).
+		SetOperationId("getSinks").
+		SetSummary("get health and write counters of each configured sink")
+}
+
+// getSinks - 获取转发目标状态
+func (s *Server) getSinks(c echo.Context) error {
+	list := []sink.Status{}
+	if s.sinks != nil {
+		list = s.sinks.Status()
+	}
+	return c.JSON(http.StatusOK, utils.ResponseV2{
+		Code: http.StatusOK,
+		Data: list,
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kiga-hub/arc/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/pangpanglabs/echoswagger/v2"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+)
+
+// maxWaveRange 单次导出最大时间范围
+const maxWaveRange = 10 * time.Minute
+
+// setupWave - 波形导出接口
+func (s *Server) setupWave(root echoswagger.ApiRoot, base string) {
+	g := root.Group("Wave", base+"/wave")
+
+	g.GET("/:sid", s.getWave).
+		AddParamPath("", "sid", "sensor id, e.g. 94C96000C248").
+		AddParamQuery(int64(0), "from", "start timestamp in milliseconds", true).
+		AddParamQuery(int64(0), "to", "end timestamp in milliseconds", true).
+		AddResponse(http.StatusOK, "wav file", nil, nil).
+		AddResponse(http.StatusBadRequest, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusNotFound, "", utils.ResponseV2{}, nil).
+		AddResponse(http.StatusConflict, "sample rate changes in the range", utils.ResponseV2{}, nil).
+		SetResponseContentType("audio/wav", echo.MIMEApplicationJSON).
+		SetOperationId("getWave").
+		SetSummary("export SegmentArc samples of a sensor in a time range as a wav file")
+}
+
+// getWave - 从支持读取的转发目标导出WAV文件
+func (s *Server) getWave(c echo.Context) error {
+	sid := strings.ToUpper(c.Param("sid"))
+	from, errFrom := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	to, errTo := strconv.ParseInt(c.QueryParam("to"), 10, 64)
+	if errFrom != nil || errTo != nil || from > to {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  "invalid time range",
+		})
+	}
+	if time.Duration(to-from)*time.Millisecond > maxWaveRange {
+		return c.JSON(http.StatusBadRequest, utils.ResponseV2{
+			Code: http.StatusBadRequest,
+			Msg:  fmt.Sprintf("time range exceeds %s", maxWaveRange),
+		})
+	}
+	if s.sinks == nil || s.sample == nil {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  "no readable sink",
+		})
+	}
+
+	frames, err := s.sinks.Read(sid, from, to)
+	if err != nil {
+		s.logger.Warnw("read frames", "sensor", sid, "err", err)
+	}
+	if len(frames) == 0 {
+		return c.JSON(http.StatusNotFound, utils.ResponseV2{
+			Code: http.StatusNotFound,
+			Msg:  fmt.Sprintf("no data of %s in [%d, %d]", sid, from, to),
+		})
+	}
+
+	// 采样率取传感器元数据，读取的数据包未带元数据时查询元数据存储
+	// 范围内采样率变化时拼接结果音调和时长错误，需分段导出
+	def := meta.Rate(s.meta.Get(sid), s.sample.SampleRate)
+	rate := meta.Rate(frames[0].Meta, def)
+	var data []byte
+	for _, f := range frames {
+		if r := meta.Rate(f.Meta, def); r != rate {
+			return c.JSON(http.StatusConflict, utils.ResponseV2{
+				Code: http.StatusConflict,
+				Msg:  fmt.Sprintf("sample rate of %s changes from %d to %d at %d, export [%d, %d) and [%d, %d] separately", sid, rate, r, f.Timestamp, from, f.Timestamp, f.Timestamp, to),
+			})
+		}
+		data = append(data, f.Arc...)
+	}
+	// 只保留完整的采样时刻
+	block := s.sample.Channels * s.sample.BytesPerSample()
+	data = data[:len(data)/block*block]
+	pcm, err := sample.WAVData(data, s.sample.BitsPerSample)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ResponseV2{
+			Code: http.StatusInternalServerError,
+			Msg:  err.Error(),
+		})
+	}
+
+	wav := *s.sample
+	wav.SampleRate = rate
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%s_%d_%d.wav", sid, from, to))
+	return c.Blob(http.StatusOK, "audio/wav", append(sample.WAVHeader(&wav, uint32(len(pcm))), pcm...))
+}
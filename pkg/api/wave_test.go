@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// readSink 可读取的转发目标，返回固定数据包
+type readSink struct {
+	frames []*sink.Frame
+}
+
+func (r *readSink) Write(*sink.Frame) error { return nil }
+func (r *readSink) Flush() error            { return nil }
+func (r *readSink) Close() error            { return nil }
+func (r *readSink) Health() sink.Health     { return sink.Health{Healthy: true} }
+func (r *readSink) Read(sid string, from, to int64) ([]*sink.Frame, error) {
+	return r.frames, nil
+}
+
+func TestWaveMetaRate(t *testing.T) {
+	s := &sample.Config{SampleRate: 1000, BitsPerSample: 16, Channels: 1}
+	arc, _ := sample.Encode([]int32{1, -1}, 16)
+	store, err := meta.New(&meta.Config{File: filepath.Join(t.TempDir(), "meta.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(meta.Metadata{SID: "94C96000C249", SampleRate: 2000}); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		sid    string
+		frames []*sink.Frame
+		rate   int // 0 采样率变化拒绝导出
+	}{
+		{"default", "94C96000C248", []*sink.Frame{{SID: "94C96000C248", Arc: arc}}, 1000},
+		{"frame meta", "94C96000C248", []*sink.Frame{{SID: "94C96000C248", Arc: arc, Meta: &meta.Metadata{SampleRate: 500}}}, 500},
+		// 读回的数据包未带元数据，查询元数据存储
+		{"meta store", "94C96000C249", []*sink.Frame{{SID: "94C96000C249", Arc: arc}}, 2000},
+		{"rate change", "94C96000C248", []*sink.Frame{
+			{SID: "94C96000C248", Timestamp: 0, Arc: arc},
+			{SID: "94C96000C248", Timestamp: 500, Arc: arc, Meta: &meta.Metadata{SampleRate: 500}},
+		}, 0},
+		{"same rate from meta and default", "94C96000C248", []*sink.Frame{
+			{SID: "94C96000C248", Timestamp: 0, Arc: arc[:2]},
+			{SID: "94C96000C248", Timestamp: 500, Arc: arc[2:], Meta: &meta.Metadata{SampleRate: 1000}},
+		}, 1000},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sinks, err := sink.New(sink.WithSink("file", "file", &readSink{frames: c.frames}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := &Server{sinks: sinks, sample: s, meta: store}
+			e := echo.New()
+			e.GET("/wave/:sid", srv.getWave)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/wave/"+c.sid+"?from=0&to=1000", nil))
+			if c.rate == 0 {
+				if rec.Code != http.StatusConflict {
+					t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+			}
+			wav := *s
+			wav.SampleRate = c.rate
+			if want := append(sample.WAVHeader(&wav, 4), 1, 0, 0xFF, 0xFF); !bytes.Equal(rec.Body.Bytes(), want) {
+				t.Fatalf("got %v, want %v", rec.Body.Bytes(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Delay - 指数退避等待时间，每次重试翻倍，不超过 max，在 [d/2, d) 区间内随机抖动
+// @param base time.Duration 首次重试等待
+// @param max time.Duration 最大等待
+// @param attempt int 已重试次数，从0开始
+// @return time.Duration 等待时间
+func Delay(base, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// Backoff - 记录重试次数的指数退避
+type Backoff struct {
+	Base    time.Duration
+	Max     time.Duration
+	Attempt int
+}
+
+// Next - 下一次重试等待时间
+func (b *Backoff) Next() time.Duration {
+	d := Delay(b.Base, b.Max, b.Attempt)
+	b.Attempt++
+	return d
+}
+
+// Reset - 成功后重置重试次数
+func (b *Backoff) Reset() {
+	b.Attempt = 0
+}
@@ -0,0 +1,43 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		name string
+		base time.Duration
+		max  time.Duration
+		want []time.Duration // 每次重连的抖动上限，实际等待在 [d/2, d) 内
+	}{
+		{"double", 100 * time.Millisecond, time.Second, []time.Duration{
+			100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second,
+		}},
+		{"base over max", 2 * time.Second, time.Second, []time.Duration{time.Second, time.Second}},
+		{"base equals max", time.Second, time.Second, []time.Duration{time.Second, time.Second, time.Second}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for round := 0; round < 20; round++ {
+				b := &Backoff{Base: c.base, Max: c.max}
+				for i, d := range c.want {
+					if got := b.Next(); got < d/2 || got >= d {
+						t.Fatalf("attempt %d: got %v, want [%v, %v)", i, got, d/2, d)
+					}
+				}
+				b.Reset()
+				if got := b.Next(); got < c.want[0]/2 || got >= c.want[0] {
+					t.Fatalf("after reset: got %v, want [%v, %v)", got, c.want[0]/2, c.want[0])
+				}
+			}
+		})
+	}
+
+	// 间隔太小无法抖动时直接返回
+	b := &Backoff{Base: time.Nanosecond, Max: time.Nanosecond}
+	if got := b.Next(); got != time.Nanosecond {
+		t.Fatalf("got %v, want 1ns", got)
+	}
+}
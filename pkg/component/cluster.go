@@ -25,6 +25,7 @@ func (c *ArcConsumerComponent) setEventHook() {
 
 	// 本服务启动，加入集群后，回调函数
 	c.gossipKVCache.OnJoinCluster = func() {
+		c.gossipJoined.Store(true)
 		if c.grpc != nil {
 			// 获取arc-storage地址
 			ip, err := c.findArcStorage(grpcConfig.Server)
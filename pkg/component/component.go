@@ -2,6 +2,9 @@ package component
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	platformConf "github.com/kiga-hub/arc/conf"
@@ -11,13 +14,35 @@ import (
 	"github.com/kiga-hub/arc/micro"
 	microComponent "github.com/kiga-hub/arc/micro/component"
 	"github.com/kiga-hub/arc/micro/conf"
+	arcTracing "github.com/kiga-hub/arc/tracing"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pangpanglabs/echoswagger/v2"
 	"github.com/spf13/viper"
+	"go.uber.org/atomic"
 
 	"github.com/kiga-hub/arc-consumer/pkg/api"
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/file"
 	"github.com/kiga-hub/arc-consumer/pkg/goss"
 	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/health"
+	"github.com/kiga-hub/arc-consumer/pkg/kafka"
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
+	"github.com/kiga-hub/arc-consumer/pkg/mqtt"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+	"github.com/kiga-hub/arc-consumer/pkg/ring"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
 	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+	"github.com/kiga-hub/arc-consumer/pkg/taos"
+	"github.com/kiga-hub/arc-consumer/pkg/tcp"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
+	"github.com/kiga-hub/arc-consumer/pkg/webhook"
 )
 
 // ArcConsumerElementKey is Element Key for arc-consumer
@@ -34,8 +59,23 @@ type ArcConsumerComponent struct {
 	gossipKVCache *microComponent.GossipKVCacheComponent
 	simulate      simulate.Handler
 	grpc          grpc.Handler
+	sinks         sink.Handler
 	api           api.Handler
+	live          *api.Live
+	recent        *ring.Buffer
+	events        *api.Events
 	kvCache       goss.Handler
+	registry      *registry.Registry
+	meta          *meta.Store
+	limiter       *limit.Limiter
+	liveness      *liveness.Tracker
+	reloader      *reload.Reloader
+	configData    []byte // 上次应用的配置文件内容
+	sampleConfig  *sample.Config
+	featureConfig *feature.Config
+	health        *health.Checker
+	gossipJoined  *atomic.Bool
+	startedAt     time.Time
 }
 
 // Name of the component
@@ -46,7 +86,23 @@ func (c *ArcConsumerComponent) Name() string {
 // PreInit called before Init()
 func (c *ArcConsumerComponent) PreInit(ctx context.Context) error {
 	simulate.SetDefaultConfig()
+	sample.SetDefaultConfig()
+	feature.SetDefaultConfig()
 	grpc.SetDefaultConfig()
+	kafka.SetDefaultConfig()
+	tcp.SetDefaultConfig()
+	file.SetDefaultConfig()
+	taos.SetDefaultConfig()
+	mqtt.SetDefaultConfig()
+	webhook.SetDefaultConfig()
+	ring.SetDefaultConfig()
+	registry.SetDefaultConfig()
+	meta.SetDefaultConfig()
+	limit.SetDefaultConfig()
+	liveness.SetDefaultConfig()
+	reload.SetDefaultConfig()
+	tracing.SetDefaultConfig()
+	api.SetDefaultConfig()
 	return nil
 }
 
@@ -55,6 +111,7 @@ func (c *ArcConsumerComponent) Init(server *micro.Server) (err error) {
 	c.cluster = server.PrivateCluster
 	c.privateIP = server.PrivateIP.String()
 	c.config = conf.GetBasicConfig()
+	c.gossipJoined = atomic.NewBool(false)
 	spew.Dump(c.config) // 打印基础配置信息
 
 	// 动态配置接口
@@ -81,23 +138,163 @@ func (c *ArcConsumerComponent) Init(server *micro.Server) (err error) {
 		)
 	}
 
+	// 指标以集群、服务为固定标签，由 /metrics 输出
+	if err = metrics.Register(c.cluster, c.config.Service); err != nil {
+		return err
+	}
+
+	// SegmentArc采样格式
+	sampleConfig := sample.GetConfig()
+	if err = sampleConfig.Validate(); err != nil {
+		return err
+	}
+	spew.Dump(sampleConfig)
+	c.sampleConfig = sampleConfig
+
+	// 特征提取与电弧事件检测
+	featureConfig := feature.GetConfig()
+	if err = featureConfig.Validate(); err != nil {
+		return err
+	}
+	c.featureConfig = featureConfig
+
+	// 数据包跟踪，按比例采样，未配置跟踪时不创建
+	traceConfig := tracing.GetConfig()
+	if err = traceConfig.Validate(); err != nil {
+		return err
+	}
+	var tracer opentracing.Tracer
+	if elTracer := server.GetElement(&arcTracing.ElementKey); elTracer != nil {
+		tracer = elTracer.(opentracing.Tracer)
+	}
+	sampler := tracing.New(tracer, traceConfig)
+
 	// 初始化grpck客户端服务，目前用于转发数据到arc-storage
-	c.grpc = grpc.New(grpc.WithLogger(c.logger))
+	grpcConfig := grpc.GetConfig()
+	if err = grpcConfig.Validate(); err != nil {
+		return err
+	}
+	// 事件推送，接收电弧事件、传感器上下线和grpc连接状态变化
+	c.events = api.NewEvents(c.logger)
+
+	c.grpc = grpc.New(
+		grpc.WithLogger(c.logger),
+		grpc.WithConfig(grpcConfig),
+		grpc.WithStateListener(c.onGrpcState),
+		grpc.WithTracer(sampler.Tracer()),
+	)
+
+	// 实时波形推送，作为转发目标接收数据包
+	c.live = api.NewLive(sampleConfig, c.logger)
+
+	// 最近数据缓存，作为转发目标接收数据包
+	ringConfig := ring.GetConfig()
+	if err = ringConfig.Validate(); err != nil {
+		return err
+	}
+	if ringConfig.Enable {
+		c.recent = ring.New(ringConfig)
+	}
+
+	// 初始化转发目标
+	if c.sinks, err = c.newSinks(); err != nil {
+		return err
+	}
+
+	// 传感器元数据，附加到转发的数据包、特征和事件
+	metaConfig := meta.GetConfig()
+	if err = metaConfig.Validate(); err != nil {
+		return err
+	}
+	if c.meta, err = meta.New(metaConfig); err != nil {
+		return err
+	}
+
+	// 传感器登记，按允许、拒绝列表和策略决定是否接收数据
+	if c.registry, err = c.newRegistry(); err != nil {
+		return err
+	}
+
+	// 接收限流，按传感器分组和全局限制速率
+	limitConfig, err := limit.GetConfig()
+	if err != nil {
+		return err
+	}
+	if err = limitConfig.Validate(); err != nil {
+		return err
+	}
+	if limitConfig.Enable {
+		if c.limiter, err = limit.New(limitConfig, c.onRateLimited, limit.WithDisconnect(c.disconnectSensor)); err != nil {
+			return err
+		}
+	}
+
+	// 传感器在线检测，已配置元数据的传感器预期上报
+	livenessConfig, err := liveness.GetConfig()
+	if err != nil {
+		return err
+	}
+	if err = livenessConfig.Validate(); err != nil {
+		return err
+	}
+	if livenessConfig.Enable {
+		if c.liveness, err = liveness.New(livenessConfig, c.expectedSensors, c.onLiveness); err != nil {
+			return err
+		}
+	}
 
 	// 初始化tcp服务
+	simulateConfig := simulate.GetConfig()
 	if c.simulate, err = simulate.New(
 		simulate.WithLogger(c.logger),
-		simulate.WithGrpc(c.grpc),
+		simulate.WithConfig(simulateConfig),
+		simulate.WithSink(c.sinks),
+		simulate.WithFeature(feature.New(featureConfig, sampleConfig)),
 		simulate.WithKVCache(c.kvCache),
+		simulate.WithRegistry(c.registry),
+		simulate.WithMeta(c.meta),
+		simulate.WithLimiter(c.limiter),
+		simulate.WithLiveness(c.liveness),
+		simulate.WithTracing(sampler),
+		simulate.WithStateListener(c.onPipelineState),
+		simulate.WithShardListener(c.onShards),
 	); err != nil {
 		return err
 	}
 
+	// grpc连接池与数据处理协程一一对应
+	if c.grpc != nil {
+		c.grpc.SetMask(uint64(simulateConfig.GoroutineCount - 1))
+	}
+
+	// 配置热更新，由nacos、配置文件修改和接口触发
+	c.reloader = c.newReloader()
+
+	// 存活与就绪检查
+	c.health = c.newHealth()
+
 	// 初始化web api接口服务
+	apiConfig := api.GetConfig()
+	if err = apiConfig.Validate(); err != nil {
+		return err
+	}
 	c.api = api.New(
 		api.WithLogger(c.logger),
+		api.WithConfig(apiConfig),
 		api.WithGossipKVCache(c.gossipKVCache),
 		api.WithSimulate(c.simulate),
+		api.WithGrpc(c.grpc),
+		api.WithSink(c.sinks),
+		api.WithSample(sampleConfig),
+		api.WithLive(c.live),
+		api.WithRecent(c.recent),
+		api.WithEvents(c.events),
+		api.WithHealth(c.health),
+		api.WithRegistry(c.registry),
+		api.WithMeta(c.meta),
+		api.WithLimiter(c.limiter),
+		api.WithLiveness(c.liveness),
+		api.WithReload(c.reloader, c.fileLoader(true)),
 	)
 
 	return nil
@@ -126,9 +323,16 @@ func (c *ArcConsumerComponent) SetDynamicConfig(nf *platformConf.NodeConfig) err
 	return nil
 }
 
-// OnConfigChanged 动态配置nacos修改回调函数
-func (c *ArcConsumerComponent) OnConfigChanged(*platformConf.NodeConfig) error {
-	return micro.ErrNeedRestart
+// OnConfigChanged 动态配置nacos修改回调函数，可热更新的配置立即生效，其他配置修改时需重启
+func (c *ArcConsumerComponent) OnConfigChanged(nf *platformConf.NodeConfig) error {
+	result := c.reloader.Reload(reload.SourceNacos, c.nacosLoader(nf, ""))
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	if len(result.Restart) > 0 {
+		return micro.ErrNeedRestart
+	}
+	return nil
 }
 
 // SetupHandler 安装路由
@@ -140,11 +344,26 @@ func (c *ArcConsumerComponent) SetupHandler(root echoswagger.ApiRoot, base strin
 
 // Start the component
 func (c *ArcConsumerComponent) Start(ctx context.Context) error {
+	c.startedAt = time.Now()
+
 	// 集群定时上报模块启动
 	if c.kvCache != nil {
 		go c.kvCache.Start(ctx)
 	}
 
+	// 转发目标定时写出缓存
+	go c.sinks.Start(ctx)
+
+	// 传感器登记集群同步
+	if c.registry != nil {
+		go c.registry.Start(ctx)
+	}
+
+	// 传感器在线检测
+	if c.liveness != nil {
+		go c.liveness.Start(ctx)
+	}
+
 	// 数据接收模块启动
 	go func() {
 		if err := c.simulate.Start(ctx); err != nil {
@@ -152,6 +371,16 @@ func (c *ArcConsumerComponent) Start(ctx context.Context) error {
 		}
 	}()
 
+	// 监听配置文件修改
+	if err := c.watchConfig(ctx); err != nil {
+		return err
+	}
+
+	// 监听grpc传输参数动态配置
+	if err := c.listenGrpcConfig(); err != nil {
+		c.logger.Errorw("listen grpc dynamic config", "err", err)
+	}
+
 	// 判断服务是否在集群内，不在集群内直接连接
 	if c.gossipKVCache == nil {
 		// 不在集群内直接连接远程grpc服务
@@ -174,9 +403,150 @@ func (c *ArcConsumerComponent) Stop(ctx context.Context) error {
 		c.logger.Errorw("stop simulate", "err", err)
 	}
 
+	// 关闭转发目标，写出缓存数据
+	if err := c.sinks.Close(); err != nil {
+		c.logger.Errorw("close sinks", "err", err)
+	}
+
 	// 停止grpc服务
 	if c.grpc != nil {
 		c.grpc.Stop()
 	}
 	return nil
 }
+
+// newRegistry 创建传感器登记，未开启时返回nil，保存到集群一致性键值时需在集群内运行
+func (c *ArcConsumerComponent) newRegistry() (*registry.Registry, error) {
+	cfg := registry.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Enable {
+		return nil, nil
+	}
+	store := registry.NewFileStore(cfg.File)
+	if cfg.Store == registry.StoreGossip {
+		if c.gossipKVCache == nil {
+			return nil, fmt.Errorf("registry store %s requires gossip kv cache", registry.StoreGossip)
+		}
+		store = registry.NewGossipStore(c.gossipKVCache)
+	}
+	return registry.New(
+		registry.WithConfig(cfg),
+		registry.WithStore(store),
+		registry.WithLogger(c.logger),
+		registry.WithListener(c.onSensorRegistered),
+	)
+}
+
+// onSensorRegistered 未知传感器按策略登记发布到事件推送
+func (c *ArcConsumerComponent) onSensorRegistered(e registry.Entry) {
+	id, _, _ := registry.ParseSID(e.SID)
+	c.events.Publish(&event.Event{
+		Type:      event.TypeSensorRegistered,
+		ID:        id,
+		SID:       e.SID,
+		Timestamp: e.Updated,
+		Message:   string(e.Status),
+		Data:      e,
+		Meta:      c.meta.Get(e.SID),
+	})
+}
+
+// onRateLimited 开始超过限流时记录日志并发布到事件推送
+func (c *ArcConsumerComponent) onRateLimited(h limit.Hit) {
+	c.logger.Warnw("rate limited", "scope", h.Scope, "sensor", h.SID, "group", h.Group, "policy", h.Policy)
+	c.events.Publish(&event.Event{
+		Type:      event.TypeRateLimited,
+		ID:        h.ID,
+		SID:       h.SID,
+		Timestamp: time.Now().UnixMilli(),
+		Message:   fmt.Sprintf("%s limit exceeded, %s", h.Scope, h.Policy),
+		Data:      h,
+		Meta:      c.meta.Get(h.SID),
+	})
+}
+
+// disconnectSensor 接收限流disconnect策略断开传感器设备会话
+func (c *ArcConsumerComponent) disconnectSensor(id uint64, sid string) {
+	if c.simulate == nil {
+		return
+	}
+	c.simulate.Disconnect(id, sid)
+}
+
+// expectedSensors 已配置元数据的传感器预期上报
+func (c *ArcConsumerComponent) expectedSensors() []string {
+	list := c.meta.List()
+	sids := make([]string, 0, len(list))
+	for _, m := range list {
+		sids = append(sids, m.SID)
+	}
+	return sids
+}
+
+// onLiveness 传感器在线状态变化时更新指标，并发布到全部支持事件的转发目标
+func (c *ArcConsumerComponent) onLiveness(t liveness.Transition) {
+	typ := event.TypeSensorOnline
+	up := 1.0
+	switch t.To {
+	case liveness.StateStale:
+		typ, up = event.TypeSensorStale, 0
+	case liveness.StateOffline:
+		typ, up = event.TypeSensorOffline, 0
+		c.logger.Warnw("sensor offline", "sensor", t.SID, "group", t.Group, "last_seen", t.LastSeen)
+	}
+	metrics.SensorUp.WithLabelValues(t.SID).Set(up)
+	message := string(t.To)
+	if t.From != "" {
+		message = fmt.Sprintf("%s -> %s", t.From, t.To)
+	}
+	if err := c.sinks.WriteEvent(&event.Event{
+		Type:      typ,
+		ID:        t.ID,
+		SID:       t.SID,
+		Timestamp: time.Now().UnixMilli(),
+		Message:   message,
+		Data:      t,
+		Meta:      c.meta.Get(t.SID),
+	}); err != nil {
+		c.logger.Warnw(err.Error(), "sensor", t.SID)
+	}
+}
+
+// onGrpcState grpc连接状态变化发布到事件推送
+func (c *ArcConsumerComponent) onGrpcState(change *grpc.StateChange) {
+	c.events.Publish(&event.Event{
+		Type:      event.TypeGrpcState,
+		Timestamp: time.Now().UnixMilli(),
+		Message:   fmt.Sprintf("%s %s -> %s", change.Addr, change.From, change.To),
+		Data:      change,
+	})
+}
+
+// onShards 数据处理协程数修改后grpc连接池与协程保持一一对应
+func (c *ArcConsumerComponent) onShards(count int) {
+	if c.grpc != nil {
+		c.grpc.SetMask(uint64(count - 1))
+	}
+}
+
+// onPipelineState 数据管道暂停转发时断开grpc，恢复转发时重连，并发布到事件推送
+// 暂停时处理中的数据包已完成，恢复时在开始处理前重连，切换过程中不丢失数据包
+func (c *ArcConsumerComponent) onPipelineState(from, to simulate.State) {
+	if c.grpc != nil {
+		switch {
+		case from.Forwarding() && !to.Forwarding():
+			c.grpc.Disconnect()
+		case !from.Forwarding() && to.Forwarding():
+			c.grpc.ReConnect()
+		}
+	}
+	c.logger.Infow("pipeline state changed", "from", from.String(), "to", to.String())
+	c.events.Publish(&event.Event{
+		Type:      event.TypePipelineState,
+		Timestamp: time.Now().UnixMilli(),
+		Message:   fmt.Sprintf("%s -> %s", from, to),
+		Data:      map[string]string{"from": from.String(), "to": to.String()},
+	})
+}
@@ -0,0 +1,83 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/health"
+)
+
+const (
+	// shardSaturation 管道占用比例达到后认为数据处理协程饱和
+	shardSaturation = 0.9
+	// gossipJoinTimeout 集群组件启动后等待加入集群的时间，超时未加入会退出进程
+	gossipJoinTimeout = 30 * time.Second
+)
+
+// newHealth 注册存活与就绪检查
+func (c *ArcConsumerComponent) newHealth() *health.Checker {
+	h := health.New()
+	h.AddLiveness("listener", c.checkListener)
+	h.AddReadiness("pipeline", c.checkPipeline)
+	h.AddReadiness("shards", c.checkShards)
+	if c.grpc != nil {
+		h.AddReadiness("grpc", c.checkGrpc)
+	}
+	if c.config.InSwarm && c.gossipKVCache != nil {
+		h.AddReadiness("gossip", c.checkGossip)
+	}
+	return h
+}
+
+// checkListener 数据接收服务已启动
+func (c *ArcConsumerComponent) checkListener() error {
+	if !c.simulate.Running() {
+		return errors.New("receive service not started")
+	}
+	return nil
+}
+
+// checkPipeline 数据管道接收新数据包，暂停接收、排空和已停止时不就绪
+func (c *ArcConsumerComponent) checkPipeline() error {
+	if state := c.simulate.State(); !state.Accepting() {
+		return fmt.Errorf("pipeline %s", state)
+	}
+	return nil
+}
+
+// checkShards 数据处理协程管道未饱和
+func (c *ArcConsumerComponent) checkShards() error {
+	var saturated []string
+	for _, s := range c.simulate.Shards() {
+		if float64(s.Depth) >= shardSaturation*float64(s.Capacity) {
+			saturated = append(saturated, fmt.Sprintf("%d:%d/%d", s.Index, s.Depth, s.Capacity))
+		}
+	}
+	if len(saturated) > 0 {
+		return fmt.Errorf("shards saturated %s", strings.Join(saturated, ","))
+	}
+	return nil
+}
+
+// checkGrpc 至少一个arc-storage连接可用
+func (c *ArcConsumerComponent) checkGrpc() error {
+	if h := grpc.NewSink(c.grpc).Health(); !h.Healthy {
+		return fmt.Errorf("no healthy arc-storage connection %s", h.Message)
+	}
+	return nil
+}
+
+// checkGossip 已加入集群
+func (c *ArcConsumerComponent) checkGossip() error {
+	if c.gossipJoined.Load() || len(c.gossipKVCache.GetMembers()) > 1 {
+		return nil
+	}
+	// 集群组件在超时内未加入集群会退出进程，进程仍在运行说明已作为首个节点建立集群
+	if time.Since(c.startedAt) >= gossipJoinTimeout {
+		return nil
+	}
+	return errors.New("waiting to join gossip cluster")
+}
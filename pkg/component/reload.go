@@ -0,0 +1,227 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"time"
+
+	platformConf "github.com/kiga-hub/arc/conf"
+	"github.com/kiga-hub/arc/micro"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/simulate"
+)
+
+// errNoConfigFile 未使用配置文件启动
+var errNoConfigFile = errors.New("no config file in use")
+
+// grpcConfigDataID nacos中grpc传输参数的配置ID，内容为grpc.Config的json
+const grpcConfigDataID = "arc-consumer-grpc"
+
+// newReloader 配置热更新，依次为接收参数、grpc传输参数、转发目标和特征提取
+func (c *ArcConsumerComponent) newReloader() *reload.Reloader {
+	if path := viper.ConfigFileUsed(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			c.configData = data
+		}
+	}
+	return reload.New(c.logger, c.restartKeys,
+		reload.Step{Name: "simulate", Prepare: c.prepareSimulate},
+		reload.Step{Name: "grpc", Prepare: c.prepareGrpc},
+		reload.Step{Name: "sink", Prepare: c.prepareSinks},
+		reload.Step{Name: "feature", Prepare: c.prepareFeature},
+	)
+}
+
+// prepareSimulate CRC校验和数据处理协程数，协程数修改时排空管道后切换并修改grpc连接池
+func (c *ArcConsumerComponent) prepareSimulate() (*reload.Change, error) {
+	cfg := simulate.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	next := *c.simulate.Config()
+	if next.EnableCRCCheck == cfg.EnableCRCCheck && next.GoroutineCount == cfg.GoroutineCount {
+		return nil, nil
+	}
+	next.EnableCRCCheck = cfg.EnableCRCCheck
+	next.GoroutineCount = cfg.GoroutineCount
+	return &reload.Change{Apply: func() {
+		if err := c.simulate.UpdateConfig(&next); err != nil {
+			c.logger.Errorw("update simulate config", "err", err)
+		}
+	}}, nil
+}
+
+// prepareGrpc 目标地址和传输参数，开关需重启生效
+// 集群发现或接口修改的目标地址优先于配置文件
+func (c *ArcConsumerComponent) prepareGrpc() (*reload.Change, error) {
+	if c.grpc == nil {
+		return nil, nil
+	}
+	current := c.grpc.Config()
+	cfg := grpc.GetConfig()
+	cfg.Enable = current.Enable
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(cfg, current) {
+		return nil, nil
+	}
+	server := cfg.Server
+	cfg.Server = current.Server
+	return &reload.Change{Apply: func() {
+		if err := c.grpc.UpdateConfig(cfg); err != nil {
+			c.logger.Errorw("update grpc config", "err", err)
+			return
+		}
+		if server != current.Server {
+			if err := c.grpc.SetServer(server); err != nil {
+				c.logger.Errorw("update grpc server", "err", err)
+			}
+		}
+	}}, nil
+}
+
+// prepareSinks 按 [[sink]] 配置创建新增和修改的转发目标，应用时替换
+func (c *ArcConsumerComponent) prepareSinks() (*reload.Change, error) {
+	configs, err := c.sinkConfigs()
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.sinks.Prepare(configs)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &reload.Change{Apply: u.Commit, Discard: u.Discard}, nil
+}
+
+// prepareFeature 特征提取开关和电弧事件阈值
+func (c *ArcConsumerComponent) prepareFeature() (*reload.Change, error) {
+	cfg := feature.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(cfg, c.featureConfig) {
+		return nil, nil
+	}
+	return &reload.Change{Apply: func() {
+		c.simulate.SetFeatures(feature.New(cfg, c.sampleConfig))
+		c.featureConfig = cfg
+	}}, nil
+}
+
+// restartKeys 已修改但需重启生效的配置项
+func (c *ArcConsumerComponent) restartKeys() []string {
+	keys := simulate.GetConfig().RestartKeys(c.simulate.Config())
+	if grpc.GetConfig().Enable != (c.grpc != nil) {
+		keys = append(keys, grpc.KeyGRPCEnable)
+	}
+	if !reflect.DeepEqual(sample.GetConfig(), c.sampleConfig) {
+		keys = append(keys, "sample")
+	}
+	return keys
+}
+
+// fileLoader 重新读取配置文件，失败时恢复上次应用的内容
+// @param force bool 内容未修改时也重新应用
+func (c *ArcConsumerComponent) fileLoader(force bool) reload.Loader {
+	return func() (func(), error) {
+		path := viper.ConfigFileUsed()
+		if path == "" {
+			return nil, errNoConfigFile
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !force && bytes.Equal(data, c.configData) {
+			return nil, reload.ErrUnchanged
+		}
+		prev := c.configData
+		restore := func() {
+			c.configData = prev
+			if err := viper.ReadConfig(bytes.NewReader(prev)); err != nil {
+				c.logger.Errorw("restore config", "err", err)
+			}
+		}
+		c.configData = data
+		if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+			restore()
+			return nil, err
+		}
+		return restore, nil
+	}
+}
+
+// nacosLoader 应用nacos数据传输配置和grpc传输参数，失败时恢复修改的配置项
+// @param nf *platformConf.NodeConfig 数据传输配置，可为nil
+// @param grpcData string grpc传输参数json，覆盖当前值，开关和地址不在此修改，可为空
+func (c *ArcConsumerComponent) nacosLoader(nf *platformConf.NodeConfig, grpcData string) reload.Loader {
+	return func() (func(), error) {
+		keys := []string{simulate.KeyEnableCRCCheck, grpc.KeyGRPCEnable}
+		prev := make([]interface{}, len(keys))
+		for i, key := range keys {
+			prev[i] = viper.Get(key)
+		}
+		prevGrpc := grpc.GetConfig()
+		restore := func() {
+			for i, key := range keys {
+				viper.Set(key, prev[i])
+			}
+			grpc.SetConfig(prevGrpc)
+		}
+		if err := c.SetDynamicConfig(nf); err != nil {
+			restore()
+			return nil, err
+		}
+		if grpcData != "" {
+			cfg := *prevGrpc
+			if err := json.Unmarshal([]byte(grpcData), &cfg); err != nil {
+				restore()
+				return nil, err
+			}
+			grpc.SetConfig(&cfg)
+		}
+		return restore, nil
+	}
+}
+
+// listenGrpcConfig 读取并监听nacos中的grpc传输参数，与其他配置一起检查后热更新
+func (c *ArcConsumerComponent) listenGrpcConfig() error {
+	if c.nacosClient == nil || c.grpc == nil {
+		return nil
+	}
+	data, err := c.nacosClient.Get(grpcConfigDataID, micro.PlatformConfigGroup)
+	if err != nil {
+		c.logger.Warnw("get grpc dynamic config", "err", err, "dataID", grpcConfigDataID)
+	} else if data != "" {
+		c.reloader.Reload(reload.SourceNacos, c.nacosLoader(nil, data))
+	}
+	return c.nacosClient.Listen(grpcConfigDataID, micro.PlatformConfigGroup,
+		func(namespace, group, dataID, data string) {
+			c.reloader.Reload(reload.SourceNacos, c.nacosLoader(nil, data))
+		})
+}
+
+// watchConfig 监听配置文件修改后自动热更新
+func (c *ArcConsumerComponent) watchConfig(ctx context.Context) error {
+	cfg := reload.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	path := viper.ConfigFileUsed()
+	if !cfg.Watch || path == "" {
+		return nil
+	}
+	return reload.Watch(ctx, path, time.Duration(cfg.Debounce)*time.Millisecond, func() {
+		c.reloader.Reload(reload.SourceFile, c.fileLoader(false))
+	})
+}
@@ -0,0 +1,61 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/reload"
+)
+
+func TestNacosGrpcConfig(t *testing.T) {
+	grpc.SetDefaultConfig()
+	viper.Set(grpc.KeyGRPCEnable, true)
+	viper.Set(grpc.KeyGRPCServer, "arc-storage:8080")
+	defer viper.Set(grpc.KeyGRPCEnable, false)
+	defer viper.Set(grpc.KeyGRPCServer, "localhost:8080")
+	defer grpc.SetConfig(grpc.GetConfig())
+
+	c := &ArcConsumerComponent{
+		logger: new(logging.NoopLogger),
+		grpc:   grpc.New(),
+	}
+	c.reloader = reload.New(c.logger, nil, reload.Step{Name: "grpc", Prepare: c.prepareGrpc})
+	cases := []struct {
+		name      string
+		data      string
+		applied   bool
+		threshold int // 应用后的熔断阈值
+	}{
+		{"overlay", `{"breaker_threshold":3,"backoff_base_delay":500}`, true, 3},
+		{"enable and server ignored", `{"enable":false,"server":"other:9090","breaker_threshold":4}`, true, 4},
+		{"invalid json keeps current", `{"breaker_threshold":`, false, 4},
+		{"invalid value keeps current", `{"breaker_threshold":0}`, false, 4},
+		{"window below minimum keeps current", `{"breaker_threshold":6,"initial_window_size":1024}`, false, 4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := c.reloader.Reload(reload.SourceNacos, c.nacosLoader(nil, tc.data))
+			if result == nil || (result.Error == "") != tc.applied {
+				t.Fatalf("unexpected result %+v", result)
+			}
+			if c.reloader.Last() != result {
+				t.Fatal("result not recorded")
+			}
+			for _, cfg := range []*grpc.Config{c.grpc.Config(), grpc.GetConfig()} {
+				if cfg.BreakerThreshold != tc.threshold {
+					t.Fatalf("breaker threshold %d, want %d", cfg.BreakerThreshold, tc.threshold)
+				}
+				if !cfg.Enable || cfg.Server != "arc-storage:8080" {
+					t.Fatalf("enable/server changed: %v %s", cfg.Enable, cfg.Server)
+				}
+			}
+		})
+	}
+	// 未出现的字段保留当前值
+	if cfg := c.grpc.Config(); cfg.BackoffBaseDelay != 500 || cfg.DialTimeout != grpc.GetConfig().DialTimeout {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
@@ -0,0 +1,89 @@
+package component
+
+import (
+	"fmt"
+
+	"github.com/kiga-hub/arc-consumer/pkg/api"
+	"github.com/kiga-hub/arc-consumer/pkg/file"
+	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/kafka"
+	"github.com/kiga-hub/arc-consumer/pkg/mqtt"
+	"github.com/kiga-hub/arc-consumer/pkg/ring"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+	"github.com/kiga-hub/arc-consumer/pkg/taos"
+	"github.com/kiga-hub/arc-consumer/pkg/tcp"
+	"github.com/kiga-hub/arc-consumer/pkg/webhook"
+)
+
+const (
+	// defaultGrpcSinkName 未配置转发目标时，默认转发到arc-storage
+	defaultGrpcSinkName = "arc-storage"
+	// liveSinkName 实时波形推送转发目标
+	liveSinkName = "live"
+	// eventsSinkName 事件推送转发目标
+	eventsSinkName = "events"
+	// recentSinkName 最近数据缓存转发目标
+	recentSinkName = "recent"
+)
+
+// sinkConfigs 读取 [[sink]] 配置，grpc关闭时跳过grpc类型的转发目标
+func (c *ArcConsumerComponent) sinkConfigs() ([]*sink.Config, error) {
+	configs, err := sink.GetConfig()
+	if err != nil || c.grpc != nil {
+		return configs, err
+	}
+	list := make([]*sink.Config, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Type == grpc.SinkType && cfg.Enable {
+			c.logger.Warnw("grpc is disabled, skip sink", "name", cfg.Name)
+			continue
+		}
+		list = append(list, cfg)
+	}
+	return list, nil
+}
+
+// newSinks 根据 [[sink]] 配置创建转发目标
+func (c *ArcConsumerComponent) newSinks() (sink.Handler, error) {
+	configs, err := c.sinkConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sink.Option{
+		sink.WithLogger(c.logger),
+		sink.WithConfig(configs),
+		sink.WithFactory(grpc.SinkType, c.newGrpcSink),
+		sink.WithFactory(kafka.SinkType, kafka.NewSink),
+		sink.WithFactory(tcp.SinkType, tcp.NewSink),
+		sink.WithFactory(file.SinkType, file.NewSink),
+		sink.WithFactory(taos.SinkType, taos.NewSink),
+		sink.WithFactory(mqtt.SinkType, mqtt.NewSink),
+		sink.WithFactory(webhook.SinkType, webhook.NewSink),
+	}
+	if c.live != nil {
+		opts = append(opts, sink.WithSink(liveSinkName, api.LiveSinkType, c.live))
+	}
+	if c.events != nil {
+		opts = append(opts, sink.WithSink(eventsSinkName, api.EventsSinkType, c.events))
+	}
+	if c.recent != nil {
+		opts = append(opts, sink.WithSink(recentSinkName, ring.SinkType, c.recent))
+	}
+	if c.grpc != nil {
+		opts = append(opts, sink.WithDefault(&sink.Config{
+			Name:   defaultGrpcSinkName,
+			Type:   grpc.SinkType,
+			Enable: true,
+		}))
+	}
+	return sink.New(opts...)
+}
+
+// newGrpcSink grpc转发目标共用同一个grpc客户端服务
+func (c *ArcConsumerComponent) newGrpcSink(*sink.Config) (sink.Sink, error) {
+	if c.grpc == nil {
+		return nil, fmt.Errorf("grpc is disabled")
+	}
+	return grpc.NewSink(c.grpc), nil
+}
@@ -0,0 +1,31 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/file"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+func TestNewSinksGrpcDisabled(t *testing.T) {
+	file.SetDefaultConfig()
+	viper.Set(sink.KeySink, []map[string]interface{}{
+		{"name": "arc-storage", "type": "grpc", "enable": true, "segments": []int{10}},
+		{"name": "local", "type": "file", "enable": true, "options": map[string]interface{}{"dir": t.TempDir()}},
+	})
+	defer viper.Set(sink.KeySink, nil)
+
+	c := &ArcConsumerComponent{logger: new(logging.NoopLogger)}
+	sinks, err := c.newSinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sinks.Close()
+	list := sinks.Status()
+	if len(list) != 1 || list[0].Name != "local" {
+		t.Fatalf("unexpected sinks %+v", list)
+	}
+}
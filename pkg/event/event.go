@@ -0,0 +1,35 @@
+package event
+
+import (
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+)
+
+const (
+	// TypeArc 检测到电弧
+	TypeArc = "arc"
+	// TypeSensorOnline 传感器开始上报数据
+	TypeSensorOnline = "sensor_online"
+	// TypeSensorStale 传感器超过数个预期上报间隔未上报数据
+	TypeSensorStale = "sensor_stale"
+	// TypeSensorOffline 传感器停止上报数据，或预期上报但从未上报
+	TypeSensorOffline = "sensor_offline"
+	// TypeGrpcState grpc连接状态变化
+	TypeGrpcState = "grpc_state"
+	// TypePipelineState 数据管道状态变化
+	TypePipelineState = "pipeline_state"
+	// TypeSensorRegistered 未知传感器按策略登记，待审批时需通过接口审批
+	TypeSensorRegistered = "sensor_registered"
+	// TypeRateLimited 传感器或全局接收开始超过限流
+	TypeRateLimited = "rate_limited"
+)
+
+// Event - 事件记录
+type Event struct {
+	Type      string         `json:"type"`
+	ID        uint64         `json:"id,omitempty"`      // 传感器编号
+	SID       string         `json:"sid,omitempty"`     // 传感器字符串编号
+	Timestamp int64          `json:"timestamp"`         // 事件时间(毫秒)
+	Message   string         `json:"message,omitempty"` // 事件说明
+	Data      interface{}    `json:"data,omitempty"`    // 事件相关数据
+	Meta      *meta.Metadata `json:"meta,omitempty"`    // 传感器元数据
+}
@@ -0,0 +1,56 @@
+package feature
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyEnable       = "feature.enable"
+	keyRMSThreshold = "feature.event_rms_threshold"
+	keyHoldoff      = "feature.event_holdoff"
+)
+
+// 配置默认值
+var defaultConfig = Config{
+	Enable:       false,
+	RMSThreshold: 0,
+	Holdoff:      1000,
+}
+
+// Config - 特征提取配置
+type Config struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// RMSThreshold 有效值超过阈值时产生电弧事件，0不检测
+	RMSThreshold float64 `toml:"event_rms_threshold" json:"event_rms_threshold"`
+	// Holdoff 同一传感器两次电弧事件最小间隔(毫秒)
+	Holdoff int `toml:"event_holdoff" json:"event_holdoff"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyEnable, defaultConfig.Enable)
+	viper.SetDefault(keyRMSThreshold, defaultConfig.RMSThreshold)
+	viper.SetDefault(keyHoldoff, defaultConfig.Holdoff)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		Enable:       viper.GetBool(keyEnable),
+		RMSThreshold: viper.GetFloat64(keyRMSThreshold),
+		Holdoff:      viper.GetInt(keyHoldoff),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.RMSThreshold < 0 {
+		return fmt.Errorf("%s must not be negative, got %v", keyRMSThreshold, c.RMSThreshold)
+	}
+	if c.Holdoff < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", keyHoldoff, c.Holdoff)
+	}
+	return nil
+}
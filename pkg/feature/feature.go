@@ -0,0 +1,117 @@
+package feature
+
+import (
+	"math"
+	"sync"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+)
+
+// Feature - 单个Frame的SegmentArc统计特征
+type Feature struct {
+	ID            uint64         `json:"id"`
+	SID           string         `json:"sid"`
+	Timestamp     int64          `json:"timestamp"` // Frame时间戳(毫秒)
+	Samples       int            `json:"samples"`
+	Min           int32          `json:"min"`
+	Max           int32          `json:"max"`
+	Mean          float64        `json:"mean"`
+	RMS           float64        `json:"rms"`
+	Peak          int32          `json:"peak"`           // 绝对值最大
+	CrestFactor   float64        `json:"crest_factor"`   // 峰值/有效值
+	ZeroCrossings int            `json:"zero_crossings"` // 过零次数
+	Meta          *meta.Metadata `json:"meta,omitempty"` // 传感器元数据
+}
+
+// Extract - 计算样本统计特征
+// @param samples []int32 样本
+// @return *Feature 特征，未填充传感器信息
+func Extract(samples []int32) *Feature {
+	f := &Feature{Samples: len(samples)}
+	if len(samples) == 0 {
+		return f
+	}
+	f.Min, f.Max = samples[0], samples[0]
+	var sum, sumSquare float64
+	for i, v := range samples {
+		if v < f.Min {
+			f.Min = v
+		}
+		if v > f.Max {
+			f.Max = v
+		}
+		abs := v
+		if abs < 0 {
+			abs = -abs
+		}
+		if abs > f.Peak {
+			f.Peak = abs
+		}
+		sum += float64(v)
+		sumSquare += float64(v) * float64(v)
+		if i > 0 && (samples[i-1] < 0) != (v < 0) {
+			f.ZeroCrossings++
+		}
+	}
+	n := float64(len(samples))
+	f.Mean = sum / n
+	f.RMS = math.Sqrt(sumSquare / n)
+	if f.RMS > 0 {
+		f.CrestFactor = float64(f.Peak) / f.RMS
+	}
+	return f
+}
+
+// Extractor - 特征提取与电弧事件检测
+type Extractor struct {
+	config *Config
+	bits   int
+	last   *sync.Map // 传感器上次电弧事件时间
+}
+
+// New - 创建特征提取，未开启时返回nil
+// @param c *Config 特征提取配置
+// @param s *sample.Config 采样格式
+// @return *Extractor 特征提取
+func New(c *Config, s *sample.Config) *Extractor {
+	if !c.Enable {
+		return nil
+	}
+	return &Extractor{
+		config: c,
+		bits:   s.BitsPerSample,
+		last:   new(sync.Map),
+	}
+}
+
+// Process - 计算特征，有效值超过阈值且超过间隔时产生电弧事件
+// @param id uint64 传感器编号
+// @param sid string 传感器字符串编号
+// @param timestamp int64 Frame时间戳(毫秒)
+// @param data []byte SegmentArc数据
+// @return *Feature 特征
+// @return *event.Event 电弧事件，未检测到为nil
+func (e *Extractor) Process(id uint64, sid string, timestamp int64, data []byte) (*Feature, *event.Event) {
+	f := Extract(sample.Decode(data, e.bits))
+	f.ID = id
+	f.SID = sid
+	f.Timestamp = timestamp
+
+	if e.config.RMSThreshold <= 0 || f.RMS < e.config.RMSThreshold {
+		return f, nil
+	}
+	if v, ok := e.last.Load(id); ok && timestamp-v.(int64) < int64(e.config.Holdoff) {
+		return f, nil
+	}
+	e.last.Store(id, timestamp)
+	return f, &event.Event{
+		Type:      event.TypeArc,
+		ID:        id,
+		SID:       sid,
+		Timestamp: timestamp,
+		Message:   "rms over threshold",
+		Data:      f,
+	}
+}
@@ -0,0 +1,135 @@
+package feature
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+)
+
+// sine 整数周期正弦波
+func sine(amplitude float64, period, periods int) []int32 {
+	samples := make([]int32, period*periods)
+	for i := range samples {
+		samples[i] = int32(math.Round(amplitude * math.Sin(2*math.Pi*float64(i)/float64(period))))
+	}
+	return samples
+}
+
+func TestExtract(t *testing.T) {
+	cases := []struct {
+		name          string
+		samples       []int32
+		min, max      int32
+		peak          int32
+		mean          float64
+		rms           float64
+		crestFactor   float64
+		zeroCrossings int
+	}{
+		{"empty", nil, 0, 0, 0, 0, 0, 0, 0},
+		{"silence", []int32{0, 0, 0, 0}, 0, 0, 0, 0, 0, 0, 0},
+		{"dc", []int32{100, 100, 100, 100}, 100, 100, 100, 100, 100, 1, 0},
+		{"square", []int32{100, -100, 100, -100, 100, -100, 100, -100}, -100, 100, 100, 0, 100, 1, 7},
+		{"impulse", []int32{0, 0, 0, 1000}, 0, 1000, 1000, 250, 500, 2, 0},
+		{"negative peak", []int32{-300, 100}, -300, 100, 300, -100, math.Sqrt(50000), 300 / math.Sqrt(50000), 1},
+		// 每周期两次过零，最后一个周期结束于负半周
+		{"sine", sine(1000, 100, 4), -1000, 1000, 1000, 0, 1000 / math.Sqrt2, math.Sqrt2, 7},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := Extract(c.samples)
+			if f.Samples != len(c.samples) || f.Min != c.min || f.Max != c.max || f.Peak != c.peak || f.ZeroCrossings != c.zeroCrossings {
+				t.Fatalf("got samples %d min %d max %d peak %d zero crossings %d", f.Samples, f.Min, f.Max, f.Peak, f.ZeroCrossings)
+			}
+			for _, v := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", f.Mean, c.mean},
+				{"rms", f.RMS, c.rms},
+				{"crest factor", f.CrestFactor, c.crestFactor},
+			} {
+				if math.Abs(v.got-v.want) > 1e-3*math.Max(1, math.Abs(v.want)) {
+					t.Fatalf("%s: got %v, want %v", v.name, v.got, v.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	s := &sample.Config{SampleRate: 25600, BitsPerSample: 16, Channels: 1}
+	if New(&Config{Enable: false, RMSThreshold: 1}, s) != nil {
+		t.Fatal("disabled extractor should be nil")
+	}
+
+	loud, err := sample.Encode(sine(1000, 100, 1), s.BitsPerSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := sample.Encode(sine(100, 100, 1), s.BitsPerSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type frame struct {
+		id        uint64
+		timestamp int64
+		data      []byte
+		event     bool
+	}
+	cases := []struct {
+		name   string
+		config Config
+		frames []frame
+	}{
+		{"no threshold", Config{Enable: true, Holdoff: 1000}, []frame{
+			{1, 0, loud, false},
+		}},
+		{"below threshold", Config{Enable: true, RMSThreshold: 500, Holdoff: 1000}, []frame{
+			{1, 0, quiet, false},
+			{1, 100, quiet, false},
+		}},
+		{"holdoff", Config{Enable: true, RMSThreshold: 500, Holdoff: 1000}, []frame{
+			{1, 0, loud, true},
+			{1, 500, loud, false},
+			{1, 999, loud, false},
+			{1, 1000, loud, true},
+			{1, 1500, quiet, false},
+			{1, 2000, loud, true},
+		}},
+		{"holdoff per sensor", Config{Enable: true, RMSThreshold: 500, Holdoff: 1000}, []frame{
+			{1, 0, loud, true},
+			{2, 100, loud, true},
+			{1, 200, loud, false},
+			{2, 300, loud, false},
+		}},
+		{"quiet frame does not restart holdoff", Config{Enable: true, RMSThreshold: 500, Holdoff: 1000}, []frame{
+			{1, 0, loud, true},
+			{1, 900, quiet, false},
+			{1, 1000, loud, true},
+		}},
+		{"zero holdoff", Config{Enable: true, RMSThreshold: 500}, []frame{
+			{1, 0, loud, true},
+			{1, 0, loud, true},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := New(&c.config, s)
+			for i, fr := range c.frames {
+				f, ev := e.Process(fr.id, "sid", fr.timestamp, fr.data)
+				if f.ID != fr.id || f.Timestamp != fr.timestamp || f.Samples != 100 {
+					t.Fatalf("frame %d: unexpected feature %+v", i, f)
+				}
+				if (ev != nil) != fr.event {
+					t.Fatalf("frame %d: event %v, want %v", i, ev != nil, fr.event)
+				}
+				if ev != nil && (ev.Type != event.TypeArc || ev.ID != fr.id || ev.Timestamp != fr.timestamp || ev.Data != f) {
+					t.Fatalf("frame %d: unexpected event %+v", i, ev)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package file
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyDir        = "file.dir"
+	keyFormat     = "file.format"
+	keyMaxSize    = "file.max_size"
+	keyBufferSize = "file.buffer_size"
+	keyFsync      = "file.fsync"
+	keyRetention  = "file.retention"
+)
+
+const (
+	// FormatRaw 原始二进制Frame
+	FormatRaw = "raw"
+	// FormatBinary SegmentArc样本，大端有符号整数
+	FormatBinary = "binary"
+	// FormatCSV 每行一个采样时刻，时间戳(微秒)+各通道样本
+	FormatCSV = "csv"
+	// FormatWAV PCM格式WAV，采样率、位宽、通道数取自 [sample]
+	FormatWAV = "wav"
+
+	// FsyncNone 由操作系统决定落盘
+	FsyncNone = "none"
+	// FsyncFlush 定时写出缓存时落盘
+	FsyncFlush = "flush"
+	// FsyncAlways 每次写入后落盘
+	FsyncAlways = "always"
+)
+
+// maxWAVSize WAV文件最大字节数
+const maxWAVSize = 1<<32 - 1
+
+// 配置默认值
+var defaultConfig = Config{
+	Dir:        "./data",
+	Format:     FormatBinary,
+	MaxSize:    256 << 20,
+	BufferSize: 64 << 10,
+	Fsync:      FsyncFlush,
+	Retention:  0,
+}
+
+// Config - 文件存储配置，[file] 为默认值，[[sink]] options 可逐项覆盖
+type Config struct {
+	// Dir 存储根目录，按 传感器/yyyy/mm/dd/hh 分目录
+	Dir string `toml:"dir" json:"dir"`
+	// Format 文件格式: raw, binary, csv, wav
+	Format string `toml:"format" json:"format"`
+	// MaxSize 单个文件最大字节数，超过后切换新文件，0不限制
+	MaxSize int64 `toml:"max_size" json:"max_size"`
+	// BufferSize 写缓存字节数
+	BufferSize int `toml:"buffer_size" json:"buffer_size"`
+	// Fsync 落盘策略: none, flush, always
+	Fsync string `toml:"fsync" json:"fsync"`
+	// Retention 保留小时数，0不清理
+	Retention int `toml:"retention" json:"retention"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyDir, defaultConfig.Dir)
+	viper.SetDefault(keyFormat, defaultConfig.Format)
+	viper.SetDefault(keyMaxSize, defaultConfig.MaxSize)
+	viper.SetDefault(keyBufferSize, defaultConfig.BufferSize)
+	viper.SetDefault(keyFsync, defaultConfig.Fsync)
+	viper.SetDefault(keyRetention, defaultConfig.Retention)
+}
+
+// GetConfig - 获取 [file] 配置
+func GetConfig() *Config {
+	return &Config{
+		Dir:        viper.GetString(keyDir),
+		Format:     viper.GetString(keyFormat),
+		MaxSize:    viper.GetInt64(keyMaxSize),
+		BufferSize: viper.GetInt(keyBufferSize),
+		Fsync:      viper.GetString(keyFsync),
+		Retention:  viper.GetInt(keyRetention),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [file] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.Dir == "" {
+		return fmt.Errorf("%s is empty", keyDir)
+	}
+	switch c.Format {
+	case FormatRaw, FormatBinary, FormatCSV:
+	case FormatWAV:
+		// WAV数据长度字段为32位
+		if c.MaxSize <= 0 || c.MaxSize > maxWAVSize {
+			return fmt.Errorf("%s must be in (0, %d] for wav format, got %d", keyMaxSize, int64(maxWAVSize), c.MaxSize)
+		}
+	default:
+		return fmt.Errorf("unsupported file format %q", c.Format)
+	}
+	switch c.Fsync {
+	case FsyncNone, FsyncFlush, FsyncAlways:
+	default:
+		return fmt.Errorf("unsupported file fsync %q", c.Fsync)
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", keyMaxSize, c.MaxSize)
+	}
+	if c.BufferSize <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyBufferSize, c.BufferSize)
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", keyRetention, c.Retention)
+	}
+	return nil
+}
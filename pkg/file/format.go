@@ -0,0 +1,149 @@
+package file
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// encoder 文件格式
+type encoder interface {
+	// ext 文件扩展名
+	ext() string
+	// begin 新文件写入文件头，f 为新文件的第一个数据包
+	begin(w io.Writer, f *sink.Frame) error
+	// encode 写入一个数据包
+	encode(w io.Writer, f *sink.Frame) error
+}
+
+// rotator 可选接口，数据包与当前文件头不一致时切换新文件
+type rotator interface {
+	rotate(f *sink.Frame) bool
+}
+
+// finisher 可选接口，关闭或写出缓存后修正文件头
+type finisher interface {
+	finish(f *os.File, size int64) error
+}
+
+// newEncoder 按格式创建
+func newEncoder(format string, s *sample.Config) encoder {
+	switch format {
+	case FormatRaw:
+		return rawEncoder{}
+	case FormatCSV:
+		return &csvEncoder{sample: s}
+	case FormatWAV:
+		return &wavEncoder{sample: s}
+	}
+	return binaryEncoder{}
+}
+
+// rawEncoder 原始Frame
+type rawEncoder struct{}
+
+func (rawEncoder) ext() string { return ".frame" }
+
+func (rawEncoder) begin(io.Writer, *sink.Frame) error { return nil }
+
+func (rawEncoder) encode(w io.Writer, f *sink.Frame) error {
+	_, err := w.Write(f.Data)
+	return err
+}
+
+// binaryEncoder SegmentArc样本原样写入
+type binaryEncoder struct{}
+
+func (binaryEncoder) ext() string { return ".bin" }
+
+func (binaryEncoder) begin(io.Writer, *sink.Frame) error { return nil }
+
+func (binaryEncoder) encode(w io.Writer, f *sink.Frame) error {
+	_, err := w.Write(f.Arc)
+	return err
+}
+
+// csvEncoder 每行: 时间戳(微秒),通道0,通道1...，按传感器元数据的采样率计算时间戳
+type csvEncoder struct {
+	sample *sample.Config
+	buf    []byte
+}
+
+func (e *csvEncoder) ext() string { return ".csv" }
+
+func (e *csvEncoder) begin(w io.Writer, _ *sink.Frame) error {
+	line := []byte("timestamp_us")
+	for ch := 0; ch < e.sample.Channels; ch++ {
+		line = append(line, ",ch"...)
+		line = strconv.AppendInt(line, int64(ch), 10)
+	}
+	line = append(line, '\n')
+	_, err := w.Write(line)
+	return err
+}
+
+func (e *csvEncoder) encode(w io.Writer, f *sink.Frame) error {
+	samples := sample.Decode(f.Arc, e.sample.BitsPerSample)
+	channels := e.sample.Channels
+	rate := int64(meta.Rate(f.Meta, e.sample.SampleRate))
+	start := f.Timestamp * 1000
+	for i := 0; i+channels <= len(samples); i += channels {
+		e.buf = strconv.AppendInt(e.buf[:0], start+int64(i/channels)*1000000/rate, 10)
+		for _, v := range samples[i : i+channels] {
+			e.buf = append(e.buf, ',')
+			e.buf = strconv.AppendInt(e.buf, int64(v), 10)
+		}
+		e.buf = append(e.buf, '\n')
+		if _, err := w.Write(e.buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wavEncoder PCM格式WAV，文件头长度字段在写出缓存时修正
+// 文件头采样率取传感器元数据，采样率变化时切换新文件
+type wavEncoder struct {
+	sample *sample.Config
+	rate   int // 当前文件采样率
+}
+
+func (e *wavEncoder) ext() string { return ".wav" }
+
+func (e *wavEncoder) begin(w io.Writer, f *sink.Frame) error {
+	e.rate = meta.Rate(f.Meta, e.sample.SampleRate)
+	c := *e.sample
+	c.SampleRate = e.rate
+	_, err := w.Write(sample.WAVHeader(&c, 0))
+	return err
+}
+
+func (e *wavEncoder) rotate(f *sink.Frame) bool {
+	return meta.Rate(f.Meta, e.sample.SampleRate) != e.rate
+}
+
+func (e *wavEncoder) encode(w io.Writer, f *sink.Frame) error {
+	pcm, err := sample.WAVData(f.Arc, e.sample.BitsPerSample)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(pcm)
+	return err
+}
+
+func (e *wavEncoder) finish(f *os.File, size int64) error {
+	dataSize := uint32(size - sample.WAVHeaderSize)
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, 36+dataSize)
+	if _, err := f.WriteAt(buf, 4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(buf, dataSize)
+	_, err := f.WriteAt(buf, 40)
+	return err
+}
@@ -0,0 +1,103 @@
+package file
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kiga-hub/arc/protocols"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// Read - 从原始Frame文件读取时间范围内的数据包，其他格式不含Frame时间戳，不支持读取
+// @param sid string 传感器字符串编号
+// @param from int64 开始时间(毫秒)
+// @param to int64 结束时间(毫秒)
+// @return []*sink.Frame 数据包
+// @return err 错误信息
+func (s *Sink) Read(sid string, from, to int64) ([]*sink.Frame, error) {
+	if s.config.Format != FormatRaw || from > to {
+		return nil, nil
+	}
+
+	// 正在写入的文件先写出缓存
+	if v, ok := s.writers.Load(sid); ok {
+		w := v.(*writer)
+		w.mu.Lock()
+		var err error
+		if w.file != nil {
+			err = w.flush()
+		}
+		w.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var frames []*sink.Frame
+	end := time.UnixMilli(to)
+	for hour := time.UnixMilli(from).Truncate(time.Hour); !hour.After(end); hour = hour.Add(time.Hour) {
+		files, err := filepath.Glob(filepath.Join(s.config.Dir, sid, filepath.FromSlash(hour.Format(hourLayout)), sid+"_*"+rawEncoder{}.ext()))
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return fileIndex(files[i]) < fileIndex(files[j])
+		})
+		for _, name := range files {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, readFrames(sid, data, from, to)...)
+		}
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Timestamp < frames[j].Timestamp
+	})
+	return frames, nil
+}
+
+// fileIndex 文件名中的序号
+func fileIndex(name string) int {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	index, _ := strconv.Atoi(base[strings.LastIndex(base, "_")+1:])
+	return index
+}
+
+// readFrames 解析连续存放的Frame，跳过无法解析的数据包，末尾未写完整的数据包忽略
+func readFrames(sid string, data []byte, from, to int64) []*sink.Frame {
+	var frames []*sink.Frame
+	decoder := protocols.NewDefaultFrame()
+	for off := 0; off+protocols.DefaultHeadLength <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[off+4:off+8])) + protocols.DefaultHeadLength
+		if n <= protocols.DefaultHeadLength || off+n > len(data) {
+			break
+		}
+		raw := data[off : off+n]
+		off += n
+		if err := decoder.Decode(raw); err != nil {
+			continue
+		}
+		if decoder.Timestamp < from || decoder.Timestamp > to {
+			continue
+		}
+		sa, err := decoder.DataGroup.GetArcSegment()
+		if err != nil {
+			continue
+		}
+		frames = append(frames, &sink.Frame{
+			SID:       sid,
+			Timestamp: decoder.Timestamp,
+			STypes:    append([]byte(nil), decoder.DataGroup.STypes...),
+			Data:      raw,
+			Arc:       sa.Data,
+		})
+	}
+	return frames
+}
@@ -0,0 +1,299 @@
+package file
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType 文件存储转发目标类型
+const SinkType = "file"
+
+const (
+	// idleTimeout 超过该时间未写入的文件关闭，释放文件句柄
+	idleTimeout = time.Minute
+	// cleanInterval 过期目录清理间隔
+	cleanInterval = 10 * time.Minute
+	// hourLayout 小时目录
+	hourLayout = "2006/01/02/15"
+)
+
+// writer 单个传感器当前写入的文件
+type writer struct {
+	mu      sync.Mutex
+	sid     string
+	encoder encoder
+	file    *os.File
+	buf     *bufio.Writer
+	hour    time.Time // 当前文件所属小时
+	index   int       // 同一小时内文件序号
+	size    int64
+	last    time.Time // 最后写入时间
+}
+
+// Sink - 按 传感器/yyyy/mm/dd/hh 分目录存储数据
+type Sink struct {
+	config    *Config
+	sample    *sample.Config
+	writers   *sync.Map
+	mu        sync.Mutex
+	lastClean time.Time
+}
+
+// NewSink - 根据转发目标配置创建文件存储
+// @param c *sink.Config 转发目标配置，options覆盖 [file] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, sample.GetConfig())
+}
+
+// New - 创建文件存储
+// @param cfg *Config 文件存储配置
+// @param s *sample.Config 采样格式
+// @return *Sink 转发目标
+// @return err 错误信息
+func New(cfg *Config, s *sample.Config) (*Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Sink{
+		config:  cfg,
+		sample:  s,
+		writers: new(sync.Map),
+	}, nil
+}
+
+// Write - 按Frame时间戳写入对应小时的文件
+func (s *Sink) Write(f *sink.Frame) error {
+	v, ok := s.writers.Load(f.SID)
+	if !ok {
+		v, _ = s.writers.LoadOrStore(f.SID, &writer{
+			sid:     f.SID,
+			encoder: newEncoder(s.config.Format, s.sample),
+		})
+	}
+	w := v.(*writer)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hour := time.UnixMilli(f.Timestamp).Truncate(time.Hour)
+	if w.file != nil && !hour.Equal(w.hour) {
+		if err := s.closeWriter(w); err != nil {
+			return err
+		}
+		w.index = 0
+	}
+	if w.file != nil && s.config.MaxSize > 0 && w.size >= s.config.MaxSize {
+		if err := s.closeWriter(w); err != nil {
+			return err
+		}
+		w.index++
+	}
+	if r, ok := w.encoder.(rotator); ok && w.file != nil && r.rotate(f) {
+		if err := s.closeWriter(w); err != nil {
+			return err
+		}
+		w.index++
+	}
+	if w.file == nil {
+		if err := s.openWriter(w, hour, f); err != nil {
+			return err
+		}
+	}
+
+	if err := w.encoder.encode(w, f); err != nil {
+		return err
+	}
+	w.last = time.Now()
+	if s.config.Fsync == FsyncAlways {
+		return w.sync()
+	}
+	return nil
+}
+
+// Write - 写入缓存并计数
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// flush 写出缓存，修正文件头
+func (w *writer) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if fin, ok := w.encoder.(finisher); ok {
+		return fin.finish(w.file, w.size)
+	}
+	return nil
+}
+
+// sync 写出缓存并落盘
+func (w *writer) sync() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// path 文件路径: 根目录/传感器/yyyy/mm/dd/hh/传感器_yyyymmddhh_序号.扩展名
+func (s *Sink) path(w *writer, hour time.Time) string {
+	name := fmt.Sprintf("%s_%s_%d%s", w.sid, hour.Format("2006010215"), w.index, w.encoder.ext())
+	return filepath.Join(s.config.Dir, w.sid, filepath.FromSlash(hour.Format(hourLayout)), name)
+}
+
+// openWriter 打开新文件，跳过已存在的序号，服务重启后不覆盖旧数据
+func (s *Sink) openWriter(w *writer, hour time.Time, f *sink.Frame) error {
+	path := s.path(w, hour)
+	for {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		w.index++
+		path = s.path(w, hour)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.buf = bufio.NewWriterSize(file, s.config.BufferSize)
+	w.hour = hour
+	w.size = 0
+	return w.encoder.begin(w, f)
+}
+
+// closeWriter 写出缓存并关闭文件
+func (s *Sink) closeWriter(w *writer) error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.flush()
+	if s.config.Fsync != FsyncNone {
+		err = errors.Join(err, w.file.Sync())
+	}
+	err = errors.Join(err, w.file.Close())
+	w.file = nil
+	w.buf = nil
+	return err
+}
+
+// Flush - 写出缓存，关闭空闲文件，定时清理过期目录
+func (s *Sink) Flush() error {
+	var errs []error
+	now := time.Now()
+	s.writers.Range(func(key, value interface{}) bool {
+		w := value.(*writer)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.file == nil {
+			return true
+		}
+		if now.Sub(w.last) > idleTimeout {
+			errs = append(errs, s.closeWriter(w))
+			return true
+		}
+		if err := w.flush(); err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		if s.config.Fsync == FsyncFlush {
+			errs = append(errs, w.file.Sync())
+		}
+		return true
+	})
+
+	s.mu.Lock()
+	clean := s.config.Retention > 0 && now.Sub(s.lastClean) >= cleanInterval
+	if clean {
+		s.lastClean = now
+	}
+	s.mu.Unlock()
+	if clean {
+		errs = append(errs, s.clean(now))
+	}
+	return errors.Join(errs...)
+}
+
+// clean 删除保留时间之前的小时目录及空的上级目录
+func (s *Sink) clean(now time.Time) error {
+	deadline := now.Add(-time.Duration(s.config.Retention) * time.Hour).Truncate(time.Hour)
+	dirs, err := filepath.Glob(filepath.Join(s.config.Dir, "*", "*", "*", "*", "*"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(s.config.Dir, dir)
+		if err != nil {
+			continue
+		}
+		// 传感器/yyyy/mm/dd/hh
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		hour, err := time.ParseInLocation(hourLayout, parts[1], time.Local)
+		if err != nil || !hour.Before(deadline) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// 上级 日/月/年 目录为空时删除，非空时删除失败忽略
+		parent := filepath.Dir(dir)
+		for i := 0; i < 3; i++ {
+			if os.Remove(parent) != nil {
+				break
+			}
+			parent = filepath.Dir(parent)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close - 关闭全部文件
+func (s *Sink) Close() error {
+	var errs []error
+	s.writers.Range(func(key, value interface{}) bool {
+		w := value.(*writer)
+		w.mu.Lock()
+		errs = append(errs, s.closeWriter(w))
+		w.mu.Unlock()
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// Health - 存储目录可写时健康
+func (s *Sink) Health() sink.Health {
+	info, err := os.Stat(s.config.Dir)
+	if err != nil {
+		return sink.Health{Healthy: false, Message: err.Error()}
+	}
+	if !info.IsDir() {
+		return sink.Health{Healthy: false, Message: s.config.Dir + " is not a directory"}
+	}
+	return sink.Health{Healthy: true}
+}
@@ -0,0 +1,281 @@
+package file
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kiga-hub/arc/protocols"
+
+	"github.com/kiga-hub/arc-consumer/pkg/frame"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+var testSample = &sample.Config{SampleRate: 1000, BitsPerSample: 16, Channels: 1}
+
+func testConfig(t *testing.T, format string) *Config {
+	cfg := defaultConfig
+	cfg.Dir = t.TempDir()
+	cfg.Format = format
+	return &cfg
+}
+
+func testFrame(sid string, ts time.Time, samples ...int32) *sink.Frame {
+	arc, _ := sample.Encode(samples, 16)
+	return &sink.Frame{SID: sid, Timestamp: ts.UnixMilli(), Data: []byte{0xFC, 0xFC, 0xFC, 0xFC}, Arc: arc}
+}
+
+func files(t *testing.T, dir string) []string {
+	var list []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			list = append(list, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestSinkHourPartition(t *testing.T) {
+	cfg := testConfig(t, FormatBinary)
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := time.Date(2023, 5, 6, 7, 0, 0, 0, time.Local)
+	for _, f := range []*sink.Frame{
+		testFrame("94C96000C248", hour, 1, 2),
+		testFrame("94C96000C248", hour.Add(time.Minute), 3),
+		testFrame("94C96000C248", hour.Add(time.Hour), 4),
+		testFrame("94C96000C249", hour, 5),
+	} {
+		if err := s.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{
+		"94C96000C248/2023/05/06/07/94C96000C248_2023050607_0.bin": 6,
+		"94C96000C248/2023/05/06/08/94C96000C248_2023050608_0.bin": 2,
+		"94C96000C249/2023/05/06/07/94C96000C249_2023050607_0.bin": 2,
+	}
+	got := files(t, cfg.Dir)
+	if len(got) != len(want) {
+		t.Fatalf("files %v", got)
+	}
+	for name, size := range want {
+		info, err := os.Stat(filepath.Join(cfg.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(size) {
+			t.Fatalf("%s size %d, want %d", name, info.Size(), size)
+		}
+	}
+}
+
+func TestSinkCSV(t *testing.T) {
+	cfg := testConfig(t, FormatCSV)
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2023, 5, 6, 7, 0, 0, 0, time.Local)
+	if err := s.Write(testFrame("94C96000C248", ts, 10, -20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.Dir, "94C96000C248/2023/05/06/07/94C96000C248_2023050607_0.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := ts.UnixMilli() * 1000
+	want := strings.Join([]string{
+		"timestamp_us,ch0",
+		strconv.FormatInt(us, 10) + ",10",
+		strconv.FormatInt(us+1000, 10) + ",-20",
+		"",
+	}, "\n")
+	if string(data) != want {
+		t.Fatalf("got %q, want %q", data, want)
+	}
+}
+
+func TestSinkRotateAndRetention(t *testing.T) {
+	cfg := testConfig(t, FormatRaw)
+	cfg.MaxSize = 4
+	cfg.Retention = 1
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	old := now.Add(-3 * time.Hour)
+	for _, ts := range []time.Time{old, now, now} {
+		if err := s.Write(testFrame("94C96000C248", ts)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过单文件大小切换序号，过期小时目录被清理
+	hour := now.Truncate(time.Hour)
+	dir := "94C96000C248/" + hour.Format(hourLayout) + "/94C96000C248_" + hour.Format("2006010215")
+	got := files(t, cfg.Dir)
+	if len(got) != 2 || got[0] != dir+"_0.frame" || got[1] != dir+"_1.frame" {
+		t.Fatalf("files %v", got)
+	}
+}
+
+// encodeFrame 打包带SegmentArc的二进制Frame
+func encodeFrame(t *testing.T, sid string, ts time.Time, samples ...int32) *sink.Frame {
+	f := testFrame(sid, ts, samples...)
+	sa := protocols.NewDefaultSegmentArc()
+	sa.Data = f.Arc
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(sa)
+	p := protocols.NewDefaultFrame()
+	p.Timestamp = f.Timestamp
+	p.SetDataGroup(g)
+	data, err := frame.Encode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Data = data
+	f.STypes = []byte{protocols.STypeArc}
+	return f
+}
+
+func TestSinkWAV(t *testing.T) {
+	cfg := testConfig(t, FormatWAV)
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2023, 5, 6, 7, 0, 0, 0, time.Local)
+	for i := 0; i < 2; i++ {
+		if err := s.Write(testFrame("94C96000C248", ts, 1, -1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.Dir, "94C96000C248/2023/05/06/07/94C96000C248_2023050607_0.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(sample.WAVHeader(testSample, 8), 1, 0, 0xFF, 0xFF, 1, 0, 0xFF, 0xFF)
+	if !bytes.Equal(data, want) {
+		t.Fatalf("got %v, want %v", data, want)
+	}
+}
+
+func TestSinkMetaRate(t *testing.T) {
+	ts := time.Date(2023, 5, 6, 7, 0, 0, 0, time.Local)
+	slow := &meta.Metadata{SID: "94C96000C248", SampleRate: 500}
+
+	// csv按元数据采样率计算时间戳
+	cfg := testConfig(t, FormatCSV)
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := testFrame("94C96000C248", ts, 10, -20)
+	f.Meta = slow
+	if err := s.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.Dir, "94C96000C248/2023/05/06/07/94C96000C248_2023050607_0.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := ts.UnixMilli() * 1000
+	if want := "timestamp_us,ch0\n" + strconv.FormatInt(us, 10) + ",10\n" + strconv.FormatInt(us+2000, 10) + ",-20\n"; string(data) != want {
+		t.Fatalf("got %q, want %q", data, want)
+	}
+
+	// wav文件头采样率取元数据，采样率变化时切换新文件
+	cfg = testConfig(t, FormatWAV)
+	if s, err = New(cfg, testSample); err != nil {
+		t.Fatal(err)
+	}
+	frames := []*sink.Frame{testFrame("94C96000C248", ts, 1), testFrame("94C96000C248", ts, 2), testFrame("94C96000C248", ts, 3)}
+	frames[0].Meta = slow
+	frames[1].Meta = slow
+	for _, f := range frames {
+		if err := s.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	slowSample := *testSample
+	slowSample.SampleRate = 500
+	for name, want := range map[string][]byte{
+		"_0.wav": append(sample.WAVHeader(&slowSample, 4), 1, 0, 2, 0),
+		"_1.wav": append(sample.WAVHeader(testSample, 2), 3, 0),
+	} {
+		data, err := os.ReadFile(filepath.Join(cfg.Dir, "94C96000C248/2023/05/06/07/94C96000C248_2023050607"+name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Fatalf("%s: got %v, want %v", name, data, want)
+		}
+	}
+}
+
+func TestSinkRead(t *testing.T) {
+	cfg := testConfig(t, FormatRaw)
+	s, err := New(cfg, testSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ts := time.Date(2023, 5, 6, 7, 59, 59, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		if err := s.Write(encodeFrame(t, "94C96000C248", ts.Add(time.Duration(i)*time.Second), int32(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 跨小时读取，未关闭的文件先写出缓存
+	frames, err := s.Read("94C96000C248", ts.Add(time.Second).UnixMilli(), ts.Add(time.Hour).UnixMilli())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	for i, f := range frames {
+		if got := sample.Decode(f.Arc, 16); len(got) != 1 || got[0] != int32(i+1) {
+			t.Fatalf("frame %d samples %v", i, got)
+		}
+	}
+}
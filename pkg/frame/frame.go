@@ -0,0 +1,53 @@
+package frame
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kiga-hub/arc/protocols"
+	"github.com/kiga-hub/arc/utils"
+)
+
+// Encode - 按 protocols.Frame.Decode 的格式打包Frame
+// protocols.Frame.Encode 会在数据段前多写类型列表并覆盖最后一个字节，打包结果无法被Decode正确解析
+// 格式: Head(4) Size(4) Timestamp(8) ID(6) Count(1) Sizes(4*n) Segments Crc(2) End(1)
+// @param f *protocols.Frame 数据包，Size由数据段重新计算
+// @return []byte 二进制数据包
+// @return err 错误信息
+func Encode(f *protocols.Frame) ([]byte, error) {
+	dg := &f.DataGroup
+	if len(dg.Segments) != len(dg.Sizes) {
+		return nil, fmt.Errorf("segment count %d don't match size count %d", len(dg.Segments), len(dg.Sizes))
+	}
+	size := protocols.LengthWithoutData + 1
+	for _, seg := range dg.Segments {
+		size += 4 + seg.Size()
+	}
+
+	buf := make([]byte, int(size)+protocols.DefaultHeadLength)
+	idx := copy(buf, f.Head[:])
+	binary.BigEndian.PutUint32(buf[idx:], size)
+	idx += 4
+	binary.BigEndian.PutUint64(buf[idx:], uint64(f.Timestamp))
+	idx += 8
+	idx += copy(buf[idx:], f.ID[:])
+
+	buf[idx] = byte(len(dg.Segments))
+	idx++
+	for _, seg := range dg.Segments {
+		binary.BigEndian.PutUint32(buf[idx:], seg.Size())
+		idx += 4
+	}
+	for _, seg := range dg.Segments {
+		n, err := seg.Encode(buf[idx:])
+		if err != nil {
+			return nil, err
+		}
+		idx += n
+	}
+
+	binary.BigEndian.PutUint16(buf[idx:], utils.CheckSum(buf[protocols.DefaultHeadLength:idx]))
+	idx += 2
+	buf[idx] = protocols.End
+	return buf, nil
+}
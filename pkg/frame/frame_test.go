@@ -0,0 +1,39 @@
+package frame
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kiga-hub/arc/protocols"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	sa := protocols.NewDefaultSegmentArc()
+	sa.SetData([]byte{1, 2, 3, 4, 5})
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(sa)
+	f := protocols.NewDefaultFrame()
+	f.SetID(15)
+	f.Timestamp = 1683356400000
+	f.SetDataGroup(g)
+
+	buf, err := Encode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protocols.FrameValidate(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got := protocols.NewDefaultFrame()
+	if err := got.Decode(buf); err != nil {
+		t.Fatal(err)
+	}
+	arc, err := got.DataGroup.GetArcSegment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Timestamp != f.Timestamp || got.ID != f.ID || !bytes.Equal(arc.Data, sa.Data) {
+		t.Fatalf("decoded %d %v %v", got.Timestamp, got.ID, arc.Data)
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/kiga-hub/arc/logging"
 	microComponent "github.com/kiga-hub/arc/micro/component"
+
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
 )
 
 // Handler -
@@ -71,7 +73,7 @@ func (s *Server) Start(ctx context.Context) {
 				return true
 			})
 			if len(sensors) > 0 {
-				err := s.gossipKVCache.HaveSensorIDs(sensors)
+				err := s.haveSensorIDs(sensors)
 				if err != nil {
 					s.logger.Error(err)
 				}
@@ -96,10 +98,20 @@ func (s *Server) Sync(sensorid uint64) error {
 		lastPkg:  time.Now().Unix(),
 	}
 	s.logger.Debugw("gossipKVCache", "time", time.Unix(c.last, 0).Format("2006-01-02 15:04:05"))
-	if err := s.gossipKVCache.HaveSensorIDs([]string{c.sensorID}); err != nil {
+	if err := s.haveSensorIDs([]string{c.sensorID}); err != nil {
 		c.last = 0
 		return err
 	}
 	s.cache.Store(sensorid, c)
 	return nil
 }
+
+// haveSensorIDs 上报传感器编号到集群，统计调用次数
+func (s *Server) haveSensorIDs(sensors []string) error {
+	metrics.GossipSyncs.Inc()
+	err := s.gossipKVCache.HaveSensorIDs(sensors)
+	if err != nil {
+		metrics.GossipSyncErrors.Inc()
+	}
+	return err
+}
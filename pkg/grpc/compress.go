@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/golang/snappy"
+	proto "github.com/kiga-hub/arc/protobuf/pb"
+	"github.com/kiga-hub/arc/protocols"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+
+	"github.com/kiga-hub/arc-consumer/pkg/frame"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+)
+
+const (
+	// CompressorNone 不压缩
+	CompressorNone = ""
+	// CompressorGzip gzip压缩
+	CompressorGzip = gzip.Name
+	// CompressorSnappy snappy压缩
+	CompressorSnappy = "snappy"
+
+	// EncodingNone SegmentArc 原样发送
+	EncodingNone = ""
+	// EncodingDelta SegmentArc 样本差分+deflate编码
+	EncodingDelta = "delta"
+
+	// MetadataEncoding 数据流元数据，告知arc-storage SegmentArc编码方式
+	MetadataEncoding = "arc-encoding"
+	// MetadataEncodingBits 数据流元数据，差分编码样本位宽
+	MetadataEncodingBits = "arc-encoding-bits"
+)
+
+func init() {
+	encoding.RegisterCompressor(&snappyCompressor{})
+}
+
+// snappyCompressor - gRPC snappy压缩
+type snappyCompressor struct {
+	writers sync.Pool
+}
+
+// Name -
+func (c *snappyCompressor) Name() string {
+	return CompressorSnappy
+}
+
+// Compress -
+func (c *snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if sw, ok := c.writers.Get().(*snappyWriter); ok {
+		sw.Writer.Reset(w)
+		return sw, nil
+	}
+	return &snappyWriter{Writer: snappy.NewBufferedWriter(w), pool: &c.writers}, nil
+}
+
+// Decompress -
+func (c *snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
+
+// snappyWriter 关闭后放回复用
+type snappyWriter struct {
+	*snappy.Writer
+	pool *sync.Pool
+}
+
+// Close -
+func (w *snappyWriter) Close() error {
+	defer w.pool.Put(w)
+	return w.Writer.Close()
+}
+
+// CompressionStat - 传感器压缩统计
+type CompressionStat struct {
+	ID           uint64  `json:"id"`
+	SID          string  `json:"sid"`
+	RawBytes     int64   `json:"raw_bytes"`     // 原始Frame字节数
+	EncodedBytes int64   `json:"encoded_bytes"` // SegmentArc编码后字节数
+	WireBytes    int64   `json:"wire_bytes"`    // gRPC压缩后字节数
+	Ratio        float64 `json:"ratio"`         // 原始/压缩后
+}
+
+// compressionCounter 传感器压缩计数
+type compressionCounter struct {
+	sid     string
+	raw     *atomic.Int64
+	encoded *atomic.Int64
+	wire    *atomic.Int64
+}
+
+// counter 获取传感器压缩计数
+func (s *Server) counter(id uint64, sid string) *compressionCounter {
+	if v, ok := s.compression.Load(id); ok {
+		return v.(*compressionCounter)
+	}
+	v, _ := s.compression.LoadOrStore(id, &compressionCounter{
+		sid:     sid,
+		raw:     atomic.NewInt64(0),
+		encoded: atomic.NewInt64(0),
+		wire:    atomic.NewInt64(0),
+	})
+	return v.(*compressionCounter)
+}
+
+// Compression - 获取每个传感器的压缩统计
+func (s *Server) Compression() []CompressionStat {
+	list := []CompressionStat{}
+	s.compression.Range(func(key, value interface{}) bool {
+		c := value.(*compressionCounter)
+		stat := CompressionStat{
+			ID:           key.(uint64),
+			SID:          c.sid,
+			RawBytes:     c.raw.Load(),
+			EncodedBytes: c.encoded.Load(),
+			WireBytes:    c.wire.Load(),
+		}
+		if stat.WireBytes > 0 {
+			stat.Ratio = float64(stat.RawBytes) / float64(stat.WireBytes)
+		}
+		list = append(list, stat)
+		return true
+	})
+	return list
+}
+
+// encodeArc 将Frame中SegmentArc样本做差分编码后重新打包
+func encodeArc(value []byte, bits int) ([]byte, error) {
+	f := protocols.NewDefaultFrame()
+	if err := f.Decode(value); err != nil {
+		return nil, err
+	}
+	sa, err := f.DataGroup.GetArcSegment()
+	if err != nil {
+		return nil, err
+	}
+	if sa.Data, err = sample.DeltaEncode(sa.Data, bits); err != nil {
+		return nil, err
+	}
+	return frame.Encode(f)
+}
+
+// streamContext 按编码方式生成数据流上下文，arc-storage据此协商解码
+func streamContext(arcEncoding string, bits int) context.Context {
+	ctx := context.Background()
+	if arcEncoding == EncodingNone {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx,
+		MetadataEncoding, arcEncoding,
+		MetadataEncodingBits, strconv.Itoa(bits),
+	)
+}
+
+// statsHandler 统计每个传感器gRPC压缩后的字节数
+type statsHandler struct {
+	srv *Server
+}
+
+// TagRPC -
+func (h *statsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// HandleRPC -
+func (h *statsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	p, ok := s.(*stats.OutPayload)
+	if !ok {
+		return
+	}
+	req, ok := p.Payload.(*proto.FrameDataRequest)
+	if !ok || len(req.Key) != 6 {
+		return
+	}
+	key := make([]byte, 8)
+	copy(key[2:], req.Key)
+	if v, ok := h.srv.compression.Load(binary.BigEndian.Uint64(key)); ok {
+		v.(*compressionCounter).wire.Add(int64(p.CompressedLength))
+	}
+}
+
+// TagConn -
+func (h *statsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn -
+func (h *statsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// validCompression 检查压缩配置
+func validCompression(compressor, arcEncoding string) error {
+	switch compressor {
+	case CompressorNone, CompressorGzip, CompressorSnappy:
+	default:
+		return fmt.Errorf("unsupported grpc compressor %q", compressor)
+	}
+	switch arcEncoding {
+	case EncodingNone, EncodingDelta:
+	default:
+		return fmt.Errorf("unsupported arc encoding %q", arcEncoding)
+	}
+	return nil
+}
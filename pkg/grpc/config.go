@@ -1,36 +1,203 @@
 package grpc
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 const (
 	// KeyGRPCEnable -
 	KeyGRPCEnable = "grpc.enable"
 	// KeyGRPCServer for data transfer
 	KeyGRPCServer = "grpc.server"
+
+	keyDialTimeout           = "grpc.dial_timeout"
+	keyKeepAliveTime         = "grpc.keepalive_time"
+	keyKeepAliveTimeout      = "grpc.keepalive_timeout"
+	keyInitialWindowSize     = "grpc.initial_window_size"
+	keyInitialConnWindowSize = "grpc.initial_conn_window_size"
+	keyMaxSendMsgSize        = "grpc.max_send_msg_size"
+	keyMaxRecvMsgSize        = "grpc.max_recv_msg_size"
+	keyBackoffBaseDelay      = "grpc.backoff_base_delay"
+	keyBackoffMaxDelay       = "grpc.backoff_max_delay"
+	keyBreakerThreshold      = "grpc.breaker_threshold"
+	keyBreakerOpenTimeout    = "grpc.breaker_open_timeout"
+	keyHealthCheckInterval   = "grpc.health_check_interval"
+	keyHealthCheckTimeout    = "grpc.health_check_timeout"
+	keyCompressor            = "grpc.compressor"
+	keyArcEncoding           = "grpc.arc_encoding"
 )
 
+// minWindowSize gRPC 要求窗口大小不小于 64KB，否则设置无效
+const minWindowSize = 64 * 1024
+
+// 配置默认值，时间单位为毫秒
 var defaultConfig = Config{
-	Enable: false,
-	Server: "localhost:8080",
+	Enable:                false,
+	Server:                "localhost:8080",
+	DialTimeout:           5000,
+	KeepAliveTime:         1000,
+	KeepAliveTimeout:      1000,
+	InitialWindowSize:     1 << 30,
+	InitialConnWindowSize: 1 << 30,
+	MaxSendMsgSize:        4 << 30,
+	MaxRecvMsgSize:        4 << 30,
+	BackoffBaseDelay:      1000,
+	BackoffMaxDelay:       30000,
+	BreakerThreshold:      5,
+	BreakerOpenTimeout:    10000,
+	HealthCheckInterval:   10000,
+	HealthCheckTimeout:    1000,
+	Compressor:            CompressorNone,
+	ArcEncoding:           EncodingNone,
 }
 
 // Config struct grpc配置信息结构
 type Config struct {
-	Enable bool   `toml:"enable"`
-	Server string `toml:"server"`
+	Enable bool   `toml:"enable" json:"enable"`
+	Server string `toml:"server" json:"server,omitempty"`
+	// DialTimeout 建立连接超时(毫秒)
+	DialTimeout int `toml:"dial_timeout" json:"dial_timeout,omitempty"`
+	// KeepAliveTime 无数据时发送ping的间隔(毫秒)
+	// KeepAliveTime = 10s 在40s时，会发送失败，设置60s，在240s，发送失败
+	KeepAliveTime int `toml:"keepalive_time" json:"keepalive_time,omitempty"`
+	// KeepAliveTimeout ping超时(毫秒)，超时后关闭连接
+	KeepAliveTimeout int `toml:"keepalive_timeout" json:"keepalive_timeout,omitempty"`
+	// InitialWindowSize 流窗口大小(字节)
+	InitialWindowSize int `toml:"initial_window_size" json:"initial_window_size,omitempty"`
+	// InitialConnWindowSize 连接窗口大小(字节)
+	InitialConnWindowSize int `toml:"initial_conn_window_size" json:"initial_conn_window_size,omitempty"`
+	// MaxSendMsgSize 单条发送消息最大字节数
+	MaxSendMsgSize int `toml:"max_send_msg_size" json:"max_send_msg_size,omitempty"`
+	// MaxRecvMsgSize 单条接收消息最大字节数
+	MaxRecvMsgSize int `toml:"max_recv_msg_size" json:"max_recv_msg_size,omitempty"`
+	// BackoffBaseDelay 首次重连等待(毫秒)，每次失败翻倍
+	BackoffBaseDelay int `toml:"backoff_base_delay" json:"backoff_base_delay,omitempty"`
+	// BackoffMaxDelay 重连最大等待(毫秒)
+	BackoffMaxDelay int `toml:"backoff_max_delay" json:"backoff_max_delay,omitempty"`
+	// BreakerThreshold 连续发送失败次数达到后打开熔断
+	BreakerThreshold int `toml:"breaker_threshold" json:"breaker_threshold,omitempty"`
+	// BreakerOpenTimeout 熔断打开后多久探测恢复(毫秒)
+	BreakerOpenTimeout int `toml:"breaker_open_timeout" json:"breaker_open_timeout,omitempty"`
+	// HealthCheckInterval 可用连接健康检查间隔(毫秒)
+	HealthCheckInterval int `toml:"health_check_interval" json:"health_check_interval,omitempty"`
+	// HealthCheckTimeout 单次健康检查超时(毫秒)
+	HealthCheckTimeout int `toml:"health_check_timeout" json:"health_check_timeout,omitempty"`
+	// Compressor gRPC消息压缩: 空不压缩, gzip, snappy
+	Compressor string `toml:"compressor" json:"compressor"`
+	// ArcEncoding SegmentArc样本编码: 空不编码, delta 差分+deflate，通过数据流元数据告知arc-storage
+	ArcEncoding string `toml:"arc_encoding" json:"arc_encoding"`
 }
 
 // SetDefaultConfig - 设置grpc配置参数
 func SetDefaultConfig() {
 	viper.SetDefault(KeyGRPCEnable, defaultConfig.Enable)
 	viper.SetDefault(KeyGRPCServer, defaultConfig.Server)
+	viper.SetDefault(keyDialTimeout, defaultConfig.DialTimeout)
+	viper.SetDefault(keyKeepAliveTime, defaultConfig.KeepAliveTime)
+	viper.SetDefault(keyKeepAliveTimeout, defaultConfig.KeepAliveTimeout)
+	viper.SetDefault(keyInitialWindowSize, defaultConfig.InitialWindowSize)
+	viper.SetDefault(keyInitialConnWindowSize, defaultConfig.InitialConnWindowSize)
+	viper.SetDefault(keyMaxSendMsgSize, defaultConfig.MaxSendMsgSize)
+	viper.SetDefault(keyMaxRecvMsgSize, defaultConfig.MaxRecvMsgSize)
+	viper.SetDefault(keyBackoffBaseDelay, defaultConfig.BackoffBaseDelay)
+	viper.SetDefault(keyBackoffMaxDelay, defaultConfig.BackoffMaxDelay)
+	viper.SetDefault(keyBreakerThreshold, defaultConfig.BreakerThreshold)
+	viper.SetDefault(keyBreakerOpenTimeout, defaultConfig.BreakerOpenTimeout)
+	viper.SetDefault(keyHealthCheckInterval, defaultConfig.HealthCheckInterval)
+	viper.SetDefault(keyHealthCheckTimeout, defaultConfig.HealthCheckTimeout)
+	viper.SetDefault(keyCompressor, defaultConfig.Compressor)
+	viper.SetDefault(keyArcEncoding, defaultConfig.ArcEncoding)
 }
 
 // GetConfig - 获取grpc配置参数
 // @return Config grpc配置数据结构
 func GetConfig() *Config {
 	return &Config{
-		Enable: viper.GetBool(KeyGRPCEnable),
-		Server: viper.GetString(KeyGRPCServer),
+		Enable:                viper.GetBool(KeyGRPCEnable),
+		Server:                viper.GetString(KeyGRPCServer),
+		DialTimeout:           viper.GetInt(keyDialTimeout),
+		KeepAliveTime:         viper.GetInt(keyKeepAliveTime),
+		KeepAliveTimeout:      viper.GetInt(keyKeepAliveTimeout),
+		InitialWindowSize:     viper.GetInt(keyInitialWindowSize),
+		InitialConnWindowSize: viper.GetInt(keyInitialConnWindowSize),
+		MaxSendMsgSize:        viper.GetInt(keyMaxSendMsgSize),
+		MaxRecvMsgSize:        viper.GetInt(keyMaxRecvMsgSize),
+		BackoffBaseDelay:      viper.GetInt(keyBackoffBaseDelay),
+		BackoffMaxDelay:       viper.GetInt(keyBackoffMaxDelay),
+		BreakerThreshold:      viper.GetInt(keyBreakerThreshold),
+		BreakerOpenTimeout:    viper.GetInt(keyBreakerOpenTimeout),
+		HealthCheckInterval:   viper.GetInt(keyHealthCheckInterval),
+		HealthCheckTimeout:    viper.GetInt(keyHealthCheckTimeout),
+		Compressor:            viper.GetString(keyCompressor),
+		ArcEncoding:           viper.GetString(keyArcEncoding),
+	}
+}
+
+// SetConfig - 写入grpc传输参数，Enable和Server不在此修改
+// @param c *Config grpc配置数据结构
+func SetConfig(c *Config) {
+	viper.Set(keyDialTimeout, c.DialTimeout)
+	viper.Set(keyKeepAliveTime, c.KeepAliveTime)
+	viper.Set(keyKeepAliveTimeout, c.KeepAliveTimeout)
+	viper.Set(keyInitialWindowSize, c.InitialWindowSize)
+	viper.Set(keyInitialConnWindowSize, c.InitialConnWindowSize)
+	viper.Set(keyMaxSendMsgSize, c.MaxSendMsgSize)
+	viper.Set(keyMaxRecvMsgSize, c.MaxRecvMsgSize)
+	viper.Set(keyBackoffBaseDelay, c.BackoffBaseDelay)
+	viper.Set(keyBackoffMaxDelay, c.BackoffMaxDelay)
+	viper.Set(keyBreakerThreshold, c.BreakerThreshold)
+	viper.Set(keyBreakerOpenTimeout, c.BreakerOpenTimeout)
+	viper.Set(keyHealthCheckInterval, c.HealthCheckInterval)
+	viper.Set(keyHealthCheckTimeout, c.HealthCheckTimeout)
+	viper.Set(keyCompressor, c.Compressor)
+	viper.Set(keyArcEncoding, c.ArcEncoding)
+}
+
+// Validate - 检查grpc配置参数
+// @return err 错误信息
+func (c *Config) Validate() error {
+	if c.Enable && c.Server == "" {
+		return fmt.Errorf("grpc server is empty")
 	}
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{keyDialTimeout, c.DialTimeout},
+		{keyKeepAliveTime, c.KeepAliveTime},
+		{keyKeepAliveTimeout, c.KeepAliveTimeout},
+		{keyMaxSendMsgSize, c.MaxSendMsgSize},
+		{keyMaxRecvMsgSize, c.MaxRecvMsgSize},
+		{keyBackoffBaseDelay, c.BackoffBaseDelay},
+		{keyBreakerThreshold, c.BreakerThreshold},
+		{keyBreakerOpenTimeout, c.BreakerOpenTimeout},
+		{keyHealthCheckInterval, c.HealthCheckInterval},
+		{keyHealthCheckTimeout, c.HealthCheckTimeout},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", p.name, p.value)
+		}
+	}
+	if c.InitialWindowSize < minWindowSize || c.InitialWindowSize > 1<<31-1 {
+		return fmt.Errorf("%s out of range [%d, %d]: %d", keyInitialWindowSize, minWindowSize, 1<<31-1, c.InitialWindowSize)
+	}
+	if c.InitialConnWindowSize < minWindowSize || c.InitialConnWindowSize > 1<<31-1 {
+		return fmt.Errorf("%s out of range [%d, %d]: %d", keyInitialConnWindowSize, minWindowSize, 1<<31-1, c.InitialConnWindowSize)
+	}
+	if c.BackoffMaxDelay < c.BackoffBaseDelay {
+		return fmt.Errorf("%s(%d) less than %s(%d)", keyBackoffMaxDelay, c.BackoffMaxDelay, keyBackoffBaseDelay, c.BackoffBaseDelay)
+	}
+	if c.HealthCheckTimeout >= c.HealthCheckInterval {
+		return fmt.Errorf("%s(%d) must be less than %s(%d)", keyHealthCheckTimeout, c.HealthCheckTimeout, keyHealthCheckInterval, c.HealthCheckInterval)
+	}
+	return validCompression(c.Compressor, c.ArcEncoding)
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
 }
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		valid  bool
+	}{
+		{"default", func(c *Config) {}, true},
+		{"enabled without server", func(c *Config) { c.Enable = true; c.Server = "" }, false},
+		{"disabled without server", func(c *Config) { c.Server = "" }, true},
+		{"zero dial timeout", func(c *Config) { c.DialTimeout = 0 }, false},
+		{"negative breaker threshold", func(c *Config) { c.BreakerThreshold = -1 }, false},
+		{"small window", func(c *Config) { c.InitialWindowSize = minWindowSize - 1 }, false},
+		{"large conn window", func(c *Config) { c.InitialConnWindowSize = 1 << 31 }, false},
+		{"max delay below base", func(c *Config) { c.BackoffBaseDelay = 2000; c.BackoffMaxDelay = 1000 }, false},
+		{"max delay equals base", func(c *Config) { c.BackoffBaseDelay = 1000; c.BackoffMaxDelay = 1000 }, true},
+		{"health timeout not below interval", func(c *Config) { c.HealthCheckTimeout = c.HealthCheckInterval }, false},
+		{"gzip delta", func(c *Config) { c.Compressor = CompressorGzip; c.ArcEncoding = EncodingDelta }, true},
+		{"unknown compressor", func(c *Config) { c.Compressor = "zstd" }, false},
+		{"unknown encoding", func(c *Config) { c.ArcEncoding = "flac" }, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultConfig
+			tc.modify(&c)
+			if err := c.Validate(); (err == nil) != tc.valid {
+				t.Fatalf("valid %v, got err %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	c := defaultConfig
+	c.Enable = true
+	srv := New(WithConfig(&c)).(*Server)
+	p := newConn(0, &c, nil)
+	p.backoff.Attempt = 3
+	p.breaker.failures = 2
+	srv.pools.Store(uint64(0), p)
+
+	invalid := c
+	invalid.BreakerThreshold = 0
+	if err := srv.UpdateConfig(&invalid); err == nil {
+		t.Fatal("invalid config should be rejected")
+	}
+	if srv.Config() != &c || p.breaker.threshold != c.BreakerThreshold {
+		t.Fatal("invalid config should keep current")
+	}
+
+	updated := c
+	updated.BackoffBaseDelay = 200
+	updated.BackoffMaxDelay = 800
+	updated.BreakerThreshold = 2
+	updated.BreakerOpenTimeout = 3000
+	if err := srv.UpdateConfig(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Config() != &updated {
+		t.Fatal("config not updated")
+	}
+	// 已有连接使用新的退避和熔断参数，保留重连次数和失败计数
+	if p.backoff.Base != 200*time.Millisecond || p.backoff.Max != 800*time.Millisecond || p.backoff.Attempt != 3 {
+		t.Fatalf("backoff not updated %+v", p.backoff)
+	}
+	if p.breaker.threshold != 2 || p.breaker.timeout != 3*time.Second || p.breaker.failures != 2 {
+		t.Fatalf("breaker not updated %+v", p.breaker)
+	}
+	if !p.breaker.failure(time.Now()) {
+		t.Fatal("breaker should open at new threshold")
+	}
+}
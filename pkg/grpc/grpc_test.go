@@ -1,19 +1,28 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/kiga-hub/arc/protobuf/pb"
 	"github.com/kiga-hub/arc/protocols"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kiga-hub/arc-consumer/pkg/frame"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
 )
 
 // ProtoStream -
@@ -214,3 +223,153 @@ func getFrame(seq int64) []byte {
 	}
 	return buf
 }
+
+func TestEncodeArc(t *testing.T) {
+	samples := make([]byte, 1024)
+	for i := range samples {
+		samples[i] = byte(i * 7)
+	}
+	sa := protocols.NewDefaultSegmentArc()
+	sa.SetData(samples)
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(sa)
+	p := protocols.NewDefaultFrame()
+	p.SetID(15)
+	p.Timestamp = 1683356400000
+	p.SetDataGroup(g)
+	value, err := frame.Encode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := encodeArc(value, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := protocols.NewDefaultFrame()
+	if err := got.Decode(encoded); err != nil {
+		t.Fatal(err)
+	}
+	arc, err := got.DataGroup.GetArcSegment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := sample.DeltaDecode(arc.Data, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Timestamp != p.Timestamp || !bytes.Equal(decoded, samples) {
+		t.Fatalf("decoded timestamp %d, %d samples bytes", got.Timestamp, len(decoded))
+	}
+}
+
+func TestStateListener(t *testing.T) {
+	SetDefaultConfig()
+	var changes []*StateChange
+	p := newConn(1, GetConfig(), func(c *StateChange) { changes = append(changes, c) })
+	p.setState(StateConnecting)
+	p.fail(errors.New("refused"))
+	p.setState(StateReady)
+	p.setState(StateReady)
+	if len(changes) != 1 || changes[0].From != "connecting" || changes[0].To != "ready" || changes[0].Error != "" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	p.lastErr = errors.New("unavailable")
+	p.setState(StateOpen)
+	if len(changes) != 2 || changes[1].To != "open-circuit" || changes[1].Error != "unavailable" {
+		t.Fatalf("unexpected changes %+v", changes[1:])
+	}
+}
+
+func TestSetServer(t *testing.T) {
+	SetDefaultConfig()
+	viper.Set(KeyGRPCEnable, true)
+	defer viper.Set(KeyGRPCEnable, false)
+	defer viper.Set(KeyGRPCServer, defaultConfig.Server)
+	srv := New().(*Server)
+	p := newConn(0, srv.getConfig(), nil)
+	srv.pools.Store(uint64(0), p)
+
+	if err := srv.SetServer("arc-storage"); err == nil {
+		t.Fatal("address without port should be rejected")
+	}
+	if err := srv.SetServer("arc-storage:9090"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.getConfig().Server != "arc-storage:9090" || GetConfig().Server != "arc-storage:9090" {
+		t.Fatalf("server not changed: %s", srv.getConfig().Server)
+	}
+	status := srv.Status()
+	if len(status) != 1 || !status[0].Reconn || status[0].InFlight != 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+// traceServer 记录数据流元数据
+type traceServer struct {
+	pb.UnimplementedFrameDataServer
+	md chan metadata.MD
+}
+
+func (t *traceServer) FrameDataCallback(request pb.FrameData_FrameDataCallbackServer) error {
+	md, _ := metadata.FromIncomingContext(request.Context())
+	t.md <- md
+	for {
+		if _, err := request.Recv(); err != nil {
+			return request.SendAndClose(&pb.FrameDataResponse{Successed: true})
+		}
+	}
+}
+
+func TestStreamTrace(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &traceServer{md: make(chan metadata.MD, 1)}
+	grpcserver := grpc.NewServer()
+	pb.RegisterFrameDataServer(grpcserver, ts)
+	go func() { _ = grpcserver.Serve(listen) }()
+	defer grpcserver.Stop()
+
+	SetDefaultConfig()
+	c := GetConfig()
+	c.Enable = true
+	c.Server = listen.Addr().String()
+	tracer := mocktracer.New()
+	srv := New(WithConfig(c), WithTracer(tracer)).(*Server)
+	srv.running.Store(true)
+	defer srv.Stop()
+
+	parent := tracer.StartSpan("frame")
+	if err := srv.WriteTrace(parent, 0, "0", getFrame(0)); err != nil {
+		t.Fatal(err)
+	}
+	parent.Finish()
+
+	var md metadata.MD
+	select {
+	case md = <-ts.md:
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream not opened")
+	}
+	spans := map[string]*mocktracer.MockSpan{}
+	for _, span := range tracer.FinishedSpans() {
+		spans[span.OperationName] = span
+	}
+	stream, send := spans["FrameDataCallback"], spans["grpc.send"]
+	if stream == nil || send == nil {
+		t.Fatalf("unexpected spans %v", tracer.FinishedSpans())
+	}
+	if stream.Tag("span.kind") != ext.SpanKindRPCClientEnum {
+		t.Fatalf("stream span kind %v", stream.Tag("span.kind"))
+	}
+	// 数据流跟踪上下文通过元数据传递给arc-storage
+	if got := md.Get("mockpfx-ids-traceid"); len(got) != 1 || got[0] != strconv.Itoa(stream.SpanContext.TraceID) {
+		t.Fatalf("trace id metadata %v, want %d", got, stream.SpanContext.TraceID)
+	}
+	// 发送跟踪以数据包跟踪为上级，引用数据流跟踪
+	if send.ParentID != parent.(*mocktracer.MockSpan).SpanContext.SpanID {
+		t.Fatalf("send span parent %d", send.ParentID)
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"github.com/kiga-hub/arc/logging"
+	"github.com/opentracing/opentracing-go"
 )
 
 // Option is a function that will set up option.
@@ -34,3 +35,17 @@ func WithConfig(c *Config) Option {
 		opts.config = c
 	}
 }
+
+// WithStateListener - 连接状态变化回调
+func WithStateListener(l StateListener) Option {
+	return func(opts *Server) {
+		opts.listener = l
+	}
+}
+
+// WithTracer - 跟踪上报，数据流打开时跟踪上下文写入gRPC元数据
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(opts *Server) {
+		opts.tracer = tracer
+	}
+}
@@ -3,78 +3,117 @@ package grpc
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/kiga-hub/arc/logging"
 	proto "github.com/kiga-hub/arc/protobuf/pb"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/spf13/viper"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
-)
-
-const (
-	// DialTimeout the timeout of create connection
-	DialTimeout = 5 * time.Second
-
-	// BackoffMaxDelay provided maximum delay when backing off after failed connection attempts.
-	BackoffMaxDelay = 3 * time.Second
-
-	// KeepAliveTime is the duration of time after which if the client doesn't see
-	// any activity it pings the server to see if the transport is still alive.
-	KeepAliveTime = time.Second // time.Duration(10) *
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
-	// KeepAliveTimeout is the duration of time for which the client waits after having
-	// pinged for keepalive check and if no activity is seen even after that the connection
-	// is closed.
-	KeepAliveTimeout = time.Second // time.Duration(3) *
-
-	// InitialWindowSize we set it 1GB is to provide system's throughput.
-	InitialWindowSize = 1 << 30
+	"github.com/kiga-hub/arc-consumer/pkg/backoff"
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
+)
 
-	// InitialConnWindowSize we set it 1GB is to provide system's throughput.
-	InitialConnWindowSize = 1 << 30
+// errNotServing arc-storage 健康检查返回非 SERVING
+var errNotServing = errors.New("arc-storage not serving")
 
-	// MaxSendMsgSize set max gRPC request message size sent to server.
-	// If any request message size is larger than current value, an error will be reported from gRPC.
-	MaxSendMsgSize = 4 << 30
-
-	// MaxRecvMsgSize set max gRPC arc-consumer message size arc-consumer from server.
-	// If any message size is larger than current value, an error will be reported from gRPC.
-	MaxRecvMsgSize = 4 << 30
-)
+// ErrDisconnected 已断开arc-storage连接，数据包未发送
+var ErrDisconnected = errors.New("grpc disconnected")
 
 // Handler - grpc接口定义
 type Handler interface {
 	Start(ctx context.Context)
 	Write(uint64, string, []byte) error
+	WriteTrace(opentracing.Span, uint64, string, []byte) error
 	Stop()
 	SetMask(uint64)
 	ReConnect()
 	Disconnect()
+	SetServer(string) error
+	Status() []PoolStatus
+	Compression() []CompressionStat
+	UpdateConfig(*Config) error
+	Config() *Config
+}
+
+// PoolStatus - 连接池状态
+type PoolStatus struct {
+	Key        uint64    `json:"key"`
+	Addr       string    `json:"addr"`
+	State      string    `json:"state"`
+	LastError  string    `json:"last_error,omitempty"`
+	Reconnects int64     `json:"reconnects"`
+	Failures   int       `json:"failures"`
+	Since      time.Time `json:"since"`
+	Valid      bool      `json:"valid"`     // 数据流可用
+	Reconn     bool      `json:"reconn"`    // 等待下次写入时重建连接
+	InFlight   int64     `json:"in_flight"` // 正在发送或等待发送的写入数
+	Sent       int64     `json:"sent"`      // 发送成功数
+	Errors     int64     `json:"errors"`    // 发送失败数
+	LastSend   time.Time `json:"last_send"` // 最近发送成功时间
 }
 
 // Conn - 连接
 type Conn struct {
+	mu         sync.Mutex
+	key        uint64
+	addr       string
 	conn       *grpc.ClientConn
 	grpcclient proto.FrameDataClient
+	health     healthpb.HealthClient
 	grpcstream proto.FrameData_FrameDataCallbackClient
+	encoding   string
+	trace      opentracing.SpanContext // 数据流跟踪，采样数据包的发送跟踪引用该数据流
 	valid      bool
 	reconn     bool
+	closed     bool
+	dialing    bool
+	state      State
+	since      time.Time
+	lastErr    error
+	reconnects int64
+	nextRetry  time.Time
+	lastProbe  time.Time
+	backoff    *backoff.Backoff
+	breaker    *breaker
+	listener   StateListener
+	inFlight   *atomic.Int64
+	sent       int64
+	errors     int64
+	lastSend   time.Time
 }
 
 // Server -
 type Server struct {
-	pools     *sync.Map
-	mask      uint64
-	logger    logging.ILogger
-	config    *Config
-	running   *atomic.Bool
-	closeChan chan struct{}
+	pools       *sync.Map
+	compression *sync.Map
+	bits        int
+	mask        *atomic.Uint64
+	logger      logging.ILogger
+	configMu    sync.RWMutex
+	config      *Config
+	running     *atomic.Bool
+	closeChan   chan struct{}
+	listener    StateListener
+	tracer      opentracing.Tracer
 }
 
 // New - 初始化grpc服务
@@ -88,6 +127,9 @@ func New(opts ...Option) Handler {
 	}
 
 	srv.pools = new(sync.Map)
+	srv.compression = new(sync.Map)
+	srv.bits = sample.GetConfig().BitsPerSample
+	srv.mask = atomic.NewUint64(0)
 	srv.running = atomic.NewBool(false)
 	srv.closeChan = make(chan struct{})
 
@@ -96,24 +138,202 @@ func New(opts ...Option) Handler {
 	return srv
 }
 
+func newConn(key uint64, c *Config, listener StateListener) *Conn {
+	p := &Conn{
+		key:      key,
+		state:    StateConnecting,
+		since:    time.Now(),
+		listener: listener,
+		inFlight: atomic.NewInt64(0),
+		backoff:  &backoff.Backoff{},
+		breaker:  &breaker{},
+	}
+	p.configure(c)
+	return p
+}
+
+// configure 按配置设置退避和熔断参数，保留当前重连次数和失败计数，调用方持有锁
+func (p *Conn) configure(c *Config) {
+	p.backoff.Base = millisecond(c.BackoffBaseDelay)
+	p.backoff.Max = millisecond(c.BackoffMaxDelay)
+	p.breaker.threshold = c.BreakerThreshold
+	p.breaker.timeout = millisecond(c.BreakerOpenTimeout)
+}
+
+// setState 切换连接状态，调用方持有锁
+func (p *Conn) setState(state State) {
+	if p.state == state {
+		return
+	}
+	change := &StateChange{
+		Key:  p.key,
+		Addr: p.addr,
+		From: p.state.String(),
+		To:   state.String(),
+	}
+	if p.lastErr != nil && state != StateReady {
+		change.Error = p.lastErr.Error()
+	}
+	p.state = state
+	p.since = time.Now()
+	metrics.GrpcState.WithLabelValues(poolLabel(p.key)).Set(float64(state))
+	if p.listener != nil {
+		p.listener(change)
+	}
+}
+
+// poolLabel 连接池指标标签
+func poolLabel(key uint64) string {
+	return strconv.FormatUint(key, 10)
+}
+
+// fail 连接失败，进入退避重连，调用方持有锁
+func (p *Conn) fail(err error) time.Duration {
+	p.lastErr = err
+	p.valid = false
+	p.reconnects++
+	metrics.GrpcReconnects.WithLabelValues(poolLabel(p.key)).Inc()
+	p.setState(StateConnecting)
+	d := p.backoff.Next()
+	p.nextRetry = time.Now().Add(d)
+	return d
+}
+
+// closeStream 关闭数据流，调用方持有锁
+func (p *Conn) closeStream() {
+	if p.grpcstream != nil {
+		// 注意： 这里不能用 CloseSend , 不然每次新建连接时，goroutine会不断增加，每次增加1个
+		_, _ = p.grpcstream.CloseAndRecv()
+		p.grpcstream = nil
+	}
+	p.valid = false
+}
+
+func (s *Server) getConfig() *Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// Config - 当前使用的配置，不可修改
+func (s *Server) Config() *Config {
+	return s.getConfig()
+}
+
+// UpdateConfig - 更新传输参数，不中断已有连接
+// 退避、熔断和健康检查参数对已有连接立即生效，拨号、窗口和压缩参数在新建连接或数据流时生效
+// @param c *Config grpc配置数据结构
+// @return err 错误信息
+func (s *Server) UpdateConfig(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	s.configMu.Lock()
+	s.config = c
+	s.configMu.Unlock()
+	s.pools.Range(func(key, value interface{}) bool {
+		p := value.(*Conn)
+		p.mu.Lock()
+		p.configure(c)
+		p.mu.Unlock()
+		return true
+	})
+	s.logger.Infow("grpc config updated", "config", c)
+	return nil
+}
+
 // ReConnect -
 func (s *Server) ReConnect() {
-	s.config = GetConfig()
+	c := GetConfig()
+	if err := c.Validate(); err != nil {
+		s.logger.Errorw("grpc config invalid, keep current", "err", err)
+		c = s.getConfig()
+	}
+	s.configMu.Lock()
+	s.config = c
+	s.configMu.Unlock()
 	s.pools.Range(func(key, value interface{}) bool {
-		value.(*Conn).reconn = true
+		p := value.(*Conn)
+		p.mu.Lock()
+		p.reconn = true
+		p.mu.Unlock()
 		return true
 	})
 	s.running.Store(true)
 }
 
-// Disconnect -
+// Disconnect - 停止发送，之后写入返回 ErrDisconnected，ReConnect 恢复
 func (s *Server) Disconnect() {
 	s.running.Store(false)
 }
 
-// SetMask -
+// SetServer - 运行时修改arc-storage地址，已有连接在下次写入时重建到新地址
+// 同时写入配置，ReConnect 重新加载配置时保持新地址
+// @param addr string 目标地址 host:port
+// @return err 错误信息
+func (s *Server) SetServer(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid grpc server %q: %v", addr, err)
+	}
+	c := *s.getConfig()
+	c.Server = addr
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	viper.Set(KeyGRPCServer, addr)
+	s.configMu.Lock()
+	s.config = &c
+	s.configMu.Unlock()
+	s.pools.Range(func(key, value interface{}) bool {
+		p := value.(*Conn)
+		p.mu.Lock()
+		p.reconn = true
+		p.mu.Unlock()
+		return true
+	})
+	s.logger.Infow("grpc server changed", "addr", addr)
+	return nil
+}
+
+// SetMask - 设置连接池编号掩码，运行中修改需在数据管道排空后调用，关闭不再使用的连接池
 func (s *Server) SetMask(mask uint64) {
-	s.mask = mask
+	s.mask.Store(mask)
+	s.pools.Range(func(key, value interface{}) bool {
+		if k := key.(uint64); k&mask != k {
+			s.closePool(k, value.(*Conn))
+		}
+		return true
+	})
+}
+
+// Status - 获取每个连接池的状态
+func (s *Server) Status() []PoolStatus {
+	list := []PoolStatus{}
+	s.pools.Range(func(key, value interface{}) bool {
+		p := value.(*Conn)
+		p.mu.Lock()
+		ps := PoolStatus{
+			Key:        p.key,
+			Addr:       p.addr,
+			State:      p.state.String(),
+			Reconnects: p.reconnects,
+			Failures:   p.breaker.failures,
+			Since:      p.since,
+			Valid:      p.valid,
+			Reconn:     p.reconn,
+			InFlight:   p.inFlight.Load(),
+			Sent:       p.sent,
+			Errors:     p.errors,
+			LastSend:   p.lastSend,
+		}
+		if p.lastErr != nil {
+			ps.LastError = p.lastErr.Error()
+		}
+		p.mu.Unlock()
+		list = append(list, ps)
+		return true
+	})
+	return list
 }
 
 // Start target server of grpc
@@ -132,137 +352,437 @@ func (s *Server) Start(ctx context.Context) {
 			if !s.running.Load() {
 				continue
 			}
-			var connections int
+			// 逐个检查连接状态，单个连接失败不影响其他连接
 			s.pools.Range(func(key, value interface{}) bool {
-				conn := value.(*Conn)
-				if conn.valid {
-					connections++
-					return true
-				}
-				if conn.reconn {
-					return true
-				}
-
-				grpcstream, err := conn.grpcclient.FrameDataCallback(context.Background())
-				if err != nil {
-					return false
-				}
-
-				conn.grpcstream = grpcstream
-				conn.valid = true
-				connections++
-				s.logger.Infow("grpc replay", "mask", key, "addr", s.config.Server)
+				s.check(value.(*Conn))
 				return true
 			})
 		}
 	}
 }
 
+// check 连接状态机，按状态重连、探测或恢复
+func (s *Server) check(p *Conn) {
+	now := time.Now()
+	p.mu.Lock()
+	if p.closed || p.reconn || p.dialing {
+		p.mu.Unlock()
+		return
+	}
+	state := p.state
+	health := p.health
+	switch state {
+	case StateConnecting:
+		if now.Before(p.nextRetry) {
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+		_ = s.connect(p)
+		return
+	case StateOpen:
+		if !p.breaker.allowProbe(now) {
+			p.mu.Unlock()
+			return
+		}
+	case StateReady, StateDegraded:
+		if now.Sub(p.lastProbe) < millisecond(s.getConfig().HealthCheckInterval) {
+			p.mu.Unlock()
+			return
+		}
+	}
+	p.lastProbe = now
+	p.mu.Unlock()
+
+	err := s.probe(health)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.state != state {
+		return
+	}
+	switch state {
+	case StateOpen:
+		if err != nil {
+			p.lastErr = err
+			p.breaker.trip(now)
+			return
+		}
+		s.halfOpen(p, now)
+	case StateReady:
+		if err != nil {
+			p.lastErr = err
+			p.setState(StateDegraded)
+			s.logger.Warnw("grpc health check", "err", err, "mask", p.key, "addr", p.addr)
+		}
+	case StateDegraded:
+		if err == nil && p.breaker.failures == 0 {
+			p.setState(StateReady)
+		}
+	}
+}
+
+// halfOpen 熔断探测成功，重新打开数据流进入半开，打开数据流时不持有锁，调用方持有锁
+func (s *Server) halfOpen(p *Conn, now time.Time) {
+	p.closeStream()
+	p.dialing = true
+	client := p.grpcclient
+	p.mu.Unlock()
+
+	stream, arcEncoding, streamTrace, err := s.newStream(client)
+
+	p.mu.Lock()
+	p.dialing = false
+	if p.closed || p.reconn {
+		if stream != nil {
+			_, _ = stream.CloseAndRecv()
+		}
+		return
+	}
+	if err != nil {
+		p.lastErr = err
+		p.breaker.trip(now)
+		return
+	}
+	p.grpcstream = stream
+	p.encoding = arcEncoding
+	p.trace = streamTrace
+	p.valid = true
+	p.setState(StateDegraded)
+	s.logger.Infow("grpc circuit half-open", "mask", p.key, "addr", p.addr)
+}
+
+// probe gRPC 健康检查，服务端未实现健康检查时视为健康
+func (s *Server) probe(health healthpb.HealthClient) error {
+	if health == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), millisecond(s.getConfig().HealthCheckTimeout))
+	defer cancel()
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return errNotServing
+	}
+	return nil
+}
+
+// connect 建立连接，健康检查通过后打开数据流，耗时操作不持有锁
+// @return err 连接失败错误，已进入退避重连
+func (s *Server) connect(p *Conn) error {
+	p.mu.Lock()
+	if p.dialing || p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.dialing = true
+	conn := p.conn
+	p.mu.Unlock()
+
+	addr := s.getConfig().Server
+	var err error
+	dialed := conn == nil
+	if dialed {
+		conn, err = s.factory()
+	}
+	var health healthpb.HealthClient
+	var client proto.FrameDataClient
+	var stream proto.FrameData_FrameDataCallbackClient
+	var arcEncoding string
+	var streamTrace opentracing.SpanContext
+	if err == nil {
+		health = healthpb.NewHealthClient(conn)
+		client = proto.NewFrameDataClient(conn)
+		if err = s.probe(health); err == nil {
+			stream, arcEncoding, streamTrace, err = s.newStream(client)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dialing = false
+	if p.closed {
+		// 连接期间已停止或重连，关闭本次打开的数据流和连接
+		if stream != nil {
+			_, _ = stream.CloseAndRecv()
+		}
+		if dialed && conn != nil {
+			conn.Close()
+		}
+		return err
+	}
+	if conn != nil && p.conn == nil {
+		p.conn = conn
+		p.addr = addr
+	}
+	if err != nil {
+		d := p.fail(err)
+		s.logger.Warnw("grpc connect", "err", err, "mask", p.key, "addr", addr, "retry", d)
+		return err
+	}
+	p.health = health
+	p.grpcclient = client
+	p.grpcstream = stream
+	p.encoding = arcEncoding
+	p.trace = streamTrace
+	p.valid = true
+	p.lastProbe = time.Now()
+	p.backoff.Reset()
+	p.breaker.success()
+	p.setState(StateReady)
+	s.logger.Infow("grpc connnect", "mask", p.key, "addr", p.addr)
+	return nil
+}
+
 // Dial return a grpc connection with defined configurations.
 // @return grpc.ClientConn grpc客户端连接
 // @return err 错误信息
 // Dial return a grpc connection with defined configurations.
 func (s *Server) factory() (*grpc.ClientConn, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DialTimeout)
+	c := s.getConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), millisecond(c.DialTimeout))
 	defer cancel()
-	return grpc.DialContext(ctx, s.config.Server,
+	return grpc.DialContext(ctx, c.Server,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		// grpc.WithBackoffMaxDelay(BackoffMaxDelay),
-		grpc.WithInitialWindowSize(InitialWindowSize),
-		grpc.WithInitialConnWindowSize(InitialConnWindowSize),
-		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(MaxSendMsgSize)),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(MaxRecvMsgSize)),
+		grpc.WithStatsHandler(&statsHandler{srv: s}),
+		grpc.WithInitialWindowSize(int32(c.InitialWindowSize)),
+		grpc.WithInitialConnWindowSize(int32(c.InitialConnWindowSize)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(c.MaxSendMsgSize)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize)),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                KeepAliveTime,
-			Timeout:             KeepAliveTimeout,
+			Time:                millisecond(c.KeepAliveTime),
+			Timeout:             millisecond(c.KeepAliveTimeout),
 			PermitWithoutStream: true,
 		}))
 }
 
-// Write - 调用grpc服务发送数据
-// @param id uint64 偏移时间段
-// @param data []byte 二进制数据包
-// @return err 错误信息
-func (s *Server) Write(id uint64, sid string, value []byte) (err error) {
-	if !s.running.Load() {
-		return nil
+// newStream 按当前配置打开数据流，返回该数据流的SegmentArc编码方式
+// 配置跟踪时，为打开数据流创建跟踪并写入gRPC元数据传递给arc-storage
+// 数据流长期复用，arc-storage 只能收到打开数据流时的跟踪上下文，单个数据包的发送跟踪只在本服务记录，并引用数据流跟踪
+func (s *Server) newStream(client proto.FrameDataClient) (stream proto.FrameData_FrameDataCallbackClient, arcEncoding string, trace opentracing.SpanContext, err error) {
+	c := s.getConfig()
+	var opts []grpc.CallOption
+	if c.Compressor != CompressorNone {
+		opts = append(opts, grpc.UseCompressor(c.Compressor))
+	}
+	ctx := streamContext(c.ArcEncoding, s.bits)
+	if s.tracer != nil {
+		span := s.tracer.StartSpan("FrameDataCallback",
+			ext.SpanKindRPCClient,
+			opentracing.Tag{Key: "grpc.encoding", Value: c.ArcEncoding},
+			opentracing.Tag{Key: "peer.address", Value: c.Server},
+		)
+		defer func() { tracing.Finish(span, err) }()
+		carrier := opentracing.TextMapCarrier{}
+		if err := s.tracer.Inject(span.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+			s.logger.Warnw("inject stream trace", "err", err)
+		} else {
+			for k, v := range carrier {
+				ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+			}
+		}
+		trace = span.Context()
+	}
+	if stream, err = client.FrameDataCallback(ctx, opts...); err != nil {
+		return nil, EncodingNone, nil, err
 	}
+	return stream, c.ArcEncoding, trace, nil
+}
 
-	v, ok := s.pools.Load(id & s.mask)
-	if !ok {
-		conn := &Conn{}
-		s.pools.Store(id&s.mask, conn)
+// request 按数据流编码方式准备发送数据
+func (s *Server) request(arcEncoding string, id uint64, value []byte) (*proto.FrameDataRequest, error) {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
 
-		c, err := s.factory()
-		if err != nil {
-			return err
-		}
-		conn.conn = c
-		conn.grpcclient = proto.NewFrameDataClient(c)
-		grpcstream, err := conn.grpcclient.FrameDataCallback(context.Background())
-		if err != nil {
-			return err
+	payload := value
+	if arcEncoding == EncodingDelta {
+		var err error
+		if payload, err = encodeArc(value, s.bits); err != nil {
+			return nil, fmt.Errorf("encode arc %v", err)
 		}
-		conn.grpcstream = grpcstream
-		conn.valid = true
-		s.logger.Infow("grpc connnect", "mask", id&s.mask, "addr", s.config.Server)
-		v = conn
 	}
+	return &proto.FrameDataRequest{
+		Key:   key[2:],
+		Value: payload,
+	}, nil
+}
 
-	p := v.(*Conn)
+// Write - 调用grpc服务发送数据
+// @param id uint64 偏移时间段
+// @param data []byte 二进制数据包
+// @return err 错误信息
+func (s *Server) Write(id uint64, sid string, value []byte) error {
+	return s.WriteTrace(nil, id, sid, value)
+}
 
-	// 需要重连
-	if p.reconn {
-		if p.grpcstream != nil {
-			_, err = p.grpcstream.CloseAndRecv()
-			if err != nil {
-				s.logger.Errorf("CloseAndRecv() error: %v", err)
+// WriteTrace - 调用grpc服务发送数据，采样的数据包记录发送跟踪
+// @param parent opentracing.Span 数据包跟踪，未采样为nil
+// @param id uint64 偏移时间段
+// @param data []byte 二进制数据包
+// @return err 错误信息
+func (s *Server) WriteTrace(parent opentracing.Span, id uint64, sid string, value []byte) (err error) {
+	var p *Conn
+	var connErr error
+	key := id & s.mask.Load()
+	for {
+		if !s.running.Load() {
+			return ErrDisconnected
+		}
+		p, connErr = s.pool(key)
+		p.inFlight.Inc()
+		p.mu.Lock()
+		if !p.reconn && !p.closed {
+			break
+		}
+		// 需要重连，关闭旧连接后按新配置重建，本数据包发送到新连接
+		if !p.closed {
+			p.closeStream()
+			if p.conn != nil {
+				p.conn.Close()
 			}
+			p.closed = true
+			s.logger.Infow("grpc reconnnect", "mask", key, "addr", s.getConfig().Server)
 		}
-		p.conn.Close()
-		s.pools.Delete(id & s.mask)
-		s.logger.Infow("grpc reconnnect", "mask", id&s.mask, "addr", s.config.Server)
-		return nil
+		s.pools.CompareAndDelete(key, p)
+		p.mu.Unlock()
+		p.inFlight.Dec()
+	}
+	defer p.inFlight.Dec()
+	defer p.mu.Unlock()
+
+	// 首次连接失败，数据包丢弃，之后由Start按退避策略重连
+	if connErr != nil {
+		p.errors++
+		metrics.GrpcErrors.WithLabelValues(poolLabel(p.key)).Inc()
+		return connErr
 	}
 
-	// 连接不可用判断
-	if !p.valid || p.grpcstream == nil {
+	// 连接不可用或熔断打开时丢弃
+	if p.closed || !p.valid || p.grpcstream == nil {
 		return nil
 	}
 
 	// 准备数据
-	key := make([]byte, 8)
-	binary.BigEndian.PutUint64(key, id)
-
-	request := proto.FrameDataRequest{
-		Key:   key[2:],
-		Value: value,
+	request, err := s.request(p.encoding, id, value)
+	if err != nil {
+		return err
 	}
+	counter := s.counter(id, sid)
+	counter.raw.Add(int64(len(value)))
+	counter.encoded.Add(int64(len(request.Value)))
 
 	// fmt.Printf("\tgrpc sending %d, goroutine %d\n", len(value), runtime.NumGoroutine())
-	// KeepAliveTime = 10s 在40s时，会发送失败，现在设置60s，在240s，发送失败
-	if err := p.grpcstream.Send(&request); err != nil {
+	pool := poolLabel(p.key)
+	span := s.startSend(parent, p, sid)
+	defer func() { tracing.Finish(span, err) }()
+	start := time.Now()
+	err = p.grpcstream.Send(request)
+	if err != nil {
 		if err != io.EOF {
-			s.logger.Infow("send error", "err", err, "mask", id&s.mask)
+			s.logger.Infow("send error", "err", err, "mask", key)
 		}
-		// 注意： 这里不能用 CloseSend , 不然每次新建连接时，goroutine会不断增加，每次增加1个
-		if _, cerr := p.grpcstream.CloseAndRecv(); cerr != nil {
-			if err != io.EOF {
-				s.logger.Infow("CloseAndRecv error", "err", err, "mask", id&s.mask)
-			}
+		err = s.resend(p, id, value, err)
+	}
+	metrics.GrpcSendDuration.WithLabelValues(pool).Observe(time.Since(start).Seconds())
+	if err != nil {
+		p.errors++
+		metrics.GrpcErrors.WithLabelValues(pool).Inc()
+		return err
+	}
+
+	p.sent++
+	p.lastSend = time.Now()
+	p.breaker.success()
+	if p.state == StateDegraded {
+		p.setState(StateReady)
+	}
+	return nil
+}
+
+// pool 获取连接池，首次写入时创建并直接建立连接，之后由Start按退避策略重连
+// @return err 首次连接失败错误
+func (s *Server) pool(key uint64) (*Conn, error) {
+	if v, ok := s.pools.Load(key); ok {
+		return v.(*Conn), nil
+	}
+	v, loaded := s.pools.LoadOrStore(key, newConn(key, s.getConfig(), s.listener))
+	if !loaded {
+		return v.(*Conn), s.connect(v.(*Conn))
+	}
+	return v.(*Conn), nil
+}
+
+// startSend 创建发送跟踪，以数据包跟踪为上级并引用数据流跟踪，调用方持有锁
+func (s *Server) startSend(parent opentracing.Span, p *Conn, sid string) opentracing.Span {
+	if parent == nil {
+		return nil
+	}
+	opts := []opentracing.StartSpanOption{
+		opentracing.Tag{Key: tracing.TagSID, Value: sid},
+		opentracing.Tag{Key: "grpc.pool", Value: poolLabel(p.key)},
+		opentracing.Tag{Key: "peer.address", Value: p.addr},
+	}
+	if p.trace != nil {
+		opts = append(opts, opentracing.FollowsFrom(p.trace))
+	}
+	return tracing.StartChild(parent, "grpc.send", opts...)
+}
+
+// resend 发送失败后重建数据流再发送一次，连续失败达到阈值时打开熔断，调用方持有锁
+// 重建和发送期间释放锁，其他写入因数据流不可用而丢弃，返回时重新持有锁
+func (s *Server) resend(p *Conn, id uint64, value []byte, sendErr error) error {
+	now := time.Now()
+	p.lastErr = sendErr
+	if p.breaker.failure(now) {
+		p.closeStream()
+		p.setState(StateOpen)
+		s.logger.Warnw("grpc circuit open", "mask", p.key, "addr", p.addr, "failures", p.breaker.failures)
+		return fmt.Errorf("send %v", sendErr)
+	}
+	p.setState(StateDegraded)
+
+	p.closeStream()
+	p.dialing = true
+	client := p.grpcclient
+	p.mu.Unlock()
+
+	var request *proto.FrameDataRequest
+	var encodeErr error
+	stream, arcEncoding, streamTrace, err := s.newStream(client)
+	if err != nil {
+		err = fmt.Errorf("frameDataCallback %v", err)
+	} else if request, encodeErr = s.request(arcEncoding, id, value); encodeErr == nil {
+		// 新数据流尚未发布，发送不需要持有锁
+		if err = stream.Send(request); err != nil {
+			err = fmt.Errorf("send %v", err)
 		}
-		p.grpcstream, err = p.grpcclient.FrameDataCallback(context.Background())
-		if err != nil {
-			p.valid = false
-			return fmt.Errorf("frameDataCallback %v", err)
+	}
+
+	p.mu.Lock()
+	p.dialing = false
+	if err != nil {
+		if stream != nil {
+			_, _ = stream.CloseAndRecv()
 		}
-		if err := p.grpcstream.Send(&request); err != nil {
-			p.valid = false
-			return fmt.Errorf("send %v", err)
+		if !p.closed {
+			p.fail(err)
 		}
+		return err
 	}
-
-	return nil
+	if p.closed || p.reconn {
+		_, _ = stream.CloseAndRecv()
+		return encodeErr
+	}
+	p.grpcstream = stream
+	p.encoding = arcEncoding
+	p.trace = streamTrace
+	p.valid = true
+	return encodeErr
 }
 
 // Stop - 停止grpc服务
@@ -272,18 +792,31 @@ func (s *Server) Stop() {
 	}
 	s.running.Store(false)
 	s.pools.Range(func(key, value interface{}) bool {
-		p := value.(*Conn)
-		if p.grpcstream != nil {
-			resp, err := p.grpcstream.CloseAndRecv()
-			if err != nil {
-				s.logger.Infow("Stop Connected", "error", err)
-			}
-			if !resp.Successed {
-				s.logger.Infow("gRPC Connected Fail", "success", resp.Successed)
-			}
-		}
-		s.pools.Delete(key)
+		s.closePool(key.(uint64), value.(*Conn))
 		return true
 	})
 	s.logger.Infow("grpc service stop")
 }
+
+// closePool 关闭数据流和连接并移出连接池
+func (s *Server) closePool(key uint64, p *Conn) {
+	p.mu.Lock()
+	if p.grpcstream != nil {
+		resp, err := p.grpcstream.CloseAndRecv()
+		if err != nil {
+			s.logger.Infow("Stop Connected", "error", err)
+		}
+		if resp != nil && !resp.Successed {
+			s.logger.Infow("gRPC Connected Fail", "success", resp.Successed)
+		}
+		p.grpcstream = nil
+	}
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	p.valid = false
+	p.closed = true
+	p.mu.Unlock()
+	s.pools.Delete(key)
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType grpc转发目标类型
+const SinkType = "grpc"
+
+// Sink - 将数据包通过grpc转发到arc-storage
+type Sink struct {
+	handler Handler
+}
+
+// NewSink - 创建grpc转发目标，连接生命周期由Handler管理
+// @param h Handler grpc处理器
+// @return sink.Sink 转发目标
+func NewSink(h Handler) sink.Sink {
+	return &Sink{handler: h}
+}
+
+// Write -
+func (s *Sink) Write(f *sink.Frame) error {
+	return s.handler.WriteTrace(f.Span, f.ID, f.SID, f.Data)
+}
+
+// Flush -
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Close -
+func (s *Sink) Close() error {
+	return nil
+}
+
+// Health - 至少一个连接可用时健康
+func (s *Sink) Health() sink.Health {
+	pools := s.handler.Status()
+	if len(pools) == 0 {
+		return sink.Health{Healthy: true, Message: "no connection yet"}
+	}
+	var states []string
+	for _, p := range pools {
+		state := fmt.Sprintf("%d:%s", p.Key, p.State)
+		if p.State == StateReady.String() || p.State == StateDegraded.String() {
+			return sink.Health{Healthy: true}
+		}
+		states = append(states, state)
+	}
+	return sink.Health{Healthy: false, Message: strings.Join(states, ",")}
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"time"
+)
+
+// State - 连接状态
+type State int32
+
+const (
+	// StateConnecting 正在建立连接或等待退避重连
+	StateConnecting State = iota
+	// StateReady 连接可用
+	StateReady
+	// StateDegraded 连接可用，但最近发送失败或健康检查未通过
+	StateDegraded
+	// StateOpen 熔断打开，暂停发送，等待探测恢复
+	StateOpen
+)
+
+// String - 状态名称
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateReady:
+		return "ready"
+	case StateDegraded:
+		return "degraded"
+	case StateOpen:
+		return "open-circuit"
+	}
+	return "unknown"
+}
+
+// StateChange - 连接状态变化
+type StateChange struct {
+	Key   uint64 `json:"key"`
+	Addr  string `json:"addr"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Error string `json:"error,omitempty"`
+}
+
+// StateListener - 连接状态变化回调，在连接锁内调用，不能阻塞
+type StateListener func(*StateChange)
+
+// breaker - 熔断器，连续发送失败达到阈值后打开
+type breaker struct {
+	threshold int
+	timeout   time.Duration
+	failures  int
+	openedAt  time.Time
+}
+
+// success 发送成功，清零失败计数
+func (b *breaker) success() {
+	b.failures = 0
+}
+
+// failure 记录一次发送失败，返回熔断是否打开
+func (b *breaker) failure(now time.Time) bool {
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+		return true
+	}
+	return false
+}
+
+// trip 探测失败，重新计时
+func (b *breaker) trip(now time.Time) {
+	b.openedAt = now
+}
+
+// allowProbe 熔断打开超过超时时间后，允许探测恢复
+func (b *breaker) allowProbe(now time.Time) bool {
+	return now.Sub(b.openedAt) >= b.timeout
+}
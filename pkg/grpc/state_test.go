@@ -0,0 +1,260 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	proto "github.com/kiga-hub/arc/protobuf/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestBreaker(t *testing.T) {
+	t0 := time.Unix(1683356400, 0)
+	type step struct {
+		op   string // failure/success/trip/probe
+		at   time.Duration
+		want bool // failure 返回熔断是否打开，probe 返回是否允许探测
+	}
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{"trip at threshold", []step{
+			{"failure", 0, false},
+			{"failure", 0, false},
+			{"failure", 0, true},
+			{"probe", 500 * time.Millisecond, false},
+			{"probe", time.Second, true},
+		}},
+		{"success resets failures", []step{
+			{"failure", 0, false},
+			{"failure", 0, false},
+			{"success", 0, false},
+			{"failure", 0, false},
+			{"failure", 0, false},
+			{"failure", 0, true},
+		}},
+		{"probe failure restarts timer", []step{
+			{"failure", 0, false},
+			{"failure", 0, false},
+			{"failure", 0, true},
+			{"probe", time.Second, true},
+			{"trip", time.Second, false},
+			{"probe", 1500 * time.Millisecond, false},
+			{"probe", 2 * time.Second, true},
+		}},
+		{"half-open failure reopens", []step{
+			{"failure", 0, false},
+			{"failure", 0, false},
+			{"failure", 0, true},
+			{"failure", 3 * time.Second, true},
+			{"probe", 3500 * time.Millisecond, false},
+			{"success", 4 * time.Second, false},
+			{"failure", 4 * time.Second, false},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := &breaker{threshold: 3, timeout: time.Second}
+			for i, s := range c.steps {
+				now := t0.Add(s.at)
+				var got bool
+				switch s.op {
+				case "failure":
+					got = b.failure(now)
+				case "success":
+					b.success()
+				case "trip":
+					b.trip(now)
+				case "probe":
+					got = b.allowProbe(now)
+				}
+				if got != s.want {
+					t.Fatalf("step %d %s at %v: got %v, want %v", i, s.op, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConnTransitions(t *testing.T) {
+	SetDefaultConfig()
+	cases := []struct {
+		name string
+		run  func(p *Conn)
+		want []string // from->to
+	}{
+		{"connect", func(p *Conn) { p.setState(StateReady) }, []string{"connecting->ready"}},
+		{"degrade and recover", func(p *Conn) {
+			p.setState(StateReady)
+			p.setState(StateDegraded)
+			p.setState(StateReady)
+		}, []string{"connecting->ready", "ready->degraded", "degraded->ready"}},
+		{"open and half-open", func(p *Conn) {
+			p.setState(StateReady)
+			p.setState(StateOpen)
+			p.setState(StateDegraded)
+			p.setState(StateReady)
+		}, []string{"connecting->ready", "ready->open-circuit", "open-circuit->degraded", "degraded->ready"}},
+		{"stream lost", func(p *Conn) {
+			p.setState(StateReady)
+			p.fail(errors.New("eof"))
+		}, []string{"connecting->ready", "ready->connecting"}},
+		{"same state", func(p *Conn) {
+			p.setState(StateConnecting)
+			p.fail(errors.New("refused"))
+		}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			p := newConn(1, GetConfig(), func(c *StateChange) { got = append(got, c.From+"->"+c.To) })
+			c.run(p)
+			if len(got) != len(c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("got %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+
+	// 连接失败进入退避重连
+	p := newConn(1, GetConfig(), nil)
+	before := time.Now()
+	d := p.fail(errors.New("refused"))
+	if p.state != StateConnecting || p.valid || p.reconnects != 1 || p.nextRetry.Before(before.Add(d)) {
+		t.Fatalf("unexpected conn after fail: state %v valid %v reconnects %d retry %v", p.state, p.valid, p.reconnects, d)
+	}
+}
+
+// blockingClient 打开数据流时阻塞，直到 release 关闭
+type blockingClient struct {
+	entered chan struct{}
+	release chan struct{}
+	stream  *fakeStream
+}
+
+func (c *blockingClient) FrameDataCallback(ctx context.Context, opts ...grpc.CallOption) (proto.FrameData_FrameDataCallbackClient, error) {
+	close(c.entered)
+	<-c.release
+	return c.stream, nil
+}
+
+// fakeStream 数据流，err 不为空时发送失败
+type fakeStream struct {
+	grpc.ClientStream
+	err  error
+	sent int
+}
+
+func (f *fakeStream) Send(*proto.FrameDataRequest) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent++
+	return nil
+}
+
+func (f *fakeStream) CloseAndRecv() (*proto.FrameDataResponse, error) {
+	return &proto.FrameDataResponse{Successed: true}, nil
+}
+
+func TestResendReleasesLock(t *testing.T) {
+	SetDefaultConfig()
+	c := GetConfig()
+	c.Enable = true
+	srv := New(WithConfig(c)).(*Server)
+	srv.running.Store(true)
+
+	client := &blockingClient{entered: make(chan struct{}), release: make(chan struct{}), stream: &fakeStream{}}
+	p := newConn(0, c, nil)
+	p.grpcclient = client
+	p.grpcstream = &fakeStream{err: errors.New("broken pipe")}
+	p.valid = true
+	p.setState(StateReady)
+	srv.pools.Store(uint64(0), p)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Write(0, "0", getFrame(0)) }()
+
+	<-client.entered
+	// 重建数据流期间不持有连接锁，状态查询和其他写入不阻塞
+	status := make(chan []PoolStatus, 1)
+	go func() { status <- srv.Status() }()
+	select {
+	case list := <-status:
+		if len(list) != 1 || list[0].Valid || list[0].State != "degraded" {
+			t.Fatalf("unexpected status during resend %+v", list)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pool lock held during resend")
+	}
+	if err := srv.Write(0, "0", getFrame(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	close(client.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	list := srv.Status()
+	if len(list) != 1 || !list[0].Valid || list[0].State != "ready" || list[0].Sent != 1 || client.stream.sent != 1 {
+		t.Fatalf("unexpected status after resend %+v", list)
+	}
+}
+
+func TestFirstConnectError(t *testing.T) {
+	SetDefaultConfig()
+	c := GetConfig()
+	c.Enable = true
+	c.Server = "127.0.0.1:1"
+	c.HealthCheckTimeout = 200
+	srv := New(WithConfig(c)).(*Server)
+	srv.running.Store(true)
+
+	if err := srv.Write(0, "0", getFrame(0)); err == nil {
+		t.Fatal("first connect error should be returned")
+	}
+	list := srv.Status()
+	if len(list) != 1 || list[0].State != "connecting" || list[0].Errors != 1 || list[0].Reconnects != 1 {
+		t.Fatalf("unexpected status %+v", list)
+	}
+	// 退避期间丢弃，不重复返回连接错误
+	if err := srv.Write(0, "0", getFrame(1)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStopClosesConn(t *testing.T) {
+	SetDefaultConfig()
+	c := GetConfig()
+	c.Enable = true
+	srv := New(WithConfig(c)).(*Server)
+	srv.running.Store(true)
+
+	conn, err := grpc.Dial("127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := &fakeStream{}
+	p := newConn(0, c, nil)
+	p.conn = conn
+	p.grpcstream = stream
+	p.valid = true
+	srv.pools.Store(uint64(0), p)
+
+	srv.Stop()
+	if conn.GetState() != connectivity.Shutdown {
+		t.Fatalf("conn not closed on stop: %v", conn.GetState())
+	}
+	if p.conn != nil || p.grpcstream != nil || !p.closed || len(srv.Status()) != 0 {
+		t.Fatal("pool not released on stop")
+	}
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// StatusOK 全部检查通过
+	StatusOK = "ok"
+	// StatusFail 至少一项检查未通过
+	StatusFail = "fail"
+)
+
+// CheckFunc - 检查函数，返回nil表示通过
+type CheckFunc func() error
+
+// Result - 单项检查结果
+type Result struct {
+	Name     string `json:"name"`
+	Healthy  bool   `json:"healthy"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report - 检查报告
+type Report struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Result  `json:"checks"`
+}
+
+// Healthy - 全部检查是否通过
+func (r *Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// check 已注册的检查
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker - 存活与就绪检查
+// 存活检查失败表示进程需要重启，就绪检查失败表示暂时不应接收流量，就绪包含全部存活检查
+type Checker struct {
+	mu        sync.RWMutex
+	liveness  []check
+	readiness []check
+}
+
+// New - 创建检查
+// @return *Checker 检查
+func New() *Checker {
+	return &Checker{}
+}
+
+// AddLiveness - 注册存活检查
+// @param name string 检查名称
+// @param fn CheckFunc 检查函数
+func (c *Checker) AddLiveness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, check{name: name, fn: fn})
+}
+
+// AddReadiness - 注册就绪检查
+// @param name string 检查名称
+// @param fn CheckFunc 检查函数
+func (c *Checker) AddReadiness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, check{name: name, fn: fn})
+}
+
+// Liveness - 执行存活检查
+// @return *Report 检查报告
+func (c *Checker) Liveness() *Report {
+	c.mu.RLock()
+	checks := append([]check(nil), c.liveness...)
+	c.mu.RUnlock()
+	return run(checks)
+}
+
+// Readiness - 执行存活和就绪检查
+// @return *Report 检查报告
+func (c *Checker) Readiness() *Report {
+	c.mu.RLock()
+	checks := append(append([]check(nil), c.liveness...), c.readiness...)
+	c.mu.RUnlock()
+	return run(checks)
+}
+
+// run 依次执行检查，任一失败则报告失败
+func run(checks []check) *Report {
+	report := &Report{
+		Status: StatusOK,
+		Time:   time.Now(),
+		Checks: make([]Result, 0, len(checks)),
+	}
+	for _, ch := range checks {
+		start := time.Now()
+		err := ch.fn()
+		result := Result{
+			Name:     ch.name,
+			Healthy:  err == nil,
+			Duration: time.Since(start).String(),
+		}
+		if err != nil {
+			result.Message = err.Error()
+			report.Status = StatusFail
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
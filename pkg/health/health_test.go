@@ -0,0 +1,29 @@
+package health
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChecker(t *testing.T) {
+	c := New()
+	ready := errors.New("shards saturated")
+	c.AddLiveness("listener", func() error { return nil })
+	c.AddReadiness("shards", func() error { return ready })
+
+	if r := c.Liveness(); !r.Healthy() || len(r.Checks) != 1 {
+		t.Fatalf("unexpected liveness %+v", r)
+	}
+	r := c.Readiness()
+	if r.Healthy() || r.Status != StatusFail || len(r.Checks) != 2 {
+		t.Fatalf("unexpected readiness %+v", r)
+	}
+	if !r.Checks[0].Healthy || r.Checks[1].Healthy || r.Checks[1].Message != ready.Error() {
+		t.Fatalf("unexpected checks %+v", r.Checks)
+	}
+
+	ready = nil
+	if r := c.Readiness(); !r.Healthy() {
+		t.Fatalf("unexpected readiness %+v", r)
+	}
+}
@@ -0,0 +1,199 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyBrokers         = "kafka.brokers"
+	keyTopic           = "kafka.topic"
+	keyFeatureTopic    = "kafka.feature_topic"
+	keyEventTopic      = "kafka.event_topic"
+	keyClientID        = "kafka.client_id"
+	keyVersion         = "kafka.version"
+	keyCompression     = "kafka.compression"
+	keyIdempotent      = "kafka.idempotent"
+	keyRequiredAcks    = "kafka.required_acks"
+	keyMaxMessageBytes = "kafka.max_message_bytes"
+	keyBatchSize       = "kafka.batch_size"
+	keyBatchBytes      = "kafka.batch_bytes"
+	keyLinger          = "kafka.linger"
+	keyRetryMax        = "kafka.retry_max"
+	keyChannelBuffer   = "kafka.channel_buffer"
+	keyReconnect       = "kafka.reconnect_interval"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Brokers:         []string{"localhost:9092"},
+	Topic:           "arc-frame",
+	FeatureTopic:    "",
+	EventTopic:      "",
+	ClientID:        "arc-consumer",
+	Version:         "2.1.0",
+	Compression:     "snappy",
+	Idempotent:      true,
+	RequiredAcks:    int(sarama.WaitForAll),
+	MaxMessageBytes: 1000000,
+	BatchSize:       100,
+	BatchBytes:      1 << 20,
+	Linger:          10,
+	RetryMax:        5,
+	ChannelBuffer:   4096,
+	Reconnect:       5000,
+}
+
+// Config - kafka转发配置，[kafka] 为默认值，[[sink]] options 可逐项覆盖
+type Config struct {
+	Brokers []string `toml:"brokers" json:"brokers"`
+	// Topic Frame原始数据主题，消息键为传感器编号，同一传感器固定分区
+	Topic string `toml:"topic" json:"topic"`
+	// FeatureTopic 特征数据主题，空不发送
+	FeatureTopic string `toml:"feature_topic" json:"feature_topic,omitempty"`
+	// EventTopic 电弧事件主题，空不发送
+	EventTopic string `toml:"event_topic" json:"event_topic,omitempty"`
+	ClientID   string `toml:"client_id" json:"client_id"`
+	// Version kafka协议版本
+	Version string `toml:"version" json:"version"`
+	// Compression 消息压缩: none, gzip, snappy, lz4, zstd
+	Compression string `toml:"compression" json:"compression"`
+	// Idempotent 幂等发送，要求 required_acks = -1
+	Idempotent bool `toml:"idempotent" json:"idempotent"`
+	// RequiredAcks 0 不等待, 1 等待leader, -1 等待全部副本
+	RequiredAcks    int `toml:"required_acks" json:"required_acks"`
+	MaxMessageBytes int `toml:"max_message_bytes" json:"max_message_bytes"`
+	// BatchSize 累计消息条数后发送
+	BatchSize int `toml:"batch_size" json:"batch_size"`
+	// BatchBytes 累计字节数后发送
+	BatchBytes int `toml:"batch_bytes" json:"batch_bytes"`
+	// Linger 最长等待(毫秒)后发送
+	Linger   int `toml:"linger" json:"linger"`
+	RetryMax int `toml:"retry_max" json:"retry_max"`
+	// ChannelBuffer 发送队列长度，队列满时丢弃
+	ChannelBuffer int `toml:"channel_buffer" json:"channel_buffer"`
+	// Reconnect 无法连接kafka时重试间隔(毫秒)，连接前的数据在队列中等待，队列满时丢弃
+	Reconnect int `toml:"reconnect_interval" json:"reconnect_interval"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyBrokers, defaultConfig.Brokers)
+	viper.SetDefault(keyTopic, defaultConfig.Topic)
+	viper.SetDefault(keyFeatureTopic, defaultConfig.FeatureTopic)
+	viper.SetDefault(keyEventTopic, defaultConfig.EventTopic)
+	viper.SetDefault(keyClientID, defaultConfig.ClientID)
+	viper.SetDefault(keyVersion, defaultConfig.Version)
+	viper.SetDefault(keyCompression, defaultConfig.Compression)
+	viper.SetDefault(keyIdempotent, defaultConfig.Idempotent)
+	viper.SetDefault(keyRequiredAcks, defaultConfig.RequiredAcks)
+	viper.SetDefault(keyMaxMessageBytes, defaultConfig.MaxMessageBytes)
+	viper.SetDefault(keyBatchSize, defaultConfig.BatchSize)
+	viper.SetDefault(keyBatchBytes, defaultConfig.BatchBytes)
+	viper.SetDefault(keyLinger, defaultConfig.Linger)
+	viper.SetDefault(keyRetryMax, defaultConfig.RetryMax)
+	viper.SetDefault(keyChannelBuffer, defaultConfig.ChannelBuffer)
+	viper.SetDefault(keyReconnect, defaultConfig.Reconnect)
+}
+
+// GetConfig - 获取 [kafka] 配置
+func GetConfig() *Config {
+	return &Config{
+		Brokers:         viper.GetStringSlice(keyBrokers),
+		Topic:           viper.GetString(keyTopic),
+		FeatureTopic:    viper.GetString(keyFeatureTopic),
+		EventTopic:      viper.GetString(keyEventTopic),
+		ClientID:        viper.GetString(keyClientID),
+		Version:         viper.GetString(keyVersion),
+		Compression:     viper.GetString(keyCompression),
+		Idempotent:      viper.GetBool(keyIdempotent),
+		RequiredAcks:    viper.GetInt(keyRequiredAcks),
+		MaxMessageBytes: viper.GetInt(keyMaxMessageBytes),
+		BatchSize:       viper.GetInt(keyBatchSize),
+		BatchBytes:      viper.GetInt(keyBatchBytes),
+		Linger:          viper.GetInt(keyLinger),
+		RetryMax:        viper.GetInt(keyRetryMax),
+		ChannelBuffer:   viper.GetInt(keyChannelBuffer),
+		Reconnect:       viper.GetInt(keyReconnect),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [kafka] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// compressionCodec 压缩配置转换
+func compressionCodec(name string) (sarama.CompressionCodec, error) {
+	switch name {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	}
+	return sarama.CompressionNone, fmt.Errorf("unsupported kafka compression %q", name)
+}
+
+// saramaConfig - 转换为sarama生产者配置并检查
+// @return *sarama.Config sarama配置
+// @return err 错误信息
+func (c *Config) saramaConfig() (*sarama.Config, error) {
+	if len(c.Brokers) == 0 {
+		return nil, fmt.Errorf("%s is empty", keyBrokers)
+	}
+	if c.Topic == "" {
+		return nil, fmt.Errorf("%s is empty", keyTopic)
+	}
+	if c.ChannelBuffer <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %d", keyChannelBuffer, c.ChannelBuffer)
+	}
+	if c.Reconnect <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %d", keyReconnect, c.Reconnect)
+	}
+	version, err := sarama.ParseKafkaVersion(c.Version)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := compressionCodec(c.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := sarama.NewConfig()
+	sc.ClientID = c.ClientID
+	sc.Version = version
+	sc.ChannelBufferSize = c.ChannelBuffer
+	sc.Producer.Partitioner = sarama.NewHashPartitioner
+	sc.Producer.Compression = codec
+	sc.Producer.RequiredAcks = sarama.RequiredAcks(c.RequiredAcks)
+	sc.Producer.MaxMessageBytes = c.MaxMessageBytes
+	sc.Producer.Flush.Messages = c.BatchSize
+	sc.Producer.Flush.Bytes = c.BatchBytes
+	sc.Producer.Flush.Frequency = millisecond(c.Linger)
+	sc.Producer.Retry.Max = c.RetryMax
+	sc.Producer.Return.Successes = true
+	sc.Producer.Return.Errors = true
+	if c.Idempotent {
+		// 幂等发送要求单连接单请求，保证同一分区顺序
+		sc.Producer.Idempotent = true
+		sc.Net.MaxOpenRequests = 1
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
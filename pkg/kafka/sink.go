@@ -0,0 +1,246 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType kafka转发目标类型
+const SinkType = "kafka"
+
+// errQueueFull 发送队列已满，丢弃数据
+var errQueueFull = errors.New("kafka producer queue full")
+
+// errClosed 转发目标已关闭
+var errClosed = errors.New("kafka sink closed")
+
+// Sink - 将数据包、特征和事件发送到kafka
+type Sink struct {
+	config  *Config
+	sc      *sarama.Config
+	queue   chan *sarama.ProducerMessage // 有界发送队列
+	queueMu sync.RWMutex                 // 保护队列关闭
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu          sync.Mutex
+	connected   bool
+	lastErr     error
+	lastErrAt   time.Time
+	lastSuccess time.Time
+
+	sent    *atomic.Int64
+	failed  *atomic.Int64
+	dropped *atomic.Int64 // 发送队列满丢弃
+}
+
+// NewSink - 根据转发目标配置创建kafka生产者
+// @param c *sink.Config 转发目标配置，options覆盖 [kafka] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// New - 创建kafka转发目标，后台连接kafka，无法连接时按间隔重试
+// @param cfg *Config kafka配置
+// @return *Sink 转发目标
+// @return err 配置错误
+func New(cfg *Config) (*Sink, error) {
+	sc, err := cfg.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		config:  cfg,
+		sc:      sc,
+		queue:   make(chan *sarama.ProducerMessage, cfg.ChannelBuffer),
+		done:    make(chan struct{}),
+		sent:    atomic.NewInt64(0),
+		failed:  atomic.NewInt64(0),
+		dropped: atomic.NewInt64(0),
+	}
+	s.wg.Add(1)
+	go s.forward()
+	return s, nil
+}
+
+// connect 创建生产者，获取元数据失败按间隔重试，关闭时返回nil
+func (s *Sink) connect() sarama.AsyncProducer {
+	for {
+		producer, err := sarama.NewAsyncProducer(s.config.Brokers, s.sc)
+		s.mu.Lock()
+		if err == nil {
+			s.connected = true
+			s.lastErr = nil
+		} else {
+			s.lastErr = err
+			s.lastErrAt = time.Now()
+		}
+		s.mu.Unlock()
+		if err == nil {
+			return producer
+		}
+		select {
+		case <-s.done:
+			return nil
+		case <-time.After(millisecond(s.config.Reconnect)):
+		}
+	}
+}
+
+// forward 连接后将发送队列消息交给生产者，队列关闭后关闭生产者
+func (s *Sink) forward() {
+	defer s.wg.Done()
+	producer := s.connect()
+	if producer == nil {
+		// 未连接即关闭，队列中的消息丢弃
+		for range s.queue {
+			s.dropped.Inc()
+		}
+		return
+	}
+	s.wg.Add(2)
+	go s.successes(producer)
+	go s.errors(producer)
+	for msg := range s.queue {
+		producer.Input() <- msg
+	}
+	producer.AsyncClose()
+}
+
+// successes 处理发送成功回执
+func (s *Sink) successes(producer sarama.AsyncProducer) {
+	defer s.wg.Done()
+	for range producer.Successes() {
+		s.sent.Inc()
+		s.mu.Lock()
+		s.lastSuccess = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// errors 处理发送失败回执
+func (s *Sink) errors(producer sarama.AsyncProducer) {
+	defer s.wg.Done()
+	for err := range producer.Errors() {
+		s.failed.Inc()
+		s.mu.Lock()
+		s.lastErr = err
+		s.lastErrAt = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// send 消息入发送队列，队列满时不阻塞数据处理协程
+func (s *Sink) send(topic, key string, value []byte, timestamp int64) error {
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("sid"), Value: []byte(key)},
+			{Key: []byte("timestamp"), Value: []byte(strconv.FormatInt(timestamp, 10))},
+		},
+		Timestamp: time.UnixMilli(timestamp),
+	}
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	if s.closed {
+		return errClosed
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		s.dropped.Inc()
+		return errQueueFull
+	}
+}
+
+// Write - 原始Frame以传感器编号为键发送
+func (s *Sink) Write(f *sink.Frame) error {
+	return s.send(s.config.Topic, f.SID, f.Data, f.Timestamp)
+}
+
+// WriteFeature - 特征以JSON发送到特征主题
+func (s *Sink) WriteFeature(f *feature.Feature) error {
+	if s.config.FeatureTopic == "" {
+		return nil
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.send(s.config.FeatureTopic, f.SID, data, f.Timestamp)
+}
+
+// WriteEvent - 事件以JSON发送到事件主题
+func (s *Sink) WriteEvent(ev *event.Event) error {
+	if s.config.EventTopic == "" {
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.send(s.config.EventTopic, ev.SID, data, ev.Timestamp)
+}
+
+// Flush - 生产者按批次参数自动发送
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Close - 发送队列中剩余消息后关闭，未连接时停止重试
+func (s *Sink) Close() error {
+	s.queueMu.Lock()
+	if s.closed {
+		s.queueMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	close(s.queue)
+	s.queueMu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Health - 已连接且最近一次失败之后有成功发送时健康
+func (s *Sink) Health() sink.Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		msg := "not connected"
+		if s.lastErr != nil {
+			msg += ": " + s.lastErr.Error()
+		}
+		return sink.Health{Healthy: false, Message: fmt.Sprintf("%s, dropped %d", msg, s.dropped.Load())}
+	}
+	if s.lastErr == nil || s.lastSuccess.After(s.lastErrAt) {
+		return sink.Health{Healthy: true, Message: fmt.Sprintf("sent %d, failed %d, dropped %d", s.sent.Load(), s.failed.Load(), s.dropped.Load())}
+	}
+	return sink.Health{Healthy: false, Message: fmt.Sprintf("%s, dropped %d", s.lastErr.Error(), s.dropped.Load())}
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
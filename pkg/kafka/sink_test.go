@@ -0,0 +1,171 @@
+package kafka
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+func newBroker(t *testing.T, topics ...string) *sarama.MockBroker {
+	return newBrokerAddr(t, "127.0.0.1:0", topics...)
+}
+
+func newBrokerAddr(t *testing.T, addr string, topics ...string) *sarama.MockBroker {
+	broker := sarama.NewMockBrokerAddr(t, 1, addr)
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for _, topic := range topics {
+		for p := int32(0); p < 4; p++ {
+			metadata.SetLeader(topic, p, broker.BrokerID())
+		}
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		// kafka 2.1.0 非zstd压缩对应 ProduceRequest v3
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3),
+	})
+	return broker
+}
+
+func testConfig(broker *sarama.MockBroker) *Config {
+	cfg := defaultConfig
+	cfg.Brokers = []string{broker.Addr()}
+	cfg.FeatureTopic = "arc-feature"
+	cfg.EventTopic = "arc-event"
+	cfg.Idempotent = false
+	cfg.Linger = 1
+	return &cfg
+}
+
+func TestSinkWrite(t *testing.T) {
+	broker := newBroker(t, "arc-frame", "arc-feature", "arc-event")
+	defer broker.Close()
+
+	s, err := New(testConfig(broker))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sids := []string{"94C96000C248", "94C96000C249"}
+	for i := 0; i < 10; i++ {
+		sid := sids[i%2]
+		if err := s.Write(&sink.Frame{SID: sid, Timestamp: int64(i), Data: []byte{0xFC, 0xFC, 0xFC, 0xFC}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.WriteFeature(&feature.Feature{SID: sids[0], RMS: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeArc, SID: sids[1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.sent.Load() < 12 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.sent.Load() != 12 || s.failed.Load() != 0 {
+		t.Fatalf("sent %d failed %d, want 12/0: %v", s.sent.Load(), s.failed.Load(), s.lastErr)
+	}
+	if h := s.Health(); !h.Healthy {
+		t.Fatalf("unhealthy: %s", h.Message)
+	}
+}
+
+func TestSinkReconnect(t *testing.T) {
+	// 预留端口，kafka启动前无法连接
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := defaultConfig
+	cfg.Brokers = []string{addr}
+	cfg.Idempotent = false
+	cfg.Linger = 1
+	cfg.ChannelBuffer = 2
+	cfg.Reconnect = 50
+	s, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未连接时写入进入队列，队列满时丢弃
+	for i := 0; i < 3; i++ {
+		err := s.Write(&sink.Frame{SID: "94C96000C248", Timestamp: int64(i), Data: []byte{0xFC}})
+		if (err != nil) != (i == 2) {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Health().Message == "not connected, dropped 1" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if h := s.Health(); h.Healthy || !strings.HasPrefix(h.Message, "not connected: ") || !strings.HasSuffix(h.Message, "dropped 1") {
+		t.Fatalf("unexpected health %+v", h)
+	}
+
+	// kafka启动后连接并发送队列中的消息
+	broker := newBrokerAddr(t, addr, cfg.Topic)
+	for s.sent.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if h := s.Health(); !h.Healthy || s.sent.Load() != 2 {
+		t.Fatalf("sent %d, health %+v", s.sent.Load(), h)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未连接即关闭不阻塞
+	broker.Close()
+	s, err = New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSinkOptions(t *testing.T) {
+	SetDefaultConfig()
+	cfg, err := getSinkConfig(&sink.Config{
+		Name: "kafka",
+		Type: SinkType,
+		Options: map[string]interface{}{
+			"brokers":     []interface{}{"k1:9092", "k2:9092"},
+			"topic":       "frames",
+			"compression": "lz4",
+			"batch_size":  "500",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Brokers) != 2 || cfg.Topic != "frames" || cfg.Compression != "lz4" || cfg.BatchSize != 500 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if cfg.ClientID != defaultConfig.ClientID || !cfg.Idempotent {
+		t.Fatalf("defaults not kept %+v", cfg)
+	}
+	if _, err := cfg.saramaConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Compression = "brotli"
+	if _, err := cfg.saramaConfig(); err == nil {
+		t.Fatal("expected compression error")
+	}
+}
@@ -0,0 +1,179 @@
+package limit
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+)
+
+const (
+	keyEnable        = "limit.enable"
+	keyGlobalFrames  = "limit.global_frames"
+	keyGlobalBytes   = "limit.global_bytes"
+	keyFrames        = "limit.frames"
+	keyBytes         = "limit.bytes"
+	keyBurst         = "limit.burst"
+	keyPolicy        = "limit.policy"
+	keySample        = "limit.sample"
+	keyBlockDuration = "limit.block_duration"
+	keyGroup         = "limit.group"
+)
+
+const (
+	// PolicyDrop 超限的数据包丢弃
+	PolicyDrop = "drop"
+	// PolicySample 超限时每 Sample 个数据包保留1个
+	PolicySample = "sample"
+	// PolicyBlock 超限后在 BlockDuration 内拒绝该传感器全部数据包，只丢弃数据包，不断开连接
+	PolicyBlock = "block"
+	// PolicyDisconnect 超限后在 BlockDuration 内拒绝该传感器全部数据包，并断开设备会话
+	PolicyDisconnect = "disconnect"
+)
+
+// 配置默认值，速率为每秒，时间单位为毫秒
+var defaultConfig = Config{
+	Enable:       false,
+	GlobalFrames: 0,
+	GlobalBytes:  0,
+	Limits: Limits{
+		Frames:        0,
+		Bytes:         0,
+		Burst:         2,
+		Policy:        PolicyDrop,
+		Sample:        10,
+		BlockDuration: 60000,
+	},
+}
+
+// Limits - 传感器限流参数
+type Limits struct {
+	// Frames 每秒数据包数，0不限制
+	Frames float64 `toml:"frames" mapstructure:"frames" json:"frames"`
+	// Bytes 每秒字节数，0不限制
+	Bytes float64 `toml:"bytes" mapstructure:"bytes" json:"bytes"`
+	// Burst 令牌桶容量(秒)，容量为速率乘以该值
+	Burst float64 `toml:"burst" mapstructure:"burst" json:"burst"`
+	// Policy 超限处理 drop/sample/block/disconnect
+	Policy string `toml:"policy" mapstructure:"policy" json:"policy"`
+	// Sample sample策略超限时每N个数据包保留1个
+	Sample int `toml:"sample" mapstructure:"sample" json:"sample"`
+	// BlockDuration block/disconnect策略拒绝时长(毫秒)
+	BlockDuration int `toml:"block_duration" mapstructure:"block_duration" json:"block_duration"`
+}
+
+// Group - 传感器分组限流，未配置的参数使用 [limit] 默认值
+type Group struct {
+	Name string `toml:"name" mapstructure:"name" json:"name"`
+	// Sensors 编号、通配符(94C96*)或范围(94C960000000-94C96000FFFF)，按配置顺序匹配第一个分组
+	Sensors []string `toml:"sensors" mapstructure:"sensors" json:"sensors"`
+	Limits  `mapstructure:",squash"`
+}
+
+// Config - 接收限流配置
+type Config struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// GlobalFrames 全部传感器每秒数据包数，0不限制，超限丢弃
+	GlobalFrames float64 `toml:"global_frames" json:"global_frames"`
+	// GlobalBytes 全部传感器每秒字节数，0不限制，超限丢弃
+	GlobalBytes float64 `toml:"global_bytes" json:"global_bytes"`
+	// Limits 不属于任何分组的传感器限流参数
+	Limits
+	Groups []*Group `toml:"group" json:"groups,omitempty"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyEnable, defaultConfig.Enable)
+	viper.SetDefault(keyGlobalFrames, defaultConfig.GlobalFrames)
+	viper.SetDefault(keyGlobalBytes, defaultConfig.GlobalBytes)
+	viper.SetDefault(keyFrames, defaultConfig.Frames)
+	viper.SetDefault(keyBytes, defaultConfig.Bytes)
+	viper.SetDefault(keyBurst, defaultConfig.Burst)
+	viper.SetDefault(keyPolicy, defaultConfig.Policy)
+	viper.SetDefault(keySample, defaultConfig.Sample)
+	viper.SetDefault(keyBlockDuration, defaultConfig.BlockDuration)
+}
+
+// GetConfig - 获取当前配置，分组对应配置文件中的 [[limit.group]]
+func GetConfig() (*Config, error) {
+	c := &Config{
+		Enable:       viper.GetBool(keyEnable),
+		GlobalFrames: viper.GetFloat64(keyGlobalFrames),
+		GlobalBytes:  viper.GetFloat64(keyGlobalBytes),
+		Limits: Limits{
+			Frames:        viper.GetFloat64(keyFrames),
+			Bytes:         viper.GetFloat64(keyBytes),
+			Burst:         viper.GetFloat64(keyBurst),
+			Policy:        viper.GetString(keyPolicy),
+			Sample:        viper.GetInt(keySample),
+			BlockDuration: viper.GetInt(keyBlockDuration),
+		},
+	}
+	if !viper.IsSet(keyGroup) {
+		return c, nil
+	}
+	items := []map[string]interface{}{}
+	if err := viper.UnmarshalKey(keyGroup, &items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		g := &Group{Limits: c.Limits}
+		if err := mapstructure.WeakDecode(item, g); err != nil {
+			return nil, err
+		}
+		c.Groups = append(c.Groups, g)
+	}
+	return c, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.GlobalFrames < 0 || c.GlobalBytes < 0 {
+		return fmt.Errorf("%s and %s must not be negative", keyGlobalFrames, keyGlobalBytes)
+	}
+	if err := c.Limits.validate("limit"); err != nil {
+		return err
+	}
+	for i, g := range c.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("%s %d name is empty", keyGroup, i)
+		}
+		if err := g.Limits.validate(keyGroup + " " + g.Name); err != nil {
+			return err
+		}
+		if _, err := registry.ParseRules(g.Sensors); err != nil {
+			return fmt.Errorf("%s %s: %w", keyGroup, g.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate 检查限流参数
+func (l *Limits) validate(name string) error {
+	if l.Frames < 0 || l.Bytes < 0 {
+		return fmt.Errorf("%s frames and bytes must not be negative", name)
+	}
+	if l.Burst <= 0 {
+		return fmt.Errorf("%s burst must be positive, got %v", name, l.Burst)
+	}
+	switch l.Policy {
+	case PolicyDrop:
+	case PolicySample:
+		if l.Sample <= 0 {
+			return fmt.Errorf("%s sample must be positive, got %d", name, l.Sample)
+		}
+	case PolicyBlock, PolicyDisconnect:
+		if l.BlockDuration <= 0 {
+			return fmt.Errorf("%s block_duration must be positive, got %d", name, l.BlockDuration)
+		}
+	default:
+		return fmt.Errorf("%s policy must be one of %s/%s/%s/%s, got %q", name, PolicyDrop, PolicySample, PolicyBlock, PolicyDisconnect, l.Policy)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package limit
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+)
+
+// 丢弃原因，作为 frames_dropped_total 的 reason 标签
+const (
+	// ReasonGlobal 超过全局限流
+	ReasonGlobal = "global_rate_limited"
+	// ReasonLimited 超过传感器限流，drop策略
+	ReasonLimited = "rate_limited"
+	// ReasonSampled 超过传感器限流，sample策略未保留
+	ReasonSampled = "rate_sampled"
+	// ReasonBlocked 超过传感器限流，block策略拒绝期间
+	ReasonBlocked = "rate_blocked"
+	// ReasonDisconnected 超过传感器限流，disconnect策略拒绝期间，断开设备会话
+	ReasonDisconnected = "rate_disconnected"
+)
+
+const (
+	// ScopeSensor 传感器限流
+	ScopeSensor = "sensor"
+	// ScopeGlobal 全局限流
+	ScopeGlobal = "global"
+)
+
+// minByteBurst 字节令牌桶最小容量
+const minByteBurst = 64 << 10
+
+// Hit - 开始超限，同一传感器持续超限只通知一次，block/disconnect策略每次开始拒绝通知
+type Hit struct {
+	Scope  string  `json:"scope"` // sensor/global
+	ID     uint64  `json:"id,omitempty"`
+	SID    string  `json:"sid,omitempty"`
+	Group  string  `json:"group,omitempty"` // 为空是默认参数
+	Policy string  `json:"policy"`
+	Frames float64 `json:"frames"`          // 每秒数据包数限制
+	Bytes  float64 `json:"bytes"`           // 每秒字节数限制
+	Until  int64   `json:"until,omitempty"` // block/disconnect策略拒绝结束时间(毫秒)
+}
+
+// Listener - 开始超限回调
+type Listener func(h Hit)
+
+// Option - 接收限流选项
+type Option func(l *Limiter)
+
+// WithDisconnect - 断开设备会话，disconnect策略拒绝期间收到数据包时调用，由接收层提供
+func WithDisconnect(fn func(id uint64, sid string)) Option {
+	return func(l *Limiter) {
+		l.disconnect = fn
+	}
+}
+
+// SensorStatus - 传感器限流状态
+type SensorStatus struct {
+	SID          string `json:"sid"`
+	Group        string `json:"group,omitempty"`
+	Policy       string `json:"policy"`
+	Limited      bool   `json:"limited"`                 // 当前是否超限
+	Dropped      int64  `json:"dropped"`                 // 限流丢弃的数据包数
+	BlockedUntil int64  `json:"blocked_until,omitempty"` // block/disconnect策略拒绝结束时间(毫秒)
+}
+
+// bucket 数据包数和字节数令牌桶，nil不限制
+type bucket struct {
+	frames *rate.Limiter
+	bytes  *rate.Limiter
+}
+
+// newBucket 创建令牌桶
+func newBucket(frames, bytes, burst float64) *bucket {
+	b := &bucket{}
+	if frames > 0 {
+		b.frames = rate.NewLimiter(rate.Limit(frames), int(math.Max(1, math.Ceil(frames*burst))))
+	}
+	if bytes > 0 {
+		b.bytes = rate.NewLimiter(rate.Limit(bytes), int(math.Max(minByteBurst, math.Ceil(bytes*burst))))
+	}
+	return b
+}
+
+// allow 两个令牌桶都有足够令牌时取走令牌
+// 超过字节桶容量的数据包在桶满时通过，超出容量的部分记为欠账，补足前拒绝后续数据包
+func (b *bucket) allow(now time.Time, n int) bool {
+	need := float64(n)
+	if b.bytes != nil && n > b.bytes.Burst() {
+		need = float64(b.bytes.Burst())
+	}
+	if b.frames != nil && b.frames.TokensAt(now) < 1 {
+		return false
+	}
+	if b.bytes != nil && b.bytes.TokensAt(now) < need {
+		return false
+	}
+	if b.frames != nil {
+		b.frames.AllowN(now, 1)
+	}
+	if b.bytes != nil {
+		// 每次预留不超过容量，令牌数可为负
+		for burst := b.bytes.Burst(); n > 0; n -= burst {
+			b.bytes.ReserveN(now, int(math.Min(float64(n), float64(burst))))
+		}
+	}
+	return true
+}
+
+// group 分组限流参数和匹配规则
+type group struct {
+	name   string
+	limits Limits
+	rules  registry.Rules
+}
+
+// sensorLimit 单个传感器限流状态
+type sensorLimit struct {
+	group   *group
+	bucket  *bucket
+	limited bool
+	over    int64 // 持续超限的数据包数，sample策略使用
+	dropped int64
+	blocked time.Time
+}
+
+// Limiter - 接收限流，按传感器分组和全局的令牌桶限制每秒数据包数和字节数
+type Limiter struct {
+	mu            sync.Mutex
+	groups        []*group // 最后一个为默认参数
+	global        *bucket
+	globalLimited bool
+	sensors       map[string]*sensorLimit
+	listener      Listener
+	disconnect    func(id uint64, sid string)
+}
+
+// New - 创建接收限流
+// @param c *Config 配置，需已检查
+// @param l Listener 开始超限回调，可为nil
+// @param opts ...Option 选项
+// @return *Limiter 接收限流
+// @return error 分组规则错误
+func New(c *Config, l Listener, opts ...Option) (*Limiter, error) {
+	lim := &Limiter{
+		global:   newBucket(c.GlobalFrames, c.GlobalBytes, c.Burst),
+		sensors:  map[string]*sensorLimit{},
+		listener: l,
+	}
+	for _, g := range c.Groups {
+		rules, err := registry.ParseRules(g.Sensors)
+		if err != nil {
+			return nil, err
+		}
+		lim.groups = append(lim.groups, &group{name: g.Name, limits: g.Limits, rules: rules})
+	}
+	lim.groups = append(lim.groups, &group{limits: c.Limits})
+	for _, opt := range opts {
+		opt(lim)
+	}
+	return lim, nil
+}
+
+// Allow - 判断数据包是否接收
+// @param id uint64 传感器编号
+// @param sid string 传感器字符串编号
+// @param n int 数据包字节数
+// @return bool 是否接收
+// @return string 丢弃原因
+func (l *Limiter) Allow(id uint64, sid string, n int) (bool, string) {
+	ok, reason, hit := l.check(time.Now(), id, sid, n)
+	if hit != nil && l.listener != nil {
+		l.listener(*hit)
+	}
+	// 拒绝期间重新连接的设备也再次断开
+	if reason == ReasonDisconnected && l.disconnect != nil {
+		l.disconnect(id, sid)
+	}
+	return ok, reason
+}
+
+// check 先按传感器再按全局限流
+func (l *Limiter) check(now time.Time, id uint64, sid string, n int) (bool, string, *Hit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.sensor(id, sid)
+	limits := &s.group.limits
+	if now.Before(s.blocked) {
+		s.dropped++
+		return false, blockReason(limits.Policy), nil
+	}
+
+	var hit *Hit
+	if !s.bucket.allow(now, n) {
+		if !s.limited || limits.Policy == PolicyBlock || limits.Policy == PolicyDisconnect {
+			hit = &Hit{Scope: ScopeSensor, ID: id, SID: sid, Group: s.group.name,
+				Policy: limits.Policy, Frames: limits.Frames, Bytes: limits.Bytes}
+		}
+		s.limited = true
+		s.over++
+		switch limits.Policy {
+		case PolicySample:
+			if (s.over-1)%int64(limits.Sample) != 0 {
+				s.dropped++
+				return false, ReasonSampled, hit
+			}
+		case PolicyBlock, PolicyDisconnect:
+			s.blocked = now.Add(time.Duration(limits.BlockDuration) * time.Millisecond)
+			hit.Until = s.blocked.UnixMilli()
+			s.dropped++
+			return false, blockReason(limits.Policy), hit
+		default:
+			s.dropped++
+			return false, ReasonLimited, hit
+		}
+	} else {
+		s.limited = false
+		s.over = 0
+	}
+
+	if !l.global.allow(now, n) {
+		if !l.globalLimited && hit == nil {
+			hit = &Hit{Scope: ScopeGlobal, Policy: PolicyDrop, Frames: limitOf(l.global.frames), Bytes: limitOf(l.global.bytes)}
+		}
+		l.globalLimited = true
+		s.dropped++
+		return false, ReasonGlobal, hit
+	}
+	l.globalLimited = false
+	return true, "", hit
+}
+
+// blockReason 拒绝期间的丢弃原因
+func blockReason(policy string) string {
+	if policy == PolicyDisconnect {
+		return ReasonDisconnected
+	}
+	return ReasonBlocked
+}
+
+// limitOf 令牌桶速率，不限制为0
+func limitOf(r *rate.Limiter) float64 {
+	if r == nil {
+		return 0
+	}
+	return float64(r.Limit())
+}
+
+// sensor 传感器限流状态，首次收到时按配置顺序匹配分组，调用方持有锁
+func (l *Limiter) sensor(id uint64, sid string) *sensorLimit {
+	if s, ok := l.sensors[sid]; ok {
+		return s
+	}
+	g := l.groups[len(l.groups)-1]
+	for _, candidate := range l.groups[:len(l.groups)-1] {
+		if candidate.rules.Match(id, sid) {
+			g = candidate
+			break
+		}
+	}
+	s := &sensorLimit{
+		group:  g,
+		bucket: newBucket(g.limits.Frames, g.limits.Bytes, g.limits.Burst),
+	}
+	l.sensors[sid] = s
+	return s
+}
+
+// Status - 超限过的传感器限流状态，按字符串编号排序
+func (l *Limiter) Status() []SensorStatus {
+	now := time.Now()
+	l.mu.Lock()
+	list := []SensorStatus{}
+	for sid, s := range l.sensors {
+		if s.dropped == 0 && !s.limited {
+			continue
+		}
+		status := SensorStatus{
+			SID:     sid,
+			Group:   s.group.name,
+			Policy:  s.group.limits.Policy,
+			Limited: s.limited || now.Before(s.blocked),
+			Dropped: s.dropped,
+		}
+		if now.Before(s.blocked) {
+			status.BlockedUntil = s.blocked.UnixMilli()
+		}
+		list = append(list, status)
+	}
+	l.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SID < list[j].SID
+	})
+	return list
+}
+
+// Unblock - 解除block/disconnect策略拒绝
+// @param sid string 传感器字符串编号
+// @return bool 传感器是否处于拒绝期间
+func (l *Limiter) Unblock(sid string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.sensors[sid]
+	if !ok || !now.Before(s.blocked) {
+		return false
+	}
+	s.blocked = time.Time{}
+	s.limited = false
+	s.over = 0
+	return true
+}
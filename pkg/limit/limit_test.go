@@ -0,0 +1,203 @@
+package limit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetConfig(t *testing.T) {
+	defer viper.Reset()
+	SetDefaultConfig()
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[limit]
+enable = true
+frames = 100
+
+[[limit.group]]
+name = "noisy"
+sensors = ["94C96*"]
+frames = 10
+policy = "sample"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Groups) != 1 {
+		t.Fatalf("unexpected groups %v", c.Groups)
+	}
+	g := c.Groups[0]
+	if g.Frames != 10 || g.Policy != PolicySample || g.Sample != 10 || g.Burst != 2 {
+		t.Fatalf("group defaults not applied %+v", g)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	var hits []Hit
+	l, err := New(&Config{
+		Enable:      true,
+		GlobalBytes: 1000,
+		Limits:      Limits{Frames: 1, Burst: 3, Policy: PolicyDrop},
+		Groups: []*Group{
+			{Name: "sample", Sensors: []string{"000000000002"}, Limits: Limits{Frames: 1, Burst: 1, Policy: PolicySample, Sample: 3}},
+			{Name: "block", Sensors: []string{"000000000003"}, Limits: Limits{Frames: 1, Burst: 1, Policy: PolicyBlock, BlockDuration: 60000}},
+		},
+	}, func(h Hit) { hits = append(hits, h) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// drop: 令牌桶容量3，之后丢弃，只通知一次
+	passed := 0
+	for i := 0; i < 6; i++ {
+		if ok, _, _ := l.check(now, 1, "000000000001", 10); ok {
+			passed++
+		}
+	}
+	if passed != 3 {
+		t.Fatalf("drop policy passed %d", passed)
+	}
+
+	// sample: 超限后每3个保留1个
+	passed = 0
+	for i := 0; i < 7; i++ {
+		if ok, _, _ := l.check(now, 2, "000000000002", 10); ok {
+			passed++
+		}
+	}
+	if passed != 3 {
+		t.Fatalf("sample policy passed %d", passed)
+	}
+
+	// block: 超限后拒绝，直到解除
+	l.check(now, 3, "000000000003", 10)
+	if ok, reason, hit := l.check(now, 3, "000000000003", 10); ok || reason != ReasonBlocked || hit == nil || hit.Until == 0 {
+		t.Fatalf("block policy %v %s %v", ok, reason, hit)
+	}
+	if ok, _, _ := l.check(now.Add(2*time.Second), 3, "000000000003", 10); ok {
+		t.Fatal("accepted while blocked")
+	}
+	if !l.Unblock("000000000003") {
+		t.Fatal("unblock")
+	}
+	if ok, _, _ := l.check(now.Add(2*time.Second), 3, "000000000003", 10); !ok {
+		t.Fatal("rejected after unblock")
+	}
+
+	// 全局字节数，最小容量64KiB，已取走部分令牌时超过容量的数据包被拒绝
+	if ok, reason, hit := l.check(now, 4, "000000000004", 70<<10); ok || reason != ReasonGlobal || hit == nil || hit.Scope != ScopeGlobal {
+		t.Fatalf("global limit %v %s %v", ok, reason, hit)
+	}
+
+	status := l.Status()
+	if len(status) != 4 || status[0].Dropped != 3 || status[1].Group != "sample" || status[1].Dropped != 4 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestLimiterLargeFrame(t *testing.T) {
+	l, err := New(&Config{
+		Enable: true,
+		Limits: Limits{Bytes: 1000, Burst: 1, Policy: PolicyDrop},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	// 超过字节桶容量(64KiB)的数据包桶满时通过，按实际大小计，欠账补足前拒绝
+	cases := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, true},
+		{time.Second, false},
+		{66 * time.Second, false},
+		{102 * time.Second, false},
+		{103 * time.Second, true},
+		{104 * time.Second, false},
+	}
+	for _, c := range cases {
+		if ok, _, _ := l.check(now.Add(c.at), 1, "000000000001", 100<<10); ok != c.want {
+			t.Fatalf("at %v: got %v, want %v", c.at, ok, c.want)
+		}
+	}
+
+	// 持续发送超过容量的数据包，接收的字节数不超过速率乘以时长加桶容量
+	const rate, burst = 1000, 64 << 10
+	l, err = New(&Config{
+		Enable: true,
+		Limits: Limits{Bytes: rate, Burst: 1, Policy: PolicyDrop},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := 0
+	for i := 0; i < 3600; i++ {
+		if ok, _, _ := l.check(now.Add(time.Duration(i)*time.Second), 1, "000000000001", 100<<10); ok {
+			accepted += 100 << 10
+		}
+	}
+	if accepted == 0 || accepted > rate*3600+burst {
+		t.Fatalf("accepted %d bytes in 3600s at %d bytes/s", accepted, rate)
+	}
+}
+
+func TestLimiterDisconnect(t *testing.T) {
+	var hits []Hit
+	var disconnected []string
+	l, err := New(&Config{
+		Enable: true,
+		Limits: Limits{Frames: 1, Burst: 1, Policy: PolicyDrop},
+		Groups: []*Group{
+			{Name: "disconnect", Sensors: []string{"000000000002"}, Limits: Limits{Frames: 1, Burst: 1, Policy: PolicyDisconnect, BlockDuration: 60000}},
+		},
+	}, func(h Hit) { hits = append(hits, h) }, WithDisconnect(func(id uint64, sid string) {
+		disconnected = append(disconnected, sid)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop策略不断开
+	for i := 0; i < 3; i++ {
+		l.Allow(1, "000000000001", 10)
+	}
+	if len(disconnected) != 0 {
+		t.Fatalf("drop policy disconnected %v", disconnected)
+	}
+
+	// 开始拒绝时断开，拒绝期间重新连接后收到数据包再次断开
+	if ok, _ := l.Allow(2, "000000000002", 10); !ok {
+		t.Fatal("rejected within burst")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, reason := l.Allow(2, "000000000002", 10); ok || reason != ReasonDisconnected {
+			t.Fatalf("disconnect policy %v %s", ok, reason)
+		}
+	}
+	if len(disconnected) != 2 || disconnected[0] != "000000000002" {
+		t.Fatalf("unexpected disconnects %v", disconnected)
+	}
+	if len(hits) != 2 || hits[1].Policy != PolicyDisconnect || hits[1].Until == 0 {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+
+	// 解除后恢复接收
+	if !l.Unblock("000000000002") {
+		t.Fatal("unblock")
+	}
+	if l.Status()[1].BlockedUntil != 0 {
+		t.Fatal("still blocked after unblock")
+	}
+}
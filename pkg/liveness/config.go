@@ -0,0 +1,114 @@
+package liveness
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+)
+
+const (
+	keyEnable        = "liveness.enable"
+	keyInterval      = "liveness.interval"
+	keyStale         = "liveness.stale"
+	keyOffline       = "liveness.offline"
+	keyCheckInterval = "liveness.check_interval"
+	keyGroup         = "liveness.group"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Enable:        true,
+	Interval:      1000,
+	Stale:         3,
+	Offline:       10,
+	CheckInterval: 1000,
+}
+
+// Group - 传感器分组上报间隔
+type Group struct {
+	Name string `toml:"name" mapstructure:"name" json:"name"`
+	// Sensors 编号、通配符(94C96*)或范围(94C960000000-94C96000FFFF)，按配置顺序匹配第一个分组
+	Sensors []string `toml:"sensors" mapstructure:"sensors" json:"sensors"`
+	// Interval 预期上报间隔(毫秒)
+	Interval int `toml:"interval" mapstructure:"interval" json:"interval"`
+}
+
+// Config - 传感器在线检测配置
+type Config struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Interval 不属于任何分组的传感器预期上报间隔(毫秒)
+	Interval int `toml:"interval" json:"interval"`
+	// Stale 超过多少个上报间隔未收到数据包为stale
+	Stale int `toml:"stale" json:"stale"`
+	// Offline 超过多少个上报间隔未收到数据包为offline
+	Offline int `toml:"offline" json:"offline"`
+	// CheckInterval 检测间隔(毫秒)
+	CheckInterval int      `toml:"check_interval" json:"check_interval"`
+	Groups        []*Group `toml:"group" json:"groups,omitempty"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyEnable, defaultConfig.Enable)
+	viper.SetDefault(keyInterval, defaultConfig.Interval)
+	viper.SetDefault(keyStale, defaultConfig.Stale)
+	viper.SetDefault(keyOffline, defaultConfig.Offline)
+	viper.SetDefault(keyCheckInterval, defaultConfig.CheckInterval)
+}
+
+// GetConfig - 获取当前配置，分组对应配置文件中的 [[liveness.group]]
+func GetConfig() (*Config, error) {
+	c := &Config{
+		Enable:        viper.GetBool(keyEnable),
+		Interval:      viper.GetInt(keyInterval),
+		Stale:         viper.GetInt(keyStale),
+		Offline:       viper.GetInt(keyOffline),
+		CheckInterval: viper.GetInt(keyCheckInterval),
+	}
+	if !viper.IsSet(keyGroup) {
+		return c, nil
+	}
+	items := []map[string]interface{}{}
+	if err := viper.UnmarshalKey(keyGroup, &items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		g := &Group{Interval: c.Interval}
+		if err := mapstructure.WeakDecode(item, g); err != nil {
+			return nil, err
+		}
+		c.Groups = append(c.Groups, g)
+	}
+	return c, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyInterval, c.Interval)
+	}
+	if c.Stale <= 0 || c.Offline <= c.Stale {
+		return fmt.Errorf("%s must be positive and less than %s, got %d and %d", keyStale, keyOffline, c.Stale, c.Offline)
+	}
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyCheckInterval, c.CheckInterval)
+	}
+	for i, g := range c.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("%s %d name is empty", keyGroup, i)
+		}
+		if g.Interval <= 0 {
+			return fmt.Errorf("%s %s interval must be positive, got %d", keyGroup, g.Name, g.Interval)
+		}
+		if _, err := registry.ParseRules(g.Sensors); err != nil {
+			return fmt.Errorf("%s %s: %w", keyGroup, g.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package liveness
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+)
+
+// State - 传感器在线状态
+type State string
+
+const (
+	// StateOnline 按预期间隔上报
+	StateOnline State = "online"
+	// StateStale 超过 Stale 个上报间隔未收到数据包
+	StateStale State = "stale"
+	// StateOffline 超过 Offline 个上报间隔未收到数据包，或预期上报但启动后从未收到
+	StateOffline State = "offline"
+)
+
+// Transition - 在线状态变化
+type Transition struct {
+	ID       uint64 `json:"id,omitempty"`
+	SID      string `json:"sid"`
+	From     State  `json:"from,omitempty"` // 首次上线为空
+	To       State  `json:"to"`
+	Group    string `json:"group,omitempty"`
+	Interval int    `json:"interval"`            // 预期上报间隔(毫秒)
+	LastSeen int64  `json:"last_seen,omitempty"` // 最后收到数据包时间(毫秒)，从未收到为0
+}
+
+// Listener - 在线状态变化回调
+type Listener func(t Transition)
+
+// SensorStatus - 传感器在线状态
+type SensorStatus struct {
+	SID      string `json:"sid"`
+	State    State  `json:"state"`
+	Group    string `json:"group,omitempty"`
+	Interval int    `json:"interval"`            // 预期上报间隔(毫秒)
+	LastSeen int64  `json:"last_seen,omitempty"` // 最后收到数据包时间(毫秒)，从未收到为0
+	Since    int64  `json:"since"`               // 进入当前状态时间(毫秒)
+}
+
+// group 分组上报间隔和匹配规则
+type group struct {
+	name     string
+	interval time.Duration
+	rules    registry.Rules
+}
+
+// sensor 单个传感器在线状态
+type sensor struct {
+	id    uint64
+	group *group
+	state State
+	last  time.Time // 从未收到为零值
+	since time.Time
+}
+
+// Tracker - 传感器在线检测，按预期上报间隔判断 online/stale/offline
+// 已收到过数据包和 expected 返回的传感器都预期上报
+type Tracker struct {
+	mu       sync.Mutex
+	config   *Config
+	groups   []*group // 最后一个为默认间隔
+	sensors  map[string]*sensor
+	started  time.Time
+	expected func() []string
+	listener Listener
+}
+
+// New - 创建传感器在线检测
+// @param c *Config 配置，需已检查
+// @param expected func() []string 预期上报的传感器字符串编号，如已配置元数据的传感器，可为nil
+// @param l Listener 状态变化回调，可为nil
+// @return *Tracker 在线检测
+// @return error 分组规则错误
+func New(c *Config, expected func() []string, l Listener) (*Tracker, error) {
+	t := &Tracker{
+		config:   c,
+		sensors:  map[string]*sensor{},
+		started:  time.Now(),
+		expected: expected,
+		listener: l,
+	}
+	for _, g := range c.Groups {
+		rules, err := registry.ParseRules(g.Sensors)
+		if err != nil {
+			return nil, err
+		}
+		t.groups = append(t.groups, &group{name: g.Name, interval: time.Duration(g.Interval) * time.Millisecond, rules: rules})
+	}
+	t.groups = append(t.groups, &group{interval: time.Duration(c.Interval) * time.Millisecond})
+	return t, nil
+}
+
+// Seen - 收到传感器数据包，未在线时切换为online
+// @param id uint64 传感器编号
+// @param sid string 传感器字符串编号
+func (t *Tracker) Seen(id uint64, sid string) {
+	t.seen(time.Now(), id, sid)
+}
+
+// seen 收到数据包
+func (t *Tracker) seen(now time.Time, id uint64, sid string) {
+	t.mu.Lock()
+	s := t.sensor(id, sid, now)
+	s.id = id
+	s.last = now
+	if s.state == StateOnline {
+		t.mu.Unlock()
+		return
+	}
+	tr := t.transition(sid, s, StateOnline, now)
+	t.mu.Unlock()
+	t.notify([]Transition{tr})
+}
+
+// sensor 传感器状态，首次出现时按配置顺序匹配分组，调用方持有锁
+func (t *Tracker) sensor(id uint64, sid string, now time.Time) *sensor {
+	if s, ok := t.sensors[sid]; ok {
+		return s
+	}
+	g := t.groups[len(t.groups)-1]
+	for _, candidate := range t.groups[:len(t.groups)-1] {
+		if candidate.rules.Match(id, sid) {
+			g = candidate
+			break
+		}
+	}
+	s := &sensor{id: id, group: g, since: now}
+	t.sensors[sid] = s
+	return s
+}
+
+// transition 切换状态，调用方持有锁
+func (t *Tracker) transition(sid string, s *sensor, to State, now time.Time) Transition {
+	tr := Transition{
+		ID:       s.id,
+		SID:      sid,
+		From:     s.state,
+		To:       to,
+		Group:    s.group.name,
+		Interval: int(s.group.interval / time.Millisecond),
+	}
+	if !s.last.IsZero() {
+		tr.LastSeen = s.last.UnixMilli()
+	}
+	s.state = to
+	s.since = now
+	return tr
+}
+
+// Check - 检测超时未上报的传感器
+// @param now time.Time 当前时间
+func (t *Tracker) Check(now time.Time) {
+	var expected []string
+	if t.expected != nil {
+		expected = t.expected()
+	}
+	var changes []Transition
+	t.mu.Lock()
+	want := map[string]struct{}{}
+	for _, text := range expected {
+		id, sid, err := registry.ParseSID(text)
+		if err != nil {
+			continue
+		}
+		want[sid] = struct{}{}
+		t.sensor(id, sid, t.started)
+	}
+	for sid, s := range t.sensors {
+		// 从未上报且不再预期上报，如已删除元数据
+		if _, ok := want[sid]; !ok && s.last.IsZero() {
+			delete(t.sensors, sid)
+			continue
+		}
+		last := s.last
+		if last.IsZero() {
+			last = t.started
+		}
+		silent := now.Sub(last)
+		var to State
+		switch {
+		case silent > s.group.interval*time.Duration(t.config.Offline):
+			to = StateOffline
+		case silent > s.group.interval*time.Duration(t.config.Stale):
+			to = StateStale
+		default:
+			continue
+		}
+		// 从未收到的传感器不经过stale，直接offline
+		if s.state == to || s.state == StateOffline || (s.last.IsZero() && to == StateStale) {
+			continue
+		}
+		changes = append(changes, t.transition(sid, s, to, now))
+	}
+	t.mu.Unlock()
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].SID < changes[j].SID
+	})
+	t.notify(changes)
+}
+
+// notify 状态变化回调，不持有锁
+func (t *Tracker) notify(changes []Transition) {
+	if t.listener == nil {
+		return
+	}
+	for _, tr := range changes {
+		t.listener(tr)
+	}
+}
+
+// Start - 定时检测
+func (t *Tracker) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(t.config.CheckInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			t.Check(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sensors - 传感器在线状态，按字符串编号排序
+// @param state State 状态过滤，为空不过滤
+// @return []SensorStatus 在线状态
+func (t *Tracker) Sensors(state State) []SensorStatus {
+	t.mu.Lock()
+	list := []SensorStatus{}
+	for sid, s := range t.sensors {
+		if s.state == "" || (state != "" && s.state != state) {
+			continue
+		}
+		status := SensorStatus{
+			SID:      sid,
+			State:    s.state,
+			Group:    s.group.name,
+			Interval: int(s.group.interval / time.Millisecond),
+			Since:    s.since.UnixMilli(),
+		}
+		if !s.last.IsZero() {
+			status.LastSeen = s.last.UnixMilli()
+		}
+		list = append(list, status)
+	}
+	t.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SID < list[j].SID
+	})
+	return list
+}
+
+// Summary - 在线状态汇总
+type Summary struct {
+	Online  int            `json:"online"`
+	Stale   int            `json:"stale"`
+	Offline int            `json:"offline"`
+	Sensors []SensorStatus `json:"sensors"` // 当前离线的预期上报传感器
+}
+
+// Summary - 各状态传感器数和当前离线的传感器
+func (t *Tracker) Summary() *Summary {
+	list := t.Sensors("")
+	s := &Summary{Sensors: []SensorStatus{}}
+	for _, status := range list {
+		switch status.State {
+		case StateOnline:
+			s.Online++
+		case StateStale:
+			s.Stale++
+		case StateOffline:
+			s.Offline++
+			s.Sensors = append(s.Sensors, status)
+		}
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package liveness
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetConfig(t *testing.T) {
+	defer viper.Reset()
+	SetDefaultConfig()
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[liveness]
+interval = 500
+
+[[liveness.group]]
+name = "slow"
+sensors = ["94C96*"]
+interval = 60000
+
+[[liveness.group]]
+name = "default"
+sensors = ["000000000001"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Groups) != 2 || c.Groups[0].Interval != 60000 || c.Groups[1].Interval != 500 {
+		t.Fatalf("unexpected groups %+v %+v", c.Groups[0], c.Groups[1])
+	}
+	c.Offline = c.Stale
+	if err := c.Validate(); err == nil {
+		t.Fatal("offline not greater than stale accepted")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	var changes []Transition
+	tr, err := New(&Config{
+		Enable:   true,
+		Interval: 1000,
+		Stale:    3,
+		Offline:  10,
+		Groups:   []*Group{{Name: "slow", Sensors: []string{"000000000002"}, Interval: 60000}},
+	}, func() []string {
+		return []string{"000000000003", "invalid"}
+	}, func(c Transition) { changes = append(changes, c) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := tr.started
+
+	// 首次上线，持续上报不重复通知
+	tr.seen(now, 1, "000000000001")
+	tr.seen(now, 2, "000000000002")
+	tr.seen(now.Add(time.Second), 1, "000000000001")
+	if len(changes) != 2 || changes[0].To != StateOnline || changes[0].From != "" {
+		t.Fatalf("unexpected online %+v", changes)
+	}
+
+	// 3个间隔后stale，慢速分组不变，从未上报的预期传感器不经过stale
+	changes = nil
+	tr.Check(now.Add(5 * time.Second))
+	if len(changes) != 1 || changes[0].SID != "000000000001" || changes[0].To != StateStale {
+		t.Fatalf("unexpected stale %+v", changes)
+	}
+
+	// 10个间隔后offline，预期传感器从未上报也offline
+	changes = nil
+	tr.Check(now.Add(12 * time.Second))
+	if len(changes) != 2 || changes[0].SID != "000000000001" || changes[0].From != StateStale ||
+		changes[1].SID != "000000000003" || changes[1].LastSeen != 0 {
+		t.Fatalf("unexpected offline %+v", changes)
+	}
+	changes = nil
+	tr.Check(now.Add(20 * time.Second))
+	if len(changes) != 0 {
+		t.Fatalf("repeated offline %+v", changes)
+	}
+
+	// 恢复上报
+	tr.seen(now.Add(21*time.Second), 1, "000000000001")
+	if len(changes) != 1 || changes[0].From != StateOffline || changes[0].To != StateOnline {
+		t.Fatalf("unexpected recovery %+v", changes)
+	}
+
+	s := tr.Summary()
+	if s.Online != 2 || s.Offline != 1 || len(s.Sensors) != 1 || s.Sensors[0].SID != "000000000003" {
+		t.Fatalf("unexpected summary %+v", s)
+	}
+	if list := tr.Sensors(StateOnline); len(list) != 2 || list[1].Group != "slow" || list[1].Interval != 60000 {
+		t.Fatalf("unexpected sensors %+v", list)
+	}
+}
+
+func TestTrackerExpectedRemoved(t *testing.T) {
+	expected := []string{"000000000003"}
+	tr, err := New(&Config{Enable: true, Interval: 1000, Stale: 3, Offline: 10}, func() []string {
+		return expected
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Check(tr.started.Add(time.Minute))
+	if s := tr.Summary(); s.Offline != 1 {
+		t.Fatalf("unexpected summary %+v", s)
+	}
+	expected = nil
+	tr.Check(tr.started.Add(2 * time.Minute))
+	if list := tr.Sensors(""); len(list) != 0 {
+		t.Fatalf("sensor no longer expected %+v", list)
+	}
+}
@@ -0,0 +1,42 @@
+package meta
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyFile = "meta.file"
+)
+
+// 配置默认值
+var defaultConfig = Config{
+	File: "./data/metadata.json",
+}
+
+// Config - 传感器元数据配置
+type Config struct {
+	// File 元数据文件，修改后整体重写，重启后读取
+	File string `toml:"file" json:"file"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyFile, defaultConfig.File)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		File: viper.GetString(keyFile),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.File == "" {
+		return fmt.Errorf("%s must be set", keyFile)
+	}
+	return nil
+}
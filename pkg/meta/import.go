@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csv固定列，其他列作为自定义标签
+const (
+	columnSID        = "sid"
+	columnSite       = "site"
+	columnCabinet    = "cabinet"
+	columnPhase      = "phase"
+	columnSampleRate = "sample_rate"
+)
+
+// ParseCSV - 解析CSV元数据，首行为列名，必须包含sid列
+// 列 site、cabinet、phase、sample_rate 对应元数据字段，其他列作为自定义标签，空值忽略
+// @param r io.Reader CSV内容
+// @return []Metadata 元数据
+// @return error 格式错误
+func ParseCSV(r io.Reader) ([]Metadata, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	hasSID := false
+	for _, h := range header {
+		hasSID = hasSID || h == columnSID
+	}
+	if !hasSID {
+		return nil, fmt.Errorf("%w: csv header has no %s column", ErrInvalid, columnSID)
+	}
+
+	var list []Metadata
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		var m Metadata
+		for i, v := range record {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			switch header[i] {
+			case columnSID:
+				m.SID = v
+			case columnSite:
+				m.Site = v
+			case columnCabinet:
+				m.Cabinet = v
+			case columnPhase:
+				m.Phase = v
+			case columnSampleRate:
+				if m.SampleRate, err = strconv.Atoi(v); err != nil {
+					return nil, fmt.Errorf("%w: line %d sample_rate %q", ErrInvalid, len(list)+2, v)
+				}
+			default:
+				if m.Tags == nil {
+					m.Tags = map[string]string{}
+				}
+				m.Tags[header[i]] = v
+			}
+		}
+		list = append(list, m)
+	}
+}
+
+// ParseJSON - 解析JSON元数据数组
+// @param r io.Reader JSON内容
+// @return []Metadata 元数据
+// @return error 格式错误
+func ParseJSON(r io.Reader) ([]Metadata, error) {
+	var list []Metadata
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return list, nil
+}
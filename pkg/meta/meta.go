@@ -0,0 +1,208 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
+)
+
+var (
+	// ErrInvalid 元数据格式错误
+	ErrInvalid = errors.New("invalid metadata")
+	// ErrNotFound 传感器没有元数据
+	ErrNotFound = errors.New("metadata not found")
+)
+
+// Metadata - 传感器安装位置与标签
+type Metadata struct {
+	SID        string            `json:"sid"`
+	Site       string            `json:"site,omitempty"`        // 站点
+	Cabinet    string            `json:"cabinet,omitempty"`     // 柜号
+	Phase      string            `json:"phase,omitempty"`       // 相位
+	SampleRate int               `json:"sample_rate,omitempty"` // 采样率，0使用 sample.rate
+	Tags       map[string]string `json:"tags,omitempty"`        // 自定义标签
+	Updated    int64             `json:"updated,omitempty"`     // 修改时间(毫秒)
+}
+
+// Rate - 传感器采样率，未设置元数据或未覆盖时返回默认值
+// @param m *Metadata 元数据，可为nil
+// @param def int 默认采样率
+// @return int 采样率
+func Rate(m *Metadata, def int) int {
+	if m == nil || m.SampleRate <= 0 {
+		return def
+	}
+	return m.SampleRate
+}
+
+// normalize 检查并规范化传感器编号
+func (m *Metadata) normalize() error {
+	id, err := strconv.ParseUint(strings.TrimSpace(m.SID), 16, 64)
+	if err != nil {
+		return fmt.Errorf("%w: sensor id %q", ErrInvalid, m.SID)
+	}
+	m.SID = fmt.Sprintf("%012X", id)
+	if m.SampleRate < 0 {
+		return fmt.Errorf("%w: %s sample_rate must not be negative, got %d", ErrInvalid, m.SID, m.SampleRate)
+	}
+	return nil
+}
+
+// Store - 传感器元数据，保存到本地文件
+// 元数据修改时整体替换，Get 返回的指针可随数据包、特征和事件传递，不会被修改
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	items map[string]*Metadata
+}
+
+// New - 创建元数据存储，读取已保存的元数据
+// @param c *Config 配置
+// @return *Store 元数据存储
+// @return error 文件读取错误
+func New(c *Config) (*Store, error) {
+	s := &Store{path: c.File, items: map[string]*Metadata{}}
+	raw, err := os.ReadFile(c.File)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Metadata
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("load %s: %w", c.File, err)
+	}
+	for _, m := range list {
+		if err := m.normalize(); err != nil {
+			return nil, fmt.Errorf("load %s: %w", c.File, err)
+		}
+		s.items[m.SID] = m
+		setInfo(nil, m)
+	}
+	return s, nil
+}
+
+// Get - 传感器元数据
+// @param sid string 传感器字符串编号
+// @return *Metadata 元数据，未设置为nil
+func (s *Store) Get(sid string) *Metadata {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items[sid]
+}
+
+// List - 全部元数据，按字符串编号排序
+func (s *Store) List() []Metadata {
+	s.mu.RLock()
+	list := make([]Metadata, 0, len(s.items))
+	for _, m := range s.items {
+		list = append(list, *m)
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SID < list[j].SID
+	})
+	return list
+}
+
+// Put - 设置传感器元数据，替换已有元数据
+func (s *Store) Put(m Metadata) (Metadata, error) {
+	if err := m.normalize(); err != nil {
+		return Metadata{}, err
+	}
+	if _, err := s.Import([]Metadata{m}, false); err != nil {
+		return Metadata{}, err
+	}
+	return *s.Get(m.SID), nil
+}
+
+// Import - 批量设置元数据，全部检查通过后写入
+// @param list []Metadata 元数据
+// @param replace bool 是否删除不在列表中的元数据
+// @return int 写入数量
+// @return error 格式或保存错误
+func (s *Store) Import(list []Metadata, replace bool) (int, error) {
+	now := time.Now().UnixMilli()
+	items := make([]*Metadata, 0, len(list))
+	for i := range list {
+		m := list[i]
+		if err := m.normalize(); err != nil {
+			return 0, err
+		}
+		m.Updated = now
+		items = append(items, &m)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replace {
+		for sid, old := range s.items {
+			delete(s.items, sid)
+			setInfo(old, nil)
+		}
+	}
+	for _, m := range items {
+		setInfo(s.items[m.SID], m)
+		s.items[m.SID] = m
+	}
+	return len(items), s.save()
+}
+
+// Delete - 删除传感器元数据
+func (s *Store) Delete(sid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.items[sid]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, sid)
+	}
+	delete(s.items, sid)
+	setInfo(old, nil)
+	return s.save()
+}
+
+// save 写入临时文件后替换，调用方持有锁
+func (s *Store) save() error {
+	list := make([]*Metadata, 0, len(s.items))
+	for _, m := range s.items {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SID < list[j].SID
+	})
+	raw, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// setInfo 更新传感器位置指标
+func setInfo(old, m *Metadata) {
+	if old != nil {
+		metrics.SensorInfo.DeleteLabelValues(old.SID, old.Site, old.Cabinet, old.Phase)
+	}
+	if m != nil {
+		metrics.SensorInfo.WithLabelValues(m.SID, m.Site, m.Cabinet, m.Phase).Set(1)
+	}
+}
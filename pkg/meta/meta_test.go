@@ -0,0 +1,79 @@
+package meta
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	list, err := ParseCSV(strings.NewReader("sid,site,cabinet,phase,sample_rate,feeder\n" +
+		"94c96000c248,plant-a,C12,A,51200,F3\n" +
+		"94C96000C249,plant-a,,B,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("unexpected list %v", list)
+	}
+	m := list[0]
+	if m.Site != "plant-a" || m.Cabinet != "C12" || m.Phase != "A" || m.SampleRate != 51200 || m.Tags["feeder"] != "F3" {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+	if list[1].Cabinet != "" || list[1].Tags != nil {
+		t.Fatalf("empty values not ignored %+v", list[1])
+	}
+
+	if _, err := ParseCSV(strings.NewReader("site\nplant-a\n")); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing sid column: %v", err)
+	}
+	if _, err := ParseCSV(strings.NewReader("sid,sample_rate\n1,fast\n")); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("invalid sample rate: %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	cfg := &Config{File: filepath.Join(t.TempDir(), "metadata.json")}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.Put(Metadata{SID: "c248", Site: "plant-a", SampleRate: 51200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SID != "00000000C248" || m.Updated == 0 {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+	if Rate(s.Get("00000000C248"), 25600) != 51200 || Rate(s.Get("00000000C249"), 25600) != 25600 {
+		t.Fatal("unexpected sample rate")
+	}
+
+	// 导入失败时不修改
+	if _, err := s.Import([]Metadata{{SID: "1"}, {SID: "not-hex"}}, true); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("invalid import: %v", err)
+	}
+	if len(s.List()) != 1 {
+		t.Fatalf("partial import %v", s.List())
+	}
+	if n, err := s.Import([]Metadata{{SID: "1", Site: "plant-b"}, {SID: "2"}}, true); err != nil || n != 2 {
+		t.Fatalf("import %d %v", n, err)
+	}
+
+	// 重启后读取
+	s, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := s.List()
+	if len(list) != 2 || list[0].SID != "000000000001" || list[0].Site != "plant-b" {
+		t.Fatalf("unexpected list %v", list)
+	}
+	if err := s.Delete("000000000002"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("000000000002"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete missing: %v", err)
+	}
+}
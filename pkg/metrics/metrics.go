@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace 指标前缀
+const namespace = "arc_consumer"
+
+var (
+	// FramesReceived 接收的数据包数
+	FramesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "frames_received_total",
+		Help:      "Frames received from sensors.",
+	}, []string{"sensor", "shard"})
+
+	// BytesReceived 接收的数据包字节数
+	BytesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_received_total",
+		Help:      "Frame bytes received from sensors.",
+	}, []string{"sensor", "shard"})
+
+	// FramesDecoded 解包成功的数据包数
+	FramesDecoded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "frames_decoded_total",
+		Help:      "Frames decoded with an arc segment.",
+	}, []string{"sensor", "shard"})
+
+	// FramesForwarded 全部转发目标写入成功的数据包数
+	FramesForwarded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "frames_forwarded_total",
+		Help:      "Frames written to every matching sink without error.",
+	}, []string{"sensor", "shard"})
+
+	// FramesDropped 丢弃的数据包数
+	FramesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "frames_dropped_total",
+		Help:      "Frames dropped before forwarding, by reason.",
+	}, []string{"sensor", "shard", "reason"})
+
+	// QueueDepth 数据处理管道待处理数据包数
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "frame_queue_depth",
+		Help:      "Frames waiting in the shard channel.",
+	}, []string{"shard"})
+
+	// HandleDuration 单个数据包处理耗时
+	HandleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handle_duration_seconds",
+		Help:      "Latency of handling one frame: decode, sinks, features.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"shard"})
+
+	// SinkWrites 转发目标写入次数
+	SinkWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sink_writes_total",
+		Help:      "Frames written to a sink.",
+	}, []string{"sink", "type"})
+
+	// SinkErrors 转发目标写入失败次数
+	SinkErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sink_errors_total",
+		Help:      "Failed sink writes of frames, features and events.",
+	}, []string{"sink", "type"})
+
+	// GrpcSendDuration grpc发送耗时
+	GrpcSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_send_duration_seconds",
+		Help:      "Latency of sending one frame to arc-storage.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"pool"})
+
+	// GrpcErrors grpc发送失败次数
+	GrpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_errors_total",
+		Help:      "Failed sends to arc-storage.",
+	}, []string{"pool"})
+
+	// GrpcReconnects grpc重连次数
+	GrpcReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_reconnects_total",
+		Help:      "Connection failures that scheduled a reconnect.",
+	}, []string{"pool"})
+
+	// GrpcState grpc连接状态: 0 connecting, 1 ready, 2 degraded, 3 open-circuit
+	GrpcState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_pool_state",
+		Help:      "Connection state: 0 connecting, 1 ready, 2 degraded, 3 open-circuit.",
+	}, []string{"pool"})
+
+	// GossipSyncs 集群同步传感器编号次数
+	GossipSyncs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gossip_sync_total",
+		Help:      "Calls publishing sensor ids to the gossip kv cache.",
+	})
+
+	// GossipSyncErrors 集群同步失败次数
+	GossipSyncErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gossip_sync_errors_total",
+		Help:      "Failed calls publishing sensor ids to the gossip kv cache.",
+	})
+
+	// SensorInfo 传感器安装位置，值恒为1，查询时按sensor标签关联其他指标
+	SensorInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sensor_info",
+		Help:      "Sensor installation metadata, always 1; join on the sensor label.",
+	}, []string{"sensor", "site", "cabinet", "phase"})
+
+	// SensorUp 传感器是否按预期间隔上报，stale和offline为0
+	SensorUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sensor_up",
+		Help:      "Whether the sensor reports within its expected interval, 0 when stale or offline.",
+	}, []string{"sensor"})
+)
+
+// collectors 全部指标
+var collectors = []prometheus.Collector{
+	FramesReceived, BytesReceived, FramesDecoded, FramesForwarded, FramesDropped,
+	QueueDepth, HandleDuration,
+	SinkWrites, SinkErrors,
+	GrpcSendDuration, GrpcErrors, GrpcReconnects, GrpcState,
+	GossipSyncs, GossipSyncErrors,
+	SensorInfo, SensorUp,
+}
+
+// Register - 以集群、服务为固定标签注册到默认registry，由 /metrics 输出
+// @param cluster string 集群
+// @param service string 服务名
+// @return err 错误信息
+func Register(cluster, service string) error {
+	return register(prometheus.DefaultRegisterer, cluster, service)
+}
+
+// register 注册到指定registry，已注册的指标忽略
+func register(r prometheus.Registerer, cluster, service string) error {
+	wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"cluster": cluster, "service": service}, r)
+	for _, c := range collectors {
+		if err := wrapped.Register(c); err != nil {
+			are := prometheus.AlreadyRegisteredError{}
+			if errors.As(err, &are) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Shard - 分片标签
+func Shard(index uint64) string {
+	return strconv.FormatUint(index, 10)
+}
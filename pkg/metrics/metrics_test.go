@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegister(t *testing.T) {
+	r := prometheus.NewRegistry()
+	if err := register(r, "c1", "arc-consumer"); err != nil {
+		t.Fatal(err)
+	}
+	// 重复注册忽略
+	if err := register(r, "c1", "arc-consumer"); err != nil {
+		t.Fatal(err)
+	}
+
+	FramesReceived.WithLabelValues("94C96000C248", Shard(3)).Inc()
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "arc_consumer_frames_received_total" {
+			continue
+		}
+		labels := map[string]string{}
+		for _, l := range f.GetMetric()[0].GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["cluster"] != "c1" || labels["service"] != "arc-consumer" ||
+			labels["sensor"] != "94C96000C248" || labels["shard"] != "3" {
+			t.Fatalf("unexpected labels %v", labels)
+		}
+		return
+	}
+	t.Fatal("frames_received_total not gathered")
+}
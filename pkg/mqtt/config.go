@@ -0,0 +1,159 @@
+package mqtt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyBroker            = "mqtt.broker"
+	keyClientID          = "mqtt.client_id"
+	keyUsername          = "mqtt.username"
+	keyPassword          = "mqtt.password"
+	keyCluster           = "mqtt.cluster"
+	keyFeatureTopic      = "mqtt.feature_topic"
+	keyEventTopic        = "mqtt.event_topic"
+	keyStatusTopic       = "mqtt.status_topic"
+	keyAvailabilityTopic = "mqtt.availability_topic"
+	keyQoS               = "mqtt.qos"
+	keyStatusInterval    = "mqtt.status_interval"
+	keyKeepalive         = "mqtt.keepalive"
+	keyTimeout           = "mqtt.timeout"
+	keyQueueSize         = "mqtt.queue_size"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Broker:            "tcp://localhost:1883",
+	ClientID:          "arc-consumer",
+	Cluster:           "default",
+	FeatureTopic:      "arc/{cluster}/{sid}/feature",
+	EventTopic:        "arc/{cluster}/{sid}/event",
+	StatusTopic:       "arc/{cluster}/{sid}/status",
+	AvailabilityTopic: "arc/{cluster}/consumer/{client_id}/availability",
+	QoS:               1,
+	StatusInterval:    10000,
+	Keepalive:         30000,
+	Timeout:           5000,
+	QueueSize:         4096,
+}
+
+// Config - mqtt转发配置，[mqtt] 为默认值，[[sink]] options 可逐项覆盖
+// 主题模板支持 {cluster}、{client_id}、{sid}、{id}、{type}，为空不发送
+type Config struct {
+	// Broker 地址，如 tcp://host:1883、ssl://host:8883、ws://host:8083/mqtt
+	Broker   string `toml:"broker" json:"broker"`
+	ClientID string `toml:"client_id" json:"client_id"`
+	Username string `toml:"username" json:"username,omitempty"`
+	Password string `toml:"password" json:"-"`
+	Cluster  string `toml:"cluster" json:"cluster"`
+	// FeatureTopic 特征主题模板
+	FeatureTopic string `toml:"feature_topic" json:"feature_topic,omitempty"`
+	// EventTopic 电弧事件主题模板
+	EventTopic string `toml:"event_topic" json:"event_topic,omitempty"`
+	// StatusTopic 传感器最新状态主题模板，保留消息
+	StatusTopic string `toml:"status_topic" json:"status_topic,omitempty"`
+	// AvailabilityTopic arc-consumer在线状态主题模板，保留消息，断线时由遗嘱发布 offline
+	AvailabilityTopic string `toml:"availability_topic" json:"availability_topic,omitempty"`
+	// QoS 特征和事件服务质量 0、1、2，状态和在线消息固定为1
+	QoS byte `toml:"qos" json:"qos"`
+	// StatusInterval 同一传感器状态最短发布间隔(毫秒)，电弧事件立即发布
+	StatusInterval int `toml:"status_interval" json:"status_interval"`
+	Keepalive      int `toml:"keepalive" json:"keepalive"`
+	// Timeout 连接和发送超时(毫秒)
+	Timeout int `toml:"timeout" json:"timeout"`
+	// QueueSize 发送队列长度，队列满时丢弃
+	QueueSize int `toml:"queue_size" json:"queue_size"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyBroker, defaultConfig.Broker)
+	viper.SetDefault(keyClientID, defaultConfig.ClientID)
+	viper.SetDefault(keyUsername, defaultConfig.Username)
+	viper.SetDefault(keyPassword, defaultConfig.Password)
+	viper.SetDefault(keyCluster, defaultConfig.Cluster)
+	viper.SetDefault(keyFeatureTopic, defaultConfig.FeatureTopic)
+	viper.SetDefault(keyEventTopic, defaultConfig.EventTopic)
+	viper.SetDefault(keyStatusTopic, defaultConfig.StatusTopic)
+	viper.SetDefault(keyAvailabilityTopic, defaultConfig.AvailabilityTopic)
+	viper.SetDefault(keyQoS, defaultConfig.QoS)
+	viper.SetDefault(keyStatusInterval, defaultConfig.StatusInterval)
+	viper.SetDefault(keyKeepalive, defaultConfig.Keepalive)
+	viper.SetDefault(keyTimeout, defaultConfig.Timeout)
+	viper.SetDefault(keyQueueSize, defaultConfig.QueueSize)
+}
+
+// GetConfig - 获取 [mqtt] 配置
+func GetConfig() *Config {
+	return &Config{
+		Broker:            viper.GetString(keyBroker),
+		ClientID:          viper.GetString(keyClientID),
+		Username:          viper.GetString(keyUsername),
+		Password:          viper.GetString(keyPassword),
+		Cluster:           viper.GetString(keyCluster),
+		FeatureTopic:      viper.GetString(keyFeatureTopic),
+		EventTopic:        viper.GetString(keyEventTopic),
+		StatusTopic:       viper.GetString(keyStatusTopic),
+		AvailabilityTopic: viper.GetString(keyAvailabilityTopic),
+		QoS:               byte(viper.GetUint(keyQoS)),
+		StatusInterval:    viper.GetInt(keyStatusInterval),
+		Keepalive:         viper.GetInt(keyKeepalive),
+		Timeout:           viper.GetInt(keyTimeout),
+		QueueSize:         viper.GetInt(keyQueueSize),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [mqtt] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("%s is empty", keyBroker)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("%s is empty", keyClientID)
+	}
+	if c.QoS > 2 {
+		return fmt.Errorf("%s must be 0, 1 or 2, got %d", keyQoS, c.QoS)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyTimeout, c.Timeout)
+	}
+	if c.QueueSize <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyQueueSize, c.QueueSize)
+	}
+	return nil
+}
+
+// topic 主题模板替换
+func (c *Config) topic(template, sid string, id uint64, typ string) string {
+	if template == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{cluster}", c.Cluster,
+		"{client_id}", c.ClientID,
+		"{sid}", sid,
+		"{id}", strconv.FormatUint(id, 10),
+		"{type}", typ,
+	).Replace(template)
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
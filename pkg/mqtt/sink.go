@@ -0,0 +1,294 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType mqtt转发目标类型
+const SinkType = "mqtt"
+
+const (
+	availabilityOnline  = "online"
+	availabilityOffline = "offline"
+)
+
+// errQueueFull 发送队列已满，丢弃数据
+var errQueueFull = errors.New("mqtt publish queue full")
+
+// errClosed 转发目标已关闭
+var errClosed = errors.New("mqtt sink closed")
+
+// errTimeout 发送超时
+var errTimeout = errors.New("mqtt publish timeout")
+
+// Status - 传感器最新状态，以保留消息发布
+type Status struct {
+	SID       string  `json:"sid"`
+	ID        uint64  `json:"id"`
+	Timestamp int64   `json:"timestamp"` // 最近特征时间(毫秒)
+	RMS       float64 `json:"rms"`
+	Peak      int32   `json:"peak"`
+	LastEvent int64   `json:"last_event,omitempty"` // 最近电弧事件时间(毫秒)
+}
+
+// sensorStatus 传感器状态和上次发布时间
+type sensorStatus struct {
+	Status
+	published time.Time
+}
+
+// message 待发布消息
+type message struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+// Sink - 将特征和电弧事件以JSON发布到mqtt，原始数据包不发送
+type Sink struct {
+	config  *Config
+	client  paho.Client
+	queue   chan *message // 有界发送队列
+	queueMu sync.RWMutex  // 保护队列关闭
+	closed  bool
+	done    chan struct{}
+
+	statusMu sync.Mutex
+	status   map[string]*sensorStatus
+
+	mu          sync.Mutex
+	lastErr     error
+	lastErrAt   time.Time
+	lastSuccess time.Time
+
+	sent   *atomic.Int64
+	failed *atomic.Int64
+}
+
+// NewSink - 根据转发目标配置创建mqtt客户端
+// @param c *sink.Config 转发目标配置，options覆盖 [mqtt] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// New - 创建mqtt转发目标，后台连接broker，断线自动重连
+// @param cfg *Config mqtt配置
+// @return *Sink 转发目标
+// @return err 错误信息
+func New(cfg *Config) (*Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		config: cfg,
+		queue:  make(chan *message, cfg.QueueSize),
+		done:   make(chan struct{}),
+		status: map[string]*sensorStatus{},
+		sent:   atomic.NewInt64(0),
+		failed: atomic.NewInt64(0),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetKeepAlive(millisecond(cfg.Keepalive)).
+		SetConnectTimeout(millisecond(cfg.Timeout)).
+		SetWriteTimeout(millisecond(cfg.Timeout)).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(s.onConnect).
+		SetConnectionLostHandler(s.onConnectionLost)
+	if topic := s.availabilityTopic(); topic != "" {
+		opts.SetWill(topic, availabilityOffline, 1, true)
+	}
+	s.client = paho.NewClient(opts)
+	s.client.Connect()
+
+	go s.publish()
+	return s, nil
+}
+
+// availabilityTopic arc-consumer在线状态主题
+func (s *Sink) availabilityTopic() string {
+	return s.config.topic(s.config.AvailabilityTopic, "", 0, "")
+}
+
+// onConnect 连接成功后发布在线状态
+func (s *Sink) onConnect(paho.Client) {
+	if topic := s.availabilityTopic(); topic != "" {
+		s.client.Publish(topic, 1, true, availabilityOnline)
+	}
+}
+
+// onConnectionLost 记录断线原因
+func (s *Sink) onConnectionLost(_ paho.Client, err error) {
+	s.setError(err)
+}
+
+// setError 记录发送失败
+func (s *Sink) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	s.lastErrAt = time.Now()
+}
+
+// publish 依次发布队列消息，等待回执，队列关闭后退出
+func (s *Sink) publish() {
+	defer close(s.done)
+	timeout := millisecond(s.config.Timeout)
+	for msg := range s.queue {
+		token := s.client.Publish(msg.topic, msg.qos, msg.retained, msg.payload)
+		err := errTimeout
+		if token.WaitTimeout(timeout) {
+			err = token.Error()
+		}
+		if err != nil {
+			s.failed.Inc()
+			s.setError(err)
+			continue
+		}
+		s.sent.Inc()
+		s.mu.Lock()
+		s.lastSuccess = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// send 消息入发送队列，队列满时不阻塞数据处理协程
+func (s *Sink) send(topic string, qos byte, retained bool, v interface{}) error {
+	if topic == "" {
+		return nil
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	if s.closed {
+		return errClosed
+	}
+	select {
+	case s.queue <- &message{topic: topic, qos: qos, retained: retained, payload: payload}:
+		return nil
+	default:
+		s.failed.Inc()
+		return errQueueFull
+	}
+}
+
+// Write - 原始数据包不通过mqtt发送
+func (s *Sink) Write(*sink.Frame) error {
+	return nil
+}
+
+// WriteFeature - 发布特征，按间隔更新传感器状态
+func (s *Sink) WriteFeature(f *feature.Feature) error {
+	c := s.config
+	if err := s.send(c.topic(c.FeatureTopic, f.SID, f.ID, ""), c.QoS, false, f); err != nil {
+		return err
+	}
+
+	s.statusMu.Lock()
+	st := s.sensor(f.SID, f.ID)
+	st.Timestamp = f.Timestamp
+	st.RMS = f.RMS
+	st.Peak = f.Peak
+	now := time.Now()
+	if now.Sub(st.published) < millisecond(c.StatusInterval) {
+		s.statusMu.Unlock()
+		return nil
+	}
+	st.published = now
+	status := st.Status
+	s.statusMu.Unlock()
+	return s.send(c.topic(c.StatusTopic, f.SID, f.ID, ""), 1, true, &status)
+}
+
+// WriteEvent - 发布电弧事件，立即更新传感器状态
+func (s *Sink) WriteEvent(ev *event.Event) error {
+	c := s.config
+	if err := s.send(c.topic(c.EventTopic, ev.SID, ev.ID, ev.Type), c.QoS, false, ev); err != nil {
+		return err
+	}
+	if ev.Type != event.TypeArc || ev.SID == "" {
+		return nil
+	}
+
+	s.statusMu.Lock()
+	st := s.sensor(ev.SID, ev.ID)
+	st.LastEvent = ev.Timestamp
+	st.published = time.Now()
+	status := st.Status
+	s.statusMu.Unlock()
+	return s.send(c.topic(c.StatusTopic, ev.SID, ev.ID, ""), 1, true, &status)
+}
+
+// sensor 获取传感器状态，调用方持有锁
+func (s *Sink) sensor(sid string, id uint64) *sensorStatus {
+	st, ok := s.status[sid]
+	if !ok {
+		st = &sensorStatus{Status: Status{SID: sid, ID: id}}
+		s.status[sid] = st
+	}
+	return st
+}
+
+// Flush - 消息由发送协程立即发布
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Close - 发布队列中剩余消息和离线状态后断开
+func (s *Sink) Close() error {
+	s.queueMu.Lock()
+	if s.closed {
+		s.queueMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.queueMu.Unlock()
+	<-s.done
+
+	if topic := s.availabilityTopic(); topic != "" && s.client.IsConnectionOpen() {
+		s.client.Publish(topic, 1, true, availabilityOffline).WaitTimeout(millisecond(s.config.Timeout))
+	}
+	s.client.Disconnect(250)
+	return nil
+}
+
+// Health - 已连接且最近一次失败之后有成功发送时健康
+func (s *Sink) Health() sink.Health {
+	if !s.client.IsConnectionOpen() {
+		return sink.Health{Healthy: false, Message: "not connected to " + s.config.Broker}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastErr == nil || s.lastSuccess.After(s.lastErrAt) {
+		return sink.Health{Healthy: true, Message: fmt.Sprintf("sent %d, failed %d", s.sent.Load(), s.failed.Load())}
+	}
+	return sink.Health{Healthy: false, Message: s.lastErr.Error()}
+}
@@ -0,0 +1,173 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+)
+
+// broker 本地mqtt服务端，记录连接和发布的消息
+type broker struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	connect  *packets.ConnectPacket
+	messages []*packets.PublishPacket
+}
+
+func newBroker(t *testing.T) *broker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		var reply packets.ControlPacket
+		switch p := p.(type) {
+		case *packets.ConnectPacket:
+			b.mu.Lock()
+			b.connect = p
+			b.mu.Unlock()
+			reply = packets.NewControlPacket(packets.Connack)
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			b.messages = append(b.messages, p)
+			b.mu.Unlock()
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				reply = ack
+			}
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if reply != nil {
+			if err := reply.Write(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wait 等待收到n条消息
+func (b *broker) wait(t *testing.T, n int) []*packets.PublishPacket {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		if len(b.messages) >= n {
+			list := append([]*packets.PublishPacket{}, b.messages...)
+			b.mu.Unlock()
+			return list
+		}
+		b.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %d messages", n)
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	b := newBroker(t)
+	defer b.ln.Close()
+
+	SetDefaultConfig()
+	cfg := GetConfig()
+	cfg.Broker = "tcp://" + b.ln.Addr().String()
+	cfg.Cluster = "plant1"
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 在线状态
+	msgs := b.wait(t, 1)
+	if msgs[0].TopicName != "arc/plant1/consumer/arc-consumer/availability" ||
+		string(msgs[0].Payload) != "online" || !msgs[0].Retain {
+		t.Fatalf("unexpected availability %s %s", msgs[0].TopicName, msgs[0].Payload)
+	}
+	b.mu.Lock()
+	will := b.connect
+	b.mu.Unlock()
+	if !will.WillFlag || !will.WillRetain || string(will.WillMessage) != "offline" {
+		t.Fatalf("unexpected will %+v", will)
+	}
+
+	// 特征、状态，间隔内第二个特征不更新状态
+	for i := int64(0); i < 2; i++ {
+		if err := s.WriteFeature(&feature.Feature{ID: 1, SID: "94C96000C248", Timestamp: i, RMS: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeArc, ID: 1, SID: "94C96000C248", Timestamp: 5}); err != nil {
+		t.Fatal(err)
+	}
+	msgs = b.wait(t, 6)
+	topics := []string{}
+	for _, m := range msgs[1:] {
+		topics = append(topics, m.TopicName)
+	}
+	want := "arc/plant1/94C96000C248/feature,arc/plant1/94C96000C248/status,arc/plant1/94C96000C248/feature," +
+		"arc/plant1/94C96000C248/event,arc/plant1/94C96000C248/status"
+	if strings.Join(topics, ",") != want {
+		t.Fatalf("unexpected topics %v", topics)
+	}
+	st := &Status{}
+	if err := json.Unmarshal(msgs[5].Payload, st); err != nil {
+		t.Fatal(err)
+	}
+	if !msgs[5].Retain || st.LastEvent != 5 || st.Timestamp != 1 || st.RMS != 10 {
+		t.Fatalf("unexpected status %s", msgs[5].Payload)
+	}
+	if h := s.Health(); !h.Healthy {
+		t.Fatalf("unexpected health %+v", h)
+	}
+
+	// 关闭时发布离线状态
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msgs = b.wait(t, 7)
+	if string(msgs[6].Payload) != "offline" || !msgs[6].Retain {
+		t.Fatalf("unexpected availability %s", msgs[6].Payload)
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeArc}); err != errClosed {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+}
+
+func TestTopic(t *testing.T) {
+	c := &Config{Cluster: "c", ClientID: "x"}
+	if got := c.topic("arc/{cluster}/{client_id}/{sid}/{id}/{type}", "S", 7, "arc"); got != "arc/c/x/S/7/arc" {
+		t.Fatalf("unexpected topic %s", got)
+	}
+	if got := c.topic("", "S", 7, "arc"); got != "" {
+		t.Fatalf("unexpected topic %s", got)
+	}
+}
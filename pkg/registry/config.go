@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyEnable       = "registry.enable"
+	keyPolicy       = "registry.policy"
+	keyAllow        = "registry.allow"
+	keyDeny         = "registry.deny"
+	keyStore        = "registry.store"
+	keyFile         = "registry.file"
+	keySyncInterval = "registry.sync_interval"
+)
+
+const (
+	// PolicyAuto 未知传感器自动登记为允许
+	PolicyAuto = "auto"
+	// PolicyPending 未知传感器登记为待审批，审批前丢弃数据
+	PolicyPending = "pending"
+	// PolicyDeny 只接收允许列表和已审批的传感器
+	PolicyDeny = "deny"
+)
+
+const (
+	// StoreFile 登记信息保存到本地文件
+	StoreFile = "file"
+	// StoreGossip 登记信息保存到集群一致性键值，集群内共享
+	StoreGossip = "gossip"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Enable:       false,
+	Policy:       PolicyAuto,
+	Allow:        []string{},
+	Deny:         []string{},
+	Store:        StoreFile,
+	File:         "./data/registry.json",
+	SyncInterval: 5000,
+}
+
+// Config - 传感器登记配置
+type Config struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Policy 不在允许、拒绝列表中且未登记的传感器处理策略 auto/pending/deny
+	Policy string `toml:"policy" json:"policy"`
+	// Allow 允许列表，支持编号、通配符(94C96*)和范围(94C960000000-94C96000FFFF)
+	Allow []string `toml:"allow" json:"allow"`
+	// Deny 拒绝列表，格式同 Allow，优先于登记信息和允许列表
+	Deny []string `toml:"deny" json:"deny"`
+	// Store 登记信息保存方式 file/gossip
+	Store string `toml:"store" json:"store"`
+	// File 登记信息文件，Store 为 file 时使用
+	File string `toml:"file" json:"file"`
+	// SyncInterval 从集群一致性键值同步登记信息间隔(毫秒)，Store 为 gossip 时使用
+	SyncInterval int `toml:"sync_interval" json:"sync_interval"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyEnable, defaultConfig.Enable)
+	viper.SetDefault(keyPolicy, defaultConfig.Policy)
+	viper.SetDefault(keyAllow, defaultConfig.Allow)
+	viper.SetDefault(keyDeny, defaultConfig.Deny)
+	viper.SetDefault(keyStore, defaultConfig.Store)
+	viper.SetDefault(keyFile, defaultConfig.File)
+	viper.SetDefault(keySyncInterval, defaultConfig.SyncInterval)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		Enable:       viper.GetBool(keyEnable),
+		Policy:       viper.GetString(keyPolicy),
+		Allow:        viper.GetStringSlice(keyAllow),
+		Deny:         viper.GetStringSlice(keyDeny),
+		Store:        viper.GetString(keyStore),
+		File:         viper.GetString(keyFile),
+		SyncInterval: viper.GetInt(keySyncInterval),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	switch c.Policy {
+	case PolicyAuto, PolicyPending, PolicyDeny:
+	default:
+		return fmt.Errorf("%s must be one of %s/%s/%s, got %q", keyPolicy, PolicyAuto, PolicyPending, PolicyDeny, c.Policy)
+	}
+	if _, err := ParseRules(c.Allow); err != nil {
+		return fmt.Errorf("%s: %w", keyAllow, err)
+	}
+	if _, err := ParseRules(c.Deny); err != nil {
+		return fmt.Errorf("%s: %w", keyDeny, err)
+	}
+	switch c.Store {
+	case StoreFile:
+		if c.File == "" {
+			return fmt.Errorf("%s must be set when %s is %s", keyFile, keyStore, StoreFile)
+		}
+	case StoreGossip:
+		if c.SyncInterval <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", keySyncInterval, c.SyncInterval)
+		}
+	default:
+		return fmt.Errorf("%s must be %s or %s, got %q", keyStore, StoreFile, StoreGossip, c.Store)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"github.com/kiga-hub/arc/logging"
+)
+
+// Option is a function that will set up option.
+type Option func(opts *Registry)
+
+func loadOptions(options ...Option) *Registry {
+	opts := &Registry{
+		entries: map[string]*Entry{},
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	if opts.logger == nil {
+		opts.logger = new(logging.NoopLogger)
+	}
+	if opts.config == nil {
+		opts.config = GetConfig()
+	}
+	if opts.store == nil {
+		opts.store = NewFileStore(opts.config.File)
+	}
+	return opts
+}
+
+// WithConfig -
+func WithConfig(c *Config) Option {
+	return func(opts *Registry) {
+		opts.config = c
+	}
+}
+
+// WithStore - 登记信息持久化，默认保存到配置的本地文件
+func WithStore(s Store) Option {
+	return func(opts *Registry) {
+		opts.store = s
+	}
+}
+
+// WithLogger -
+func WithLogger(logger logging.ILogger) Option {
+	return func(opts *Registry) {
+		opts.logger = logger
+	}
+}
+
+// WithListener - 新登记传感器回调，如待审批通知
+func WithListener(l Listener) Option {
+	return func(opts *Registry) {
+		opts.listener = l
+	}
+}
@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// Status - 传感器登记状态
+type Status string
+
+const (
+	// StatusAllowed 接收数据
+	StatusAllowed Status = "allowed"
+	// StatusDenied 丢弃数据
+	StatusDenied Status = "denied"
+	// StatusPending 待审批，审批前丢弃数据
+	StatusPending Status = "pending"
+)
+
+const (
+	// SourceAuto 首次收到数据时按策略登记
+	SourceAuto = "auto"
+	// SourceAPI 通过接口审批或拒绝
+	SourceAPI = "api"
+)
+
+var (
+	// ErrInvalidSID 传感器编号格式错误
+	ErrInvalidSID = errors.New("invalid sensor id")
+	// ErrNotFound 传感器未登记
+	ErrNotFound = errors.New("sensor not registered")
+	// ErrDenyRule 传感器在配置的拒绝列表中，不能通过接口允许
+	ErrDenyRule = errors.New("sensor matches deny list")
+)
+
+// Entry - 传感器登记信息
+type Entry struct {
+	SID       string `json:"sid"`
+	Status    Status `json:"status"`
+	Source    string `json:"source"`               // auto/api
+	FirstSeen int64  `json:"first_seen,omitempty"` // 首次收到数据时间(毫秒)
+	Updated   int64  `json:"updated"`              // 状态修改时间(毫秒)
+	Operator  string `json:"operator,omitempty"`   // 审批人
+}
+
+// Listener - 传感器按策略新登记时回调
+type Listener func(e Entry)
+
+// Registry - 传感器登记，按拒绝列表、登记信息、允许列表、策略依次决定是否接收数据
+type Registry struct {
+	mu       sync.RWMutex
+	config   *Config
+	allow    Rules
+	deny     Rules
+	entries  map[string]*Entry
+	store    Store
+	logger   logging.ILogger
+	listener Listener
+}
+
+// New - 创建传感器登记，从持久化读取登记信息
+func New(opts ...Option) (*Registry, error) {
+	r := loadOptions(opts...)
+	var err error
+	if r.allow, err = ParseRules(r.config.Allow); err != nil {
+		return nil, err
+	}
+	if r.deny, err = ParseRules(r.config.Deny); err != nil {
+		return nil, err
+	}
+	if err = r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ParseSID - 解析传感器编号，返回数字编号和12位大写十六进制字符串编号
+func ParseSID(s string) (uint64, string, error) {
+	id, err := strconv.ParseUint(strings.TrimSpace(s), 16, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %q", ErrInvalidSID, s)
+	}
+	return id, fmt.Sprintf("%012X", id), nil
+}
+
+// Check - 判断传感器是否接收数据，未知传感器按策略登记
+// @param id uint64 传感器编号
+// @param sid string 传感器字符串编号
+// @return Status 登记状态，只有 StatusAllowed 接收数据
+func (r *Registry) Check(id uint64, sid string) Status {
+	r.mu.RLock()
+	status, known := r.decide(id, sid)
+	r.mu.RUnlock()
+	if known {
+		return status
+	}
+
+	switch r.config.Policy {
+	case PolicyAuto:
+		status = StatusAllowed
+	case PolicyPending:
+		status = StatusPending
+	default:
+		// 拒绝策略不登记，避免未知设备占满登记信息
+		return StatusDenied
+	}
+
+	now := time.Now().UnixMilli()
+	r.mu.Lock()
+	if s, ok := r.decide(id, sid); ok {
+		r.mu.Unlock()
+		return s
+	}
+	e := &Entry{SID: sid, Status: status, Source: SourceAuto, FirstSeen: now, Updated: now}
+	r.entries[sid] = e
+	r.mu.Unlock()
+
+	r.logger.Infow("sensor registered", "sensor", sid, "status", status)
+	if err := r.store.Put(e); err != nil {
+		r.logger.Errorw("save sensor registry", "sensor", sid, "err", err)
+	}
+	if r.listener != nil {
+		r.listener(*e)
+	}
+	return status
+}
+
+// decide 按拒绝列表、登记信息、允许列表判断，调用方持有锁
+func (r *Registry) decide(id uint64, sid string) (Status, bool) {
+	if r.deny.Match(id, sid) {
+		return StatusDenied, true
+	}
+	if e, ok := r.entries[sid]; ok {
+		return e.Status, true
+	}
+	if r.allow.Match(id, sid) {
+		return StatusAllowed, true
+	}
+	return "", false
+}
+
+// Approve - 允许传感器接收数据
+func (r *Registry) Approve(sid, operator string) (Entry, error) {
+	return r.set(sid, StatusAllowed, operator)
+}
+
+// Deny - 拒绝传感器数据
+func (r *Registry) Deny(sid, operator string) (Entry, error) {
+	return r.set(sid, StatusDenied, operator)
+}
+
+// set 通过接口修改登记状态
+func (r *Registry) set(s string, status Status, operator string) (Entry, error) {
+	id, sid, err := ParseSID(s)
+	if err != nil {
+		return Entry{}, err
+	}
+	if status == StatusAllowed && r.deny.Match(id, sid) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrDenyRule, sid)
+	}
+	now := time.Now().UnixMilli()
+	r.mu.Lock()
+	e := &Entry{SID: sid, Status: status, Source: SourceAPI, Updated: now, Operator: operator}
+	if old, ok := r.entries[sid]; ok {
+		e.FirstSeen = old.FirstSeen
+	}
+	r.entries[sid] = e
+	r.mu.Unlock()
+	return *e, r.store.Put(e)
+}
+
+// Remove - 删除登记信息，再次收到数据时重新按策略登记
+func (r *Registry) Remove(s string) error {
+	_, sid, err := ParseSID(s)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	_, ok := r.entries[sid]
+	delete(r.entries, sid)
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, sid)
+	}
+	return r.store.Delete(sid)
+}
+
+// Entries - 登记信息，按字符串编号排序
+// @param status Status 状态过滤，为空不过滤
+// @return []Entry 登记信息
+func (r *Registry) Entries(status Status) []Entry {
+	r.mu.RLock()
+	entries := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if status == "" || e.Status == status {
+			entries = append(entries, *e)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SID < entries[j].SID
+	})
+	return entries
+}
+
+// Config - 当前配置
+func (r *Registry) Config() Config {
+	return *r.config
+}
+
+// Start - 保存到集群一致性键值时定时同步其他arc-consumer的修改
+func (r *Registry) Start(ctx context.Context) {
+	if r.config.Store != StoreGossip {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(r.config.SyncInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.load(); err != nil {
+				r.logger.Errorw("sync sensor registry", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// load 从持久化读取登记信息，替换内存中的登记信息
+func (r *Registry) load() error {
+	list, err := r.store.Load()
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]*Entry, len(list))
+	for _, e := range list {
+		entries[e.SID] = e
+	}
+	r.mu.Lock()
+	r.entries = entries
+	r.mu.Unlock()
+	return nil
+}
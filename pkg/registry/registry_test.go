@@ -0,0 +1,143 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestRule(t *testing.T) {
+	cases := []struct {
+		rule  string
+		id    uint64
+		match bool
+	}{
+		{"94C96000C248", 0x94C96000C248, true},
+		{"94c96000c248", 0x94C96000C249, false},
+		{"94C96*", 0x94C96000C249, true},
+		{"94C96*", 0x94C97000C249, false},
+		{"94C960000000-94C96000FFFF", 0x94C96000C248, true},
+		{"94C960000000-94C96000FFFF", 0x94C960010000, false},
+	}
+	for _, c := range cases {
+		r, err := parseRule(c.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.match(c.id, fmt.Sprintf("%012X", c.id)); got != c.match {
+			t.Fatalf("%s match %012X: got %v", c.rule, c.id, got)
+		}
+	}
+	for _, bad := range []string{"", "XYZ", "2-1", "94C96["} {
+		if _, err := parseRule(bad); err == nil {
+			t.Fatalf("rule %q accepted", bad)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cfg := &Config{
+		Enable: true,
+		Policy: PolicyPending,
+		Allow:  []string{"000000000001"},
+		Deny:   []string{"0000000000F*"},
+		Store:  StoreFile,
+		File:   filepath.Join(t.TempDir(), "registry.json"),
+	}
+	var registered []Entry
+	r, err := New(WithConfig(cfg), WithListener(func(e Entry) {
+		registered = append(registered, e)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := r.Check(0x1, "000000000001"); s != StatusAllowed {
+		t.Fatalf("allow list: %s", s)
+	}
+	if s := r.Check(0xF1, "0000000000F1"); s != StatusDenied {
+		t.Fatalf("deny list: %s", s)
+	}
+	if _, err := r.Approve("0000000000F1", "admin"); !errors.Is(err, ErrDenyRule) {
+		t.Fatalf("approve deny list: %v", err)
+	}
+
+	// 未知传感器待审批，审批后接收
+	if s := r.Check(0x2, "000000000002"); s != StatusPending {
+		t.Fatalf("unknown sensor: %s", s)
+	}
+	if len(registered) != 1 || registered[0].SID != "000000000002" {
+		t.Fatalf("unexpected registered %v", registered)
+	}
+	e, err := r.Approve("2", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.SID != "000000000002" || e.Operator != "admin" || e.FirstSeen == 0 {
+		t.Fatalf("unexpected entry %v", e)
+	}
+	if s := r.Check(0x2, "000000000002"); s != StatusAllowed {
+		t.Fatalf("approved sensor: %s", s)
+	}
+	if _, err := r.Deny("000000000003", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新加载文件
+	r, err = New(WithConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := r.Check(0x2, "000000000002"); s != StatusAllowed {
+		t.Fatalf("reloaded sensor: %s", s)
+	}
+	if entries := r.Entries(StatusDenied); len(entries) != 1 || entries[0].SID != "000000000003" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if err := r.Remove("000000000003"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove("000000000003"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("remove missing: %v", err)
+	}
+	if _, err := r.Approve("not-a-sid", "admin"); !errors.Is(err, ErrInvalidSID) {
+		t.Fatalf("invalid sid: %v", err)
+	}
+}
+
+// memKV 内存键值
+type memKV map[string]string
+
+func (m memKV) Add(k, v string) error { m[k] = v; return nil }
+
+func (m memKV) Delete(k string) error { delete(m, k); return nil }
+
+func (m memKV) GetAll() (map[string]string, error) { return m, nil }
+
+func TestGossipStore(t *testing.T) {
+	kv := memKV{"000000000002": "cluster"}
+	cfg := &Config{Enable: true, Policy: PolicyAuto, Store: StoreGossip, SyncInterval: 1000}
+	a, err := New(WithConfig(cfg), WithStore(NewGossipStore(kv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Check(0x2, "000000000002"); s != StatusAllowed {
+		t.Fatalf("auto registered: %s", s)
+	}
+	if _, err := a.Deny("000000000001", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他arc-consumer读取相同的登记信息
+	b, err := New(WithConfig(cfg), WithStore(NewGossipStore(kv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := b.Entries(""); len(entries) != 2 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if s := b.Check(0x1, "000000000001"); s != StatusDenied {
+		t.Fatalf("shared denied: %s", s)
+	}
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// rule 传感器编号匹配规则
+type rule struct {
+	text    string
+	pattern string // 通配符，为空时按范围匹配
+	from    uint64
+	to      uint64
+}
+
+// parseRule 解析规则：编号(94C96000C248)、通配符(94C96*)或闭区间范围(94C960000000-94C96000FFFF)
+func parseRule(text string) (*rule, error) {
+	s := strings.ToUpper(strings.TrimSpace(text))
+	if s == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+	if strings.ContainsAny(s, "*?[") {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", text, err)
+		}
+		return &rule{text: s, pattern: s}, nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	from, err := strconv.ParseUint(strings.TrimSpace(lo), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensor id %q", text)
+	}
+	to, err := strconv.ParseUint(strings.TrimSpace(hi), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensor id %q", text)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid range %q", text)
+	}
+	return &rule{text: s, from: from, to: to}, nil
+}
+
+// Rules - 传感器编号匹配规则列表
+type Rules []*rule
+
+// ParseRules - 解析规则列表，规则为编号(94C96000C248)、通配符(94C96*)或闭区间范围(94C960000000-94C96000FFFF)
+// @param texts []string 规则
+// @return Rules 规则列表
+// @return error 格式错误
+func ParseRules(texts []string) (Rules, error) {
+	rules := make(Rules, 0, len(texts))
+	for _, text := range texts {
+		r, err := parseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// match 传感器是否匹配规则
+func (r *rule) match(id uint64, sid string) bool {
+	if r.pattern != "" {
+		ok, _ := path.Match(r.pattern, sid)
+		return ok
+	}
+	return id >= r.from && id <= r.to
+}
+
+// Match - 传感器是否匹配任一规则
+func (rs Rules) Match(id uint64, sid string) bool {
+	for _, r := range rs {
+		if r.match(id, sid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// gossipKeyPrefix 集群一致性键值中登记信息的键前缀，与传感器编号上报的键区分
+const gossipKeyPrefix = "registry/"
+
+// Store - 登记信息持久化
+type Store interface {
+	Load() ([]*Entry, error)
+	Put(*Entry) error
+	Delete(sid string) error
+}
+
+// fileStore 登记信息保存到本地JSON文件，每次修改整体重写
+type fileStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*Entry
+}
+
+// NewFileStore - 本地文件保存登记信息
+// @param path string 文件路径
+// @return Store 持久化接口
+func NewFileStore(path string) Store {
+	return &fileStore{path: path, entries: map[string]*Entry{}}
+}
+
+// Load - 读取文件，文件不存在时为空
+func (s *fileStore) Load() ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	s.entries = map[string]*Entry{}
+	for _, e := range entries {
+		s.entries[e.SID] = e
+	}
+	return entries, nil
+}
+
+// Put - 保存登记信息
+func (s *fileStore) Put(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[e.SID] = e
+	return s.save()
+}
+
+// Delete - 删除登记信息
+func (s *fileStore) Delete(sid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, sid)
+	return s.save()
+}
+
+// save 写入临时文件后替换，避免写入中断损坏文件
+func (s *fileStore) save() error {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SID < entries[j].SID
+	})
+	raw, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// KV - 集群一致性键值，*component.GossipKVCacheComponent 实现该接口
+type KV interface {
+	Add(key, val string) error
+	Delete(key string) error
+	GetAll() (map[string]string, error)
+}
+
+// gossipStore 登记信息保存到集群一致性键值，集群内所有arc-consumer共享
+type gossipStore struct {
+	kv KV
+}
+
+// NewGossipStore - 集群一致性键值保存登记信息
+// @param kv KV 集群一致性键值
+// @return Store 持久化接口
+func NewGossipStore(kv KV) Store {
+	return &gossipStore{kv: kv}
+}
+
+// Load - 读取所有登记信息
+func (s *gossipStore) Load() ([]*Entry, error) {
+	all, err := s.kv.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for k, v := range all {
+		if !strings.HasPrefix(k, gossipKeyPrefix) {
+			continue
+		}
+		e := &Entry{}
+		if err := json.Unmarshal([]byte(v), e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Put - 保存登记信息，广播到集群
+func (s *gossipStore) Put(e *Entry) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.kv.Add(gossipKeyPrefix+e.SID, string(raw))
+}
+
+// Delete - 删除登记信息，广播到集群
+func (s *gossipStore) Delete(sid string) error {
+	return s.kv.Delete(gossipKeyPrefix + sid)
+}
@@ -0,0 +1,48 @@
+package reload
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyWatch    = "reload.watch"
+	keyDebounce = "reload.debounce"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Watch:    true,
+	Debounce: 500,
+}
+
+// Config - 配置热更新
+type Config struct {
+	// Watch 监听配置文件修改后自动重新加载
+	Watch bool `toml:"watch" json:"watch"`
+	// Debounce 文件连续修改时等待多久后加载(毫秒)
+	Debounce int `toml:"debounce" json:"debounce"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyWatch, defaultConfig.Watch)
+	viper.SetDefault(keyDebounce, defaultConfig.Debounce)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		Watch:    viper.GetBool(keyWatch),
+		Debounce: viper.GetInt(keyDebounce),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.Debounce < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", keyDebounce, c.Debounce)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package reload
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+)
+
+// 触发来源
+const (
+	// SourceFile 配置文件修改
+	SourceFile = "file"
+	// SourceNacos nacos动态配置修改
+	SourceNacos = "nacos"
+	// SourceAPI 接口调用
+	SourceAPI = "api"
+)
+
+var (
+	// ErrNoLoader 未设置配置加载
+	ErrNoLoader = errors.New("no config loader")
+	// ErrUnchanged 配置内容未修改，Loader 返回时跳过本次热更新
+	ErrUnchanged = errors.New("config unchanged")
+)
+
+// Change - 已检查并准备好的配置修改
+type Change struct {
+	// Apply 应用修改，不应失败
+	Apply func()
+	// Discard 放弃修改，释放准备时创建的资源，可为nil
+	Discard func()
+}
+
+// Step - 可热更新的配置项
+type Step struct {
+	Name string
+	// Prepare 读取并检查配置，准备需要的资源，配置未修改时返回nil
+	Prepare func() (*Change, error)
+}
+
+// Loader - 加载新配置，返回恢复原配置的函数
+type Loader func() (restore func(), err error)
+
+// Result - 配置热更新结果
+type Result struct {
+	Source  string   `json:"source"`
+	Time    int64    `json:"time"`              // 开始时间(毫秒)
+	Applied []string `json:"applied"`           // 已应用修改的配置项
+	Restart []string `json:"restart,omitempty"` // 已修改但需重启生效的配置
+	Error   string   `json:"error,omitempty"`   // 失败原因，失败时全部配置保持不变
+}
+
+// Reloader - 配置热更新，全部配置项检查并准备通过后才依次应用，任一失败则恢复原配置
+type Reloader struct {
+	mu      sync.Mutex
+	steps   []Step
+	restart func() []string
+	logger  logging.ILogger
+	last    *Result
+}
+
+// New - 创建配置热更新
+// @param logger logging.ILogger 日志
+// @param restart func() []string 已修改但需重启生效的配置，可为nil
+// @param steps Step 可热更新的配置项，按顺序准备和应用
+// @return *Reloader 配置热更新
+func New(logger logging.ILogger, restart func() []string, steps ...Step) *Reloader {
+	if logger == nil {
+		logger = new(logging.NoopLogger)
+	}
+	return &Reloader{
+		steps:   steps,
+		restart: restart,
+		logger:  logger,
+	}
+}
+
+// Reload - 加载并应用新配置，同一时间只执行一次
+// @param source string 触发来源
+// @param load Loader 加载新配置
+// @return *Result 热更新结果，配置内容未修改时为nil
+func (r *Reloader) Reload(source string, load Loader) *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := &Result{Source: source, Time: time.Now().UnixMilli(), Applied: []string{}}
+	err := r.reload(load, result)
+	if errors.Is(err, ErrUnchanged) {
+		return nil
+	}
+	if err != nil {
+		result.Applied = []string{}
+		result.Error = err.Error()
+		r.logger.Errorw("config reload failed, keep current", "source", source, "err", err)
+	} else {
+		r.logger.Infow("config reloaded", "source", source, "applied", result.Applied, "restart", result.Restart)
+	}
+	r.last = result
+	return result
+}
+
+// reload 加载、准备全部配置项，全部通过后应用，调用方持有锁
+func (r *Reloader) reload(load Loader, result *Result) error {
+	if load == nil {
+		return ErrNoLoader
+	}
+	restore, err := load()
+	if err != nil {
+		return err
+	}
+
+	type prepared struct {
+		name   string
+		change *Change
+	}
+	var changes []prepared
+	for _, step := range r.steps {
+		change, err := step.Prepare()
+		if err == nil {
+			if change != nil {
+				changes = append(changes, prepared{name: step.Name, change: change})
+			}
+			continue
+		}
+		for i := len(changes) - 1; i >= 0; i-- {
+			if changes[i].change.Discard != nil {
+				changes[i].change.Discard()
+			}
+		}
+		if restore != nil {
+			restore()
+		}
+		return fmt.Errorf("%s: %w", step.Name, err)
+	}
+
+	for _, p := range changes {
+		p.change.Apply()
+		result.Applied = append(result.Applied, p.name)
+	}
+	if r.restart != nil {
+		result.Restart = r.restart()
+	}
+	return nil
+}
+
+// Last - 最近一次热更新结果，未执行过为nil
+func (r *Reloader) Last() *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
@@ -0,0 +1,89 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	var log []string
+	step := func(name string, changed bool, err error) Step {
+		return Step{Name: name, Prepare: func() (*Change, error) {
+			if err != nil || !changed {
+				return nil, err
+			}
+			return &Change{
+				Apply:   func() { log = append(log, "apply "+name) },
+				Discard: func() { log = append(log, "discard "+name) },
+			}, nil
+		}}
+	}
+	restored := false
+	load := func() (func(), error) {
+		return func() { restored = true }, nil
+	}
+
+	// 全部准备通过后按顺序应用，未修改的不应用
+	r := New(nil, func() []string { return []string{"service.port"} },
+		step("simulate", true, nil), step("grpc", false, nil), step("sink", true, nil))
+	result := r.Reload(SourceAPI, load)
+	if result.Error != "" || !reflect.DeepEqual(result.Applied, []string{"simulate", "sink"}) ||
+		!reflect.DeepEqual(result.Restart, []string{"service.port"}) {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !reflect.DeepEqual(log, []string{"apply simulate", "apply sink"}) || restored {
+		t.Fatalf("unexpected apply %v %v", log, restored)
+	}
+
+	// 任一失败放弃已准备的修改并恢复原配置
+	log = nil
+	r = New(nil, nil, step("simulate", true, nil), step("sink", true, nil), step("feature", false, errors.New("invalid")))
+	result = r.Reload(SourceFile, load)
+	if result.Error != "feature: invalid" || len(result.Applied) != 0 || r.Last() != result {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !reflect.DeepEqual(log, []string{"discard sink", "discard simulate"}) || !restored {
+		t.Fatalf("unexpected rollback %v %v", log, restored)
+	}
+
+	// 加载失败不准备，内容未修改跳过
+	if result = r.Reload(SourceNacos, func() (func(), error) { return nil, errors.New("parse") }); result.Error != "parse" {
+		t.Fatalf("unexpected load error %+v", result)
+	}
+	if result = r.Reload(SourceFile, func() (func(), error) { return nil, ErrUnchanged }); result != nil || r.Last().Error != "parse" {
+		t.Fatalf("unexpected unchanged result %+v", result)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "arc-consumer.toml")
+	if err := os.WriteFile(path, []byte("a = 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	if err := Watch(ctx, path, 50*time.Millisecond, func() { changed <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("a = 2"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+	select {
+	case <-changed:
+		t.Fatal("changes not merged")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
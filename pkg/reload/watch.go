@@ -0,0 +1,53 @@
+package reload
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch - 监听配置文件所在目录，编辑器替换文件和Kubernetes ConfigMap更新软链接都能触发
+// 连续修改在 debounce 内合并为一次，fn 需自行判断内容是否变化
+// @param ctx context.Context 结束后停止监听
+// @param path string 配置文件路径
+// @param debounce time.Duration 合并间隔
+// @param fn func() 文件修改回调
+// @return error 创建监听失败
+func Watch(ctx context.Context, path string, debounce time.Duration, fn func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		w.Close()
+		return err
+	}
+	go func() {
+		defer w.Close()
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					timer.Reset(debounce)
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			case <-timer.C:
+				fn()
+			}
+		}
+	}()
+	return nil
+}
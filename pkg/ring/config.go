@@ -0,0 +1,68 @@
+package ring
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyEnable      = "ring.enable"
+	keyDuration    = "ring.duration"
+	keySensorBytes = "ring.sensor_bytes"
+	keyMaxBytes    = "ring.max_bytes"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Enable:      true,
+	Duration:    30000,
+	SensorBytes: 16 << 20,
+	MaxBytes:    256 << 20,
+}
+
+// Config - 最近数据缓存配置
+type Config struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Duration 每个传感器保留的时间长度(毫秒)，以最新Frame时间戳为准
+	Duration int `toml:"duration" json:"duration"`
+	// SensorBytes 每个传感器最大缓存字节数，超过后丢弃最早的数据包
+	SensorBytes int64 `toml:"sensor_bytes" json:"sensor_bytes"`
+	// MaxBytes 全部传感器最大缓存字节数，超过后清除最久未收到数据的传感器
+	MaxBytes int64 `toml:"max_bytes" json:"max_bytes"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyEnable, defaultConfig.Enable)
+	viper.SetDefault(keyDuration, defaultConfig.Duration)
+	viper.SetDefault(keySensorBytes, defaultConfig.SensorBytes)
+	viper.SetDefault(keyMaxBytes, defaultConfig.MaxBytes)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		Enable:      viper.GetBool(keyEnable),
+		Duration:    viper.GetInt(keyDuration),
+		SensorBytes: viper.GetInt64(keySensorBytes),
+		MaxBytes:    viper.GetInt64(keyMaxBytes),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyDuration, c.Duration)
+	}
+	if c.SensorBytes <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keySensorBytes, c.SensorBytes)
+	}
+	if c.MaxBytes < c.SensorBytes {
+		return fmt.Errorf("%s must not be less than %s, got %d < %d", keyMaxBytes, keySensorBytes, c.MaxBytes, c.SensorBytes)
+	}
+	return nil
+}
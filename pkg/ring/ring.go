@@ -0,0 +1,203 @@
+package ring
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType 最近数据缓存转发目标类型
+const SinkType = "ring"
+
+// SensorStatus - 传感器缓存状态
+type SensorStatus struct {
+	SID    string `json:"sid"`
+	Frames int    `json:"frames"`
+	Bytes  int64  `json:"bytes"`
+	From   int64  `json:"from"` // 最早Frame时间戳(毫秒)
+	To     int64  `json:"to"`   // 最新Frame时间戳(毫秒)
+}
+
+// sensor 单个传感器的数据包，按接收顺序存放
+type sensor struct {
+	sid    string
+	frames []*sink.Frame
+	bytes  int64
+}
+
+// first 最早的数据包时间戳
+func (s *sensor) first() int64 {
+	return s.frames[0].Timestamp
+}
+
+// last 最新的数据包时间戳
+func (s *sensor) last() int64 {
+	return s.frames[len(s.frames)-1].Timestamp
+}
+
+// Buffer - 每个传感器最近一段时间的数据包，作为转发目标接收数据包
+// 按时间长度和字节数限制单个传感器，超过全局上限时清除最久未收到数据的传感器
+type Buffer struct {
+	mu      sync.Mutex
+	config  *Config
+	sensors map[string]*list.Element // 值为 *sensor
+	lru     *list.List               // 最近写入的传感器在前
+	bytes   int64
+	evicted int64
+}
+
+// New - 创建最近数据缓存
+// @param c *Config 配置
+// @return *Buffer 最近数据缓存
+func New(c *Config) *Buffer {
+	return &Buffer{
+		config:  c,
+		sensors: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// size 数据包占用字节数
+func size(f *sink.Frame) int64 {
+	return int64(len(f.Data) + len(f.Arc))
+}
+
+// Write - 缓存数据包，不保留跟踪
+func (b *Buffer) Write(f *sink.Frame) error {
+	frame := &sink.Frame{
+		ID:        f.ID,
+		SID:       f.SID,
+		Timestamp: f.Timestamp,
+		STypes:    f.STypes,
+		Data:      f.Data,
+		Arc:       f.Arc,
+		Meta:      f.Meta,
+	}
+	n := size(frame)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	el, ok := b.sensors[f.SID]
+	if !ok {
+		el = b.lru.PushFront(&sensor{sid: f.SID})
+		b.sensors[f.SID] = el
+	} else {
+		b.lru.MoveToFront(el)
+	}
+	s := el.Value.(*sensor)
+	s.frames = append(s.frames, frame)
+	s.bytes += n
+	b.bytes += n
+
+	// 单个传感器按时间长度和字节数丢弃最早的数据包
+	oldest := s.last() - int64(b.config.Duration)
+	drop := 0
+	for drop < len(s.frames)-1 && (s.frames[drop].Timestamp < oldest || s.bytes > b.config.SensorBytes) {
+		d := size(s.frames[drop])
+		s.bytes -= d
+		b.bytes -= d
+		s.frames[drop] = nil
+		drop++
+	}
+	s.frames = s.frames[drop:]
+
+	// 超过全局上限时清除最久未收到数据的传感器
+	for b.bytes > b.config.MaxBytes && b.lru.Len() > 1 {
+		back := b.lru.Back()
+		idle := back.Value.(*sensor)
+		b.lru.Remove(back)
+		delete(b.sensors, idle.sid)
+		b.bytes -= idle.bytes
+		b.evicted++
+	}
+	return nil
+}
+
+// Flush -
+func (b *Buffer) Flush() error {
+	return nil
+}
+
+// Close - 清空缓存
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sensors = map[string]*list.Element{}
+	b.lru.Init()
+	b.bytes = 0
+	return nil
+}
+
+// Health - 缓存占用
+func (b *Buffer) Health() sink.Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sink.Health{
+		Healthy: true,
+		Message: fmt.Sprintf("%d sensors, %d/%d bytes, %d evicted", len(b.sensors), b.bytes, b.config.MaxBytes, b.evicted),
+	}
+}
+
+// Range - 读取传感器 [from, to] 毫秒时间范围内已缓存的数据包，按时间排序，可能只覆盖部分范围
+// @param sid string 传感器字符串编号
+// @param from int64 开始时间(毫秒)
+// @param to int64 结束时间(毫秒)
+// @return []*sink.Frame 数据包
+func (b *Buffer) Range(sid string, from, to int64) []*sink.Frame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rangeLocked(sid, from, to, false)
+}
+
+// Read - 缓存完整覆盖 [from, to] 时返回数据包，否则返回空，由其他转发目标读取
+func (b *Buffer) Read(sid string, from, to int64) ([]*sink.Frame, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rangeLocked(sid, from, to, true), nil
+}
+
+// rangeLocked 读取时间范围内的数据包，调用方持有锁
+func (b *Buffer) rangeLocked(sid string, from, to int64, covered bool) []*sink.Frame {
+	el, ok := b.sensors[sid]
+	if !ok || from > to {
+		return nil
+	}
+	s := el.Value.(*sensor)
+	if covered && s.first() > from {
+		return nil
+	}
+	var frames []*sink.Frame
+	for _, f := range s.frames {
+		if f.Timestamp >= from && f.Timestamp <= to {
+			frames = append(frames, f)
+		}
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Timestamp < frames[j].Timestamp
+	})
+	return frames
+}
+
+// Status - 各传感器缓存状态，按字符串编号排序
+func (b *Buffer) Status() []SensorStatus {
+	b.mu.Lock()
+	statuses := make([]SensorStatus, 0, len(b.sensors))
+	for _, el := range b.sensors {
+		s := el.Value.(*sensor)
+		statuses = append(statuses, SensorStatus{
+			SID:    s.sid,
+			Frames: len(s.frames),
+			Bytes:  s.bytes,
+			From:   s.first(),
+			To:     s.last(),
+		})
+	}
+	b.mu.Unlock()
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].SID < statuses[j].SID
+	})
+	return statuses
+}
@@ -0,0 +1,53 @@
+package ring
+
+import (
+	"testing"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+func frame(sid string, ts int64, n int) *sink.Frame {
+	return &sink.Frame{SID: sid, Timestamp: ts, Data: make([]byte, n)}
+}
+
+func TestBuffer(t *testing.T) {
+	b := New(&Config{Enable: true, Duration: 1000, SensorBytes: 300, MaxBytes: 500})
+
+	// 按时间长度丢弃最早的数据包
+	for ts := int64(0); ts <= 2000; ts += 500 {
+		if err := b.Write(frame("A", ts, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frames := b.Range("A", 0, 3000)
+	if len(frames) != 3 || frames[0].Timestamp != 1000 || frames[2].Timestamp != 2000 {
+		t.Fatalf("unexpected frames %v", frames)
+	}
+
+	// 缓存未覆盖起始时间时 Read 返回空，由其他转发目标读取
+	if frames, _ := b.Read("A", 500, 2000); frames != nil {
+		t.Fatalf("read uncovered range %v", frames)
+	}
+	if frames, _ := b.Read("A", 1200, 2000); len(frames) != 2 {
+		t.Fatalf("unexpected read %v", frames)
+	}
+
+	// 按单个传感器字节数丢弃
+	for i := 0; i < 4; i++ {
+		_ = b.Write(frame("B", 100+int64(i), 100))
+	}
+	if frames := b.Range("B", 0, 1000); len(frames) != 3 || frames[0].Timestamp != 101 {
+		t.Fatalf("unexpected frames %v", frames)
+	}
+
+	// 超过全局上限时清除最久未收到数据的传感器
+	_ = b.Write(frame("A", 2100, 10))
+	_ = b.Write(frame("C", 100, 200))
+	status := b.Status()
+	if len(status) != 2 || status[0].SID != "A" || status[1].SID != "C" {
+		t.Fatalf("unexpected status %v", status)
+	}
+	if status[0].Frames != 3 || status[0].From != 1500 || status[0].To != 2100 {
+		t.Fatalf("unexpected status %v", status[0])
+	}
+}
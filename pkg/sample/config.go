@@ -0,0 +1,64 @@
+package sample
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keySampleRate    = "sample.rate"
+	keyBitsPerSample = "sample.bits"
+	keyChannels      = "sample.channels"
+)
+
+// 配置默认值，SegmentArc 不携带采样参数，由配置约定
+var defaultConfig = Config{
+	SampleRate:    25600,
+	BitsPerSample: 16,
+	Channels:      1,
+}
+
+// Config - SegmentArc 采样格式，样本为大端有符号整数，多通道交错存放
+type Config struct {
+	SampleRate    int `toml:"rate" json:"rate"`
+	BitsPerSample int `toml:"bits" json:"bits"`
+	Channels      int `toml:"channels" json:"channels"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keySampleRate, defaultConfig.SampleRate)
+	viper.SetDefault(keyBitsPerSample, defaultConfig.BitsPerSample)
+	viper.SetDefault(keyChannels, defaultConfig.Channels)
+}
+
+// GetConfig - 获取当前配置
+func GetConfig() *Config {
+	return &Config{
+		SampleRate:    viper.GetInt(keySampleRate),
+		BitsPerSample: viper.GetInt(keyBitsPerSample),
+		Channels:      viper.GetInt(keyChannels),
+	}
+}
+
+// Validate - 检查采样格式
+func (c *Config) Validate() error {
+	if c.SampleRate <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keySampleRate, c.SampleRate)
+	}
+	switch c.BitsPerSample {
+	case 8, 16, 24, 32:
+	default:
+		return fmt.Errorf("%s must be 8, 16, 24 or 32, got %d", keyBitsPerSample, c.BitsPerSample)
+	}
+	if c.Channels <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", keyChannels, c.Channels)
+	}
+	return nil
+}
+
+// BytesPerSample - 单个样本字节数
+func (c *Config) BytesPerSample() int {
+	return c.BitsPerSample / 8
+}
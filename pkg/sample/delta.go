@@ -0,0 +1,88 @@
+package sample
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// DeltaEncode - 样本差分后zigzag变长编码，再做deflate熵编码
+// 格式: 原始字节数(uvarint) + deflate(差分varint序列) ，末尾不足一个样本的字节原样追加在差分序列之后
+// @param data []byte SegmentArc数据
+// @param bits int 样本位宽
+// @return []byte 编码数据
+func DeltaEncode(data []byte, bits int) ([]byte, error) {
+	size := bits / 8
+	if size <= 0 || size > 4 {
+		return nil, fmt.Errorf("invalid sample bits %d", bits)
+	}
+	samples := Decode(data, bits)
+
+	raw := make([]byte, 0, len(data))
+	tmp := make([]byte, binary.MaxVarintLen64)
+	var prev int32
+	for _, v := range samples {
+		n := binary.PutVarint(tmp, int64(v)-int64(prev))
+		raw = append(raw, tmp[:n]...)
+		prev = v
+	}
+	raw = append(raw, data[len(samples)*size:]...)
+
+	out := new(bytes.Buffer)
+	n := binary.PutUvarint(tmp, uint64(len(data)))
+	out.Write(tmp[:n])
+	w, err := flate.NewWriter(out, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// DeltaDecode - DeltaEncode 的逆过程
+// @param data []byte 编码数据
+// @param bits int 样本位宽
+// @return []byte SegmentArc数据
+func DeltaDecode(data []byte, bits int) ([]byte, error) {
+	size := bits / 8
+	if size <= 0 || size > 4 {
+		return nil, fmt.Errorf("invalid sample bits %d", bits)
+	}
+	total, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid delta header")
+	}
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data[n:])))
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(total) / size
+	samples := make([]int32, count)
+	r := bytes.NewReader(raw)
+	var prev int64
+	for i := 0; i < count; i++ {
+		d, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("delta sample %d: %v", i, err)
+		}
+		prev += d
+		samples[i] = int32(prev)
+	}
+	out, err := Encode(samples, bits)
+	if err != nil {
+		return nil, err
+	}
+	tail, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, tail...), nil
+}
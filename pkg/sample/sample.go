@@ -0,0 +1,47 @@
+package sample
+
+import (
+	"fmt"
+)
+
+// Decode - 按位宽解析大端有符号样本，末尾不足一个样本的字节被忽略
+// @param data []byte SegmentArc数据
+// @param bits int 样本位宽
+// @return []int32 样本
+func Decode(data []byte, bits int) []int32 {
+	size := bits / 8
+	if size <= 0 {
+		return nil
+	}
+	samples := make([]int32, len(data)/size)
+	for i := range samples {
+		b := data[i*size : (i+1)*size]
+		var v int32
+		for _, c := range b {
+			v = v<<8 | int32(c)
+		}
+		// 符号扩展
+		shift := 32 - uint(bits)
+		samples[i] = v << shift >> shift
+	}
+	return samples
+}
+
+// Encode - 按位宽将样本写为大端字节
+// @param samples []int32 样本
+// @param bits int 样本位宽
+// @return []byte SegmentArc数据
+func Encode(samples []int32, bits int) ([]byte, error) {
+	size := bits / 8
+	if size <= 0 || size > 4 {
+		return nil, fmt.Errorf("invalid sample bits %d", bits)
+	}
+	buf := make([]byte, len(samples)*size)
+	for i, v := range samples {
+		for j := size - 1; j >= 0; j-- {
+			buf[i*size+j] = byte(v)
+			v >>= 8
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,74 @@
+package sample
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	for _, bits := range []int{8, 16, 24, 32} {
+		max := int32(math.MaxInt32 >> (32 - bits))
+		samples := []int32{0, 1, -1, max, -max - 1, 100, -100}
+		data, err := Encode(samples, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := Decode(data, bits)
+		for i := range samples {
+			if got[i] != samples[i] {
+				t.Fatalf("bits %d sample %d: got %d, want %d", bits, i, got[i], samples[i])
+			}
+		}
+	}
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	samples := make([]int32, 1024)
+	for i := range samples {
+		samples[i] = int32(1000 * math.Sin(float64(i)/20))
+	}
+	data, err := Encode(samples, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 末尾不足一个样本的字节
+	data = append(data, 0x7F)
+
+	encoded, err := DeltaEncode(data, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) >= len(data) {
+		t.Fatalf("delta encoding did not compress: %d >= %d", len(encoded), len(data))
+	}
+	decoded, err := DeltaDecode(encoded, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("delta round trip mismatch")
+	}
+}
+
+func TestWAV(t *testing.T) {
+	c := &Config{SampleRate: 25600, BitsPerSample: 16, Channels: 2}
+	h := WAVHeader(c, 8)
+	if string(h[0:4]) != "RIFF" || string(h[8:12]) != "WAVE" || string(h[36:40]) != "data" {
+		t.Fatalf("invalid header %q", h)
+	}
+	if !bytes.Equal(h[22:24], []byte{2, 0}) || !bytes.Equal(h[32:36], []byte{4, 0, 16, 0}) {
+		t.Fatalf("invalid format %v", h[20:36])
+	}
+
+	pcm, err := WAVData([]byte{0x01, 0x02, 0xFF, 0xFE}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pcm, []byte{0x02, 0x01, 0xFE, 0xFF}) {
+		t.Fatalf("16 bit pcm %v", pcm)
+	}
+	if pcm, _ = WAVData([]byte{0x00, 0xFF}, 8); !bytes.Equal(pcm, []byte{0x80, 0x7F}) {
+		t.Fatalf("8 bit pcm %v", pcm)
+	}
+}
@@ -0,0 +1,56 @@
+package sample
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// WAVHeaderSize - PCM格式WAV文件头字节数
+const WAVHeaderSize = 44
+
+// WAVHeader - 生成PCM格式WAV文件头
+// @param c *Config 采样格式
+// @param dataSize uint32 样本数据字节数
+// @return []byte 文件头
+func WAVHeader(c *Config, dataSize uint32) []byte {
+	blockAlign := c.Channels * c.BytesPerSample()
+	h := make([]byte, WAVHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], 36+dataSize)
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(h[22:], uint16(c.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(c.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(c.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:], uint16(c.BitsPerSample))
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], dataSize)
+	return h
+}
+
+// WAVData - SegmentArc大端有符号样本转换为WAV小端PCM，8位样本转换为无符号
+// @param data []byte SegmentArc样本数据
+// @param bits int 样本位宽
+// @return []byte PCM数据
+// @return err 错误信息
+func WAVData(data []byte, bits int) ([]byte, error) {
+	size := bits / 8
+	if size < 1 || size > 4 || bits%8 != 0 {
+		return nil, fmt.Errorf("invalid sample bits %d", bits)
+	}
+	n := len(data) / size * size
+	pcm := make([]byte, n)
+	for i := 0; i < n; i += size {
+		if size == 1 {
+			pcm[i] = data[i] ^ 0x80
+			continue
+		}
+		for j := 0; j < size; j++ {
+			pcm[i+j] = data[i+size-1-j]
+		}
+	}
+	return pcm, nil
+}
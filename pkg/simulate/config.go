@@ -1,6 +1,10 @@
 package simulate
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
 
 const (
 	nettype           = "service.net_type"
@@ -62,3 +66,34 @@ func GetConfig() *Config {
 		ProxyTimealign: viper.GetBool(proxyTimealign),
 	}
 }
+
+// Validate - 检查配置，数据处理协程数需为2的幂
+func (c *Config) Validate() error {
+	if c.GoroutineCount <= 0 || (c.GoroutineCount&(c.GoroutineCount-1)) != 0 {
+		return fmt.Errorf("%s must be a power of two, got %d", goroutineCount, c.GoroutineCount)
+	}
+	return nil
+}
+
+// RestartKeys - 与运行中配置相比已修改但需重启生效的配置项
+// @param running *Config 运行中配置
+// @return []string 配置项
+func (c *Config) RestartKeys(running *Config) []string {
+	var keys []string
+	for _, f := range []struct {
+		key     string
+		changed bool
+	}{
+		{nettype, c.NetType != running.NetType},
+		{host, c.Host != running.Host},
+		{devicehost, c.DeviceHost != running.DeviceHost},
+		{port, c.Port != running.Port},
+		{keepalive, c.Keepalive != running.Keepalive},
+		{proxyTimealign, c.ProxyTimealign != running.ProxyTimealign},
+	} {
+		if f.changed {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,116 @@
+package simulate
+
+import (
+	"testing"
+
+	"github.com/kiga-hub/arc/protocols"
+)
+
+// encodeFrame 编码含数据段的数据包
+func encodeFrame(t *testing.T) []byte {
+	sa := protocols.NewDefaultSegmentArc()
+	sa.Data = make([]byte, 256)
+	for i := range sa.Data {
+		sa.Data[i] = byte(i)
+	}
+	g := protocols.NewDefaultDataGroup()
+	g.AppendSegment(sa)
+	f := protocols.NewDefaultFrame()
+	f.SetID(1)
+	f.Timestamp = 1683356400000
+	f.SetDataGroup(g)
+	buf := make([]byte, f.Size+9)
+	n, err := f.Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+func TestCheckCRC(t *testing.T) {
+	h, err := New(WithConfig(&Config{GoroutineCount: 1, EnableCRCCheck: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := h.(*Server)
+	sensor := &Sensor{id: 1, sid: "000000000001"}
+
+	data := encodeFrame(t)
+	frame := &protocols.Frame{}
+	if err := frame.Decode(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.checkCRC(frame, data, sensor); err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据段损坏仍可解包，校验和不一致
+	data[len(data)-10] ^= 0xFF
+	if err := frame.Decode(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.checkCRC(frame, data, sensor); err == nil {
+		t.Fatal("corrupted frame should fail crc check")
+	}
+
+	// 热更新关闭校验后立即生效
+	if err := cs.UpdateConfig(&Config{GoroutineCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.checkCRC(frame, data, sensor); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateConfigReshard(t *testing.T) {
+	running := &Config{GoroutineCount: 4, EnableCRCCheck: true}
+	var counts []int
+	h, err := New(WithConfig(running), WithShardListener(func(count int) {
+		counts = append(counts, count)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := h.(*Server)
+	sensor := &Sensor{id: 7, sid: "000000000007"}
+	for i := 0; i < 3; i++ {
+		cs.ToHandle(sensor, make([]byte, 100))
+	}
+
+	// 协程数热更新，排空管道后切换，恢复原状态
+	next := &Config{GoroutineCount: 2}
+	if keys := next.RestartKeys(running); len(keys) != 0 {
+		t.Fatalf("unexpected restart keys %v", keys)
+	}
+	if err := cs.UpdateConfig(next); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := cs.Sensor(sensor.sid); status.Errors != 3 || status.Shard != 1 {
+		t.Fatalf("frames not drained before reshard %+v", status)
+	}
+	if len(counts) != 1 || counts[0] != 2 || cs.Config() != next || cs.State() != StateRunning {
+		t.Fatalf("reshard not applied %v %v", counts, cs.State())
+	}
+	if shards := cs.Shards(); len(shards) != 0 {
+		t.Fatalf("old shards not closed %+v", shards)
+	}
+	cs.ToHandle(sensor, make([]byte, 100))
+	waitFor(t, func() bool {
+		status, _ := cs.Sensor(sensor.sid)
+		return status.Errors == 4
+	})
+	if shards := cs.Shards(); len(shards) != 1 || shards[0].Index != 1 {
+		t.Fatalf("unexpected shards %+v", shards)
+	}
+
+	// 暂停转发时管道无法排空，拒绝修改并保留当前配置
+	if err := cs.PauseForward(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.UpdateConfig(&Config{GoroutineCount: 8}); err == nil {
+		t.Fatal("goroutine count change should be rejected while forward paused")
+	}
+	if cs.Config() != next || cs.State() != StateForwardPaused {
+		t.Fatal("rejected config should keep current")
+	}
+}
@@ -2,18 +2,27 @@ package simulate
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kiga-hub/arc/protocols"
+	"github.com/kiga-hub/arc/utils"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
 )
 
 // Package - 处理包结构
 type Package struct {
-	Sensor *Sensor // 传感器
-	Data   []byte  // 数据
+	Sensor *Sensor          // 传感器
+	Data   []byte           // 数据
+	Span   opentracing.Span // 采样跟踪，未采样为nil
 }
 
 // decodePackage -
-func (cs *Server) decodePackage(frameBuff *protocols.Frame, data []byte, sensor *Sensor) (int64, error) {
+func (cs *Server) decodePackage(frameBuff *protocols.Frame, data []byte, sensor *Sensor) (*protocols.SegmentArc, error) {
 	// 从Frame包获取数据段(获取Frame包时间是根据时间计算的)
 	var err error
 	var sa *protocols.SegmentArc
@@ -22,23 +31,45 @@ func (cs *Server) decodePackage(frameBuff *protocols.Frame, data []byte, sensor
 		case protocols.STypeArc:
 			sa, err = frameBuff.DataGroup.GetArcSegment()
 			if err != nil {
-				return 0, err
+				return nil, err
 			}
 		}
 	}
 
 	// Frame包内必须有数据段
 	if sa == nil {
-		return 0, fmt.Errorf("%012X package not find arc data", sensor.id)
+		return nil, fmt.Errorf("%012X package not find arc data", sensor.id)
 	}
+	return sa, nil
+}
 
-	// 根据数据，计算数据时间（微秒）
-	dataSize := int64(len(sa.Data))
-	return dataSize, nil
+// checkCRC 开启CRC校验时检查数据包校验和，每次读取当前配置，热更新后立即生效
+func (cs *Server) checkCRC(frameBuff *protocols.Frame, data []byte, sensor *Sensor) error {
+	if !cs.getConfig().EnableCRCCheck {
+		return nil
+	}
+	if crc := utils.CheckSum(data[protocols.DefaultHeadLength : len(data)-3]); crc != frameBuff.Crc {
+		return fmt.Errorf("%012X bad crc check sum %v != %v", sensor.id, crc, frameBuff.Crc)
+	}
+	return nil
+}
+
+// shard 传感器对应的数据处理协程
+func (cs *Server) shard(id uint64) uint64 {
+	return id & uint64(cs.getConfig().GoroutineCount-1)
 }
 
 // 从管道获取package结构，包处理
 func (cs *Server) handlePackage(frameBuff *protocols.Frame, pkg *Package) {
+	start := time.Now()
+	shard := metrics.Shard(cs.shard(pkg.Sensor.id))
+	stats := cs.stats(pkg.Sensor, start)
+	var err error
+	defer func() {
+		metrics.HandleDuration.WithLabelValues(shard).Observe(time.Since(start).Seconds())
+		tracing.Finish(pkg.Span, err)
+	}()
+
 	// 集群同步上报传感器编号
 	if cs.kvCache != nil {
 		if err := cs.kvCache.Sync(pkg.Sensor.id); err != nil {
@@ -47,24 +78,73 @@ func (cs *Server) handlePackage(frameBuff *protocols.Frame, pkg *Package) {
 	}
 
 	// 解包
-	if err := frameBuff.Decode(pkg.Data); err != nil {
+	span := tracing.StartChild(pkg.Span, "frame.decode")
+	if err = frameBuff.Decode(pkg.Data); err != nil {
+		tracing.Finish(span, err)
+		stats.errors.Inc()
+		metrics.FramesDropped.WithLabelValues(pkg.Sensor.sid, shard, "decode").Inc()
+		cs.logger.Errorw(err.Error(), "sensor", pkg.Sensor.sid)
+		return
+	}
+	if err = cs.checkCRC(frameBuff, pkg.Data, pkg.Sensor); err != nil {
+		tracing.Finish(span, err)
+		stats.errors.Inc()
+		metrics.FramesDropped.WithLabelValues(pkg.Sensor.sid, shard, "crc").Inc()
 		cs.logger.Errorw(err.Error(), "sensor", pkg.Sensor.sid)
 		return
 	}
 
 	// 时间对齐，统计检查
-	_, err := cs.decodePackage(frameBuff, pkg.Data, pkg.Sensor)
+	var sa *protocols.SegmentArc
+	sa, err = cs.decodePackage(frameBuff, pkg.Data, pkg.Sensor)
+	tracing.Finish(span, err)
 	if err != nil {
+		stats.errors.Inc()
+		metrics.FramesDropped.WithLabelValues(pkg.Sensor.sid, shard, "no_arc").Inc()
 		cs.logger.Errorw(err.Error())
 		return
 	}
+	metrics.FramesDecoded.WithLabelValues(pkg.Sensor.sid, shard).Inc()
+	if pkg.Span != nil {
+		pkg.Span.SetTag(tracing.TagTimestamp, frameBuff.Timestamp)
+	}
 
 	cs.tmap.Store(pkg.Sensor.id, frameBuff.Timestamp)
+	m := cs.meta.Get(pkg.Sensor.sid)
 
-	// 数据包gRPC转发
-	if cs.grpc != nil {
-		if err := cs.grpc.Write(pkg.Sensor.id, pkg.Sensor.sid, pkg.Data); err != nil {
+	// 数据包分发到各转发目标
+	if cs.sinks != nil {
+		frame := &sink.Frame{
+			ID:        pkg.Sensor.id,
+			SID:       pkg.Sensor.sid,
+			Timestamp: frameBuff.Timestamp,
+			STypes:    append([]byte(nil), frameBuff.DataGroup.STypes...),
+			Data:      pkg.Data,
+			Arc:       sa.Data,
+			Meta:      m,
+			Span:      pkg.Span,
+		}
+		if err := cs.sinks.Write(frame); err != nil {
 			cs.logger.Warnw(err.Error(), "sensor", pkg.Sensor.sid)
+		} else {
+			metrics.FramesForwarded.WithLabelValues(pkg.Sensor.sid, shard).Inc()
+		}
+	}
+
+	// 特征提取与电弧事件
+	if features := cs.extractor(); features != nil && cs.sinks != nil {
+		span := tracing.StartChild(pkg.Span, "feature.extract")
+		f, ev := features.Process(pkg.Sensor.id, pkg.Sensor.sid, frameBuff.Timestamp, sa.Data)
+		tracing.Finish(span, nil)
+		f.Meta = m
+		if err := cs.sinks.WriteFeature(f); err != nil {
+			cs.logger.Warnw(err.Error(), "sensor", pkg.Sensor.sid)
+		}
+		if ev != nil {
+			ev.Meta = m
+			if err := cs.sinks.WriteEvent(ev); err != nil {
+				cs.logger.Warnw(err.Error(), "sensor", pkg.Sensor.sid)
+			}
 		}
 	}
 }
@@ -72,7 +152,50 @@ func (cs *Server) handlePackage(frameBuff *protocols.Frame, pkg *Package) {
 // ToHandle - 放入管道之前包处理
 func (cs *Server) ToHandle(sensor *Sensor, data []byte) {
 	// 负载均衡创建goroutine
-	index := sensor.id & uint64(cs.config.GoroutineCount-1)
+	index := cs.shard(sensor.id)
+	shard := metrics.Shard(index)
+	metrics.FramesReceived.WithLabelValues(sensor.sid, shard).Inc()
+	metrics.BytesReceived.WithLabelValues(sensor.sid, shard).Add(float64(len(data)))
+	now := time.Now()
+	cs.stats(sensor, now).observe(len(data), now)
+
+	// 拒绝或待审批的传感器丢弃
+	if cs.registry != nil {
+		if status := cs.registry.Check(sensor.id, sensor.sid); status != registry.StatusAllowed {
+			metrics.FramesDropped.WithLabelValues(sensor.sid, shard, string(status)).Inc()
+			return
+		}
+	}
+
+	// 在线检测，超限丢弃的传感器仍在上报
+	if cs.liveness != nil {
+		cs.liveness.Seen(sensor.id, sensor.sid)
+	}
+
+	// 超过传感器或全局限流时按策略丢弃
+	if cs.limiter != nil {
+		if ok, reason := cs.limiter.Allow(sensor.id, sensor.sid, len(data)); !ok {
+			metrics.FramesDropped.WithLabelValues(sensor.sid, shard, reason).Inc()
+			return
+		}
+	}
+
+	// 暂停接收或排空时丢弃
+	state, ok := cs.pipe.admit()
+	if !ok {
+		metrics.FramesDropped.WithLabelValues(sensor.sid, shard, state.String()).Inc()
+		return
+	}
+	defer cs.pipe.admitted()
+	// 已入管道的数据包处理完之前协程数不会修改，按当前协程数重新计算
+	index = cs.shard(sensor.id)
+	shard = metrics.Shard(index)
+	depth := metrics.QueueDepth.WithLabelValues(shard)
+	span := cs.tracing.StartFrame(sensor.sid)
+	if span != nil {
+		span.SetTag(tracing.TagShard, index)
+	}
+
 	v, loaded := cs.frameChans.Load(index)
 	if !loaded {
 		v = make(chan *Package, 1024*100)
@@ -82,15 +205,24 @@ func (cs *Server) ToHandle(sensor *Sensor, data []byte) {
 
 		go func() {
 			handleFrameBuff := protocols.NewDefaultFrame()
-			for p := range v.(chan *Package) {
+			ch := v.(chan *Package)
+			for p := range ch {
+				cs.pipe.acquire()
+				depth.Set(float64(len(ch)))
 				cs.handlePackage(handleFrameBuff, p)
+				cs.pipe.release()
 			}
 		}()
 	}
 
 	// 数据包入管道
-	v.(chan *Package) <- &Package{
+	enqueue := tracing.StartChild(span, "frame.enqueue")
+	ch := v.(chan *Package)
+	ch <- &Package{
 		Sensor: sensor,
 		Data:   data,
+		Span:   span,
 	}
+	tracing.Finish(enqueue, nil)
+	depth.Set(float64(len(ch)))
 }
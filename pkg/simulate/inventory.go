@@ -0,0 +1,143 @@
+package simulate
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// rateWindow 数据速率统计窗口
+const rateWindow = time.Second
+
+// SensorStatus - 传感器接收统计
+type SensorStatus struct {
+	ID              uint64  `json:"id"`
+	SID             string  `json:"sid"`
+	Shard           uint64  `json:"shard"`             // 数据处理协程
+	FirstSeen       int64   `json:"first_seen"`        // 首次接收时间(毫秒)
+	LastSeen        int64   `json:"last_seen"`         // 最近接收时间(毫秒)
+	Timestamp       int64   `json:"timestamp"`         // 最近解包成功的Frame时间戳(毫秒)
+	Frames          int64   `json:"frames"`            // 接收数据包数
+	Bytes           int64   `json:"bytes"`             // 接收字节数
+	Errors          int64   `json:"errors"`            // 解包失败数
+	FramesPerSecond float64 `json:"frames_per_second"` // 当前数据包速率
+	BytesPerSecond  float64 `json:"bytes_per_second"`  // 当前数据速率
+}
+
+// sensorStats 传感器接收计数
+type sensorStats struct {
+	id        uint64
+	sid       string
+	firstSeen int64
+	lastSeen  *atomic.Int64
+	frames    *atomic.Int64
+	bytes     *atomic.Int64
+	errors    *atomic.Int64
+
+	mu           sync.Mutex
+	windowStart  time.Time
+	windowFrames int64
+	windowBytes  int64
+	frameRate    float64
+	byteRate     float64
+}
+
+// observe 记录接收的数据包，每个统计窗口更新一次速率
+func (s *sensorStats) observe(size int, now time.Time) {
+	s.lastSeen.Store(now.UnixMilli())
+	s.frames.Inc()
+	s.bytes.Add(int64(size))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windowFrames++
+	s.windowBytes += int64(size)
+	if elapsed := now.Sub(s.windowStart); elapsed >= rateWindow {
+		s.frameRate = float64(s.windowFrames) / elapsed.Seconds()
+		s.byteRate = float64(s.windowBytes) / elapsed.Seconds()
+		s.windowStart = now
+		s.windowFrames = 0
+		s.windowBytes = 0
+	}
+}
+
+// rates 当前速率，超过两个统计窗口未收到数据时为0
+func (s *sensorStats) rates(now time.Time) (float64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.windowStart) > 2*rateWindow {
+		return 0, 0
+	}
+	return s.frameRate, s.byteRate
+}
+
+// stats 获取传感器接收计数
+func (cs *Server) stats(sensor *Sensor, now time.Time) *sensorStats {
+	if v, ok := cs.sensors.Load(sensor.id); ok {
+		return v.(*sensorStats)
+	}
+	v, _ := cs.sensors.LoadOrStore(sensor.id, &sensorStats{
+		id:          sensor.id,
+		sid:         sensor.sid,
+		firstSeen:   now.UnixMilli(),
+		lastSeen:    atomic.NewInt64(0),
+		frames:      atomic.NewInt64(0),
+		bytes:       atomic.NewInt64(0),
+		errors:      atomic.NewInt64(0),
+		windowStart: now,
+	})
+	return v.(*sensorStats)
+}
+
+// status 传感器接收统计
+func (cs *Server) status(s *sensorStats, now time.Time) SensorStatus {
+	status := SensorStatus{
+		ID:        s.id,
+		SID:       s.sid,
+		Shard:     cs.shard(s.id),
+		FirstSeen: s.firstSeen,
+		LastSeen:  s.lastSeen.Load(),
+		Frames:    s.frames.Load(),
+		Bytes:     s.bytes.Load(),
+		Errors:    s.errors.Load(),
+	}
+	if v, ok := cs.tmap.Load(s.id); ok {
+		status.Timestamp = v.(int64)
+	}
+	status.FramesPerSecond, status.BytesPerSecond = s.rates(now)
+	return status
+}
+
+// Sensors - 获取全部已接收传感器的统计，按字符串编号排序
+// @return []SensorStatus 传感器接收统计
+func (cs *Server) Sensors() []SensorStatus {
+	now := time.Now()
+	list := []SensorStatus{}
+	cs.sensors.Range(func(_, value interface{}) bool {
+		list = append(list, cs.status(value.(*sensorStats), now))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SID < list[j].SID
+	})
+	return list
+}
+
+// Sensor - 获取单个传感器的统计
+// @param sid string 传感器字符串编号，十六进制
+// @return SensorStatus 传感器接收统计
+// @return bool 是否接收过该传感器数据
+func (cs *Server) Sensor(sid string) (SensorStatus, bool) {
+	id, err := strconv.ParseUint(sid, 16, 64)
+	if err != nil {
+		return SensorStatus{}, false
+	}
+	v, ok := cs.sensors.Load(id)
+	if !ok {
+		return SensorStatus{}, false
+	}
+	return cs.status(v.(*sensorStats), time.Now()), true
+}
@@ -0,0 +1,43 @@
+package simulate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSensors(t *testing.T) {
+	h, err := New(WithConfig(&Config{GoroutineCount: 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := h.(*Server)
+	sensor := &Sensor{id: 0x94C96000C248, sid: "94C96000C248"}
+	cs.ToHandle(sensor, make([]byte, 100))
+	cs.ToHandle(sensor, make([]byte, 50))
+	cs.ToHandle(&Sensor{id: 1, sid: "000000000001"}, make([]byte, 64))
+
+	// 无效数据包解包失败计入错误数
+	deadline := time.Now().Add(time.Second)
+	for {
+		status, ok := cs.Sensor("94c96000c248")
+		if ok && status.Errors == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	list := cs.Sensors()
+	if len(list) != 2 || list[0].SID != "000000000001" || list[1].SID != "94C96000C248" {
+		t.Fatalf("unexpected sensors %+v", list)
+	}
+	s := list[1]
+	if s.Frames != 2 || s.Bytes != 150 || s.Shard != 0 || s.FirstSeen == 0 || s.LastSeen < s.FirstSeen {
+		t.Fatalf("unexpected sensor %+v", s)
+	}
+	if _, ok := cs.Sensor("FFFF"); ok {
+		t.Fatal("unseen sensor found")
+	}
+}
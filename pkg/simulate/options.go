@@ -3,8 +3,14 @@ package simulate
 import (
 	"sync"
 
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
 	"github.com/kiga-hub/arc-consumer/pkg/goss"
-	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
 	"github.com/kiga-hub/arc/logging"
 )
 
@@ -28,10 +34,17 @@ func loadOptions(options ...Option) *Server {
 	return opts
 }
 
-// WithGrpc -
-func WithGrpc(g grpc.Handler) Option {
+// WithSink -
+func WithSink(s sink.Handler) Option {
 	return func(opts *Server) {
-		opts.grpc = g
+		opts.sinks = s
+	}
+}
+
+// WithFeature - 特征提取，nil不提取
+func WithFeature(e *feature.Extractor) Option {
+	return func(opts *Server) {
+		opts.features = e
 	}
 }
 
@@ -42,6 +55,34 @@ func WithKVCache(g goss.Handler) Option {
 	}
 }
 
+// WithRegistry - 传感器登记，nil接收所有传感器
+func WithRegistry(r *registry.Registry) Option {
+	return func(opts *Server) {
+		opts.registry = r
+	}
+}
+
+// WithMeta - 传感器元数据，附加到转发的数据包、特征和事件
+func WithMeta(m *meta.Store) Option {
+	return func(opts *Server) {
+		opts.meta = m
+	}
+}
+
+// WithLimiter - 接收限流，nil不限流
+func WithLimiter(l *limit.Limiter) Option {
+	return func(opts *Server) {
+		opts.limiter = l
+	}
+}
+
+// WithLiveness - 传感器在线检测，nil不检测
+func WithLiveness(t *liveness.Tracker) Option {
+	return func(opts *Server) {
+		opts.liveness = t
+	}
+}
+
 // WithLogger -
 func WithLogger(logger logging.ILogger) Option {
 	return func(opts *Server) {
@@ -55,3 +96,24 @@ func WithConfig(c *Config) Option {
 		opts.config = c
 	}
 }
+
+// WithTracing - 数据包采样跟踪
+func WithTracing(t *tracing.Sampler) Option {
+	return func(opts *Server) {
+		opts.tracing = t
+	}
+}
+
+// WithStateListener - 数据管道状态切换回调
+func WithStateListener(l StateListener) Option {
+	return func(opts *Server) {
+		opts.listener = l
+	}
+}
+
+// WithShardListener - 数据处理协程数修改回调
+func WithShardListener(l ShardListener) Option {
+	return func(opts *Server) {
+		opts.shards = l
+	}
+}
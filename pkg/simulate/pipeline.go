@@ -0,0 +1,296 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// drainInterval 排空时检查管道的间隔
+const drainInterval = 100 * time.Millisecond
+
+// reshardTimeout 修改数据处理协程数时等待管道排空的最长时间
+const reshardTimeout = 30 * time.Second
+
+// State - 数据管道状态
+type State int32
+
+const (
+	// StateRunning 接收、处理并转发
+	StateRunning State = iota
+	// StateForwardPaused 继续接收并缓存在管道中，暂停处理和转发
+	StateForwardPaused
+	// StateIngestPaused 丢弃新接收的数据包，管道中已缓存的数据继续处理
+	StateIngestPaused
+	// StateDraining 拒绝接收，处理完管道中全部数据后停止
+	StateDraining
+	// StateStopped 管道已排空，停止接收和转发
+	StateStopped
+)
+
+// String - 状态名称
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateForwardPaused:
+		return "forward_paused"
+	case StateIngestPaused:
+		return "ingest_paused"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// Accepting - 是否接收新数据包
+func (s State) Accepting() bool {
+	return s == StateRunning || s == StateForwardPaused
+}
+
+// Forwarding - 是否处理并转发数据包
+func (s State) Forwarding() bool {
+	return s == StateRunning || s == StateIngestPaused || s == StateDraining
+}
+
+// ErrTransition 当前状态不允许切换到目标状态
+var ErrTransition = errors.New("invalid pipeline state transition")
+
+// StateListener - 管道状态切换回调，暂停转发时在处理中的数据包完成后调用，恢复转发时在开始处理前调用
+type StateListener func(from, to State)
+
+// ShardListener - 数据处理协程数修改回调，管道已排空，恢复接收前调用(例如修改grpc连接池掩码)
+type ShardListener func(count int)
+
+// pipeline 数据管道状态，控制数据包入管道和处理协程
+type pipeline struct {
+	transition sync.Mutex // 串行化状态切换
+	mu         sync.Mutex
+	cond       *sync.Cond
+	state      State
+	admitting  int // 正在入管道的数据包数
+	queued     int // 已入管道等待处理的数据包数
+	busy       int // 正在处理的数据包数
+	drained    chan struct{}
+	listener   StateListener
+}
+
+func newPipeline(listener StateListener) *pipeline {
+	p := &pipeline{listener: listener}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// get 当前状态
+func (p *pipeline) get() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// admit 数据包入管道前检查，返回false时丢弃，返回true时入管道后调用admitted
+func (p *pipeline) admit() (State, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.state.Accepting() {
+		return p.state, false
+	}
+	p.admitting++
+	return p.state, true
+}
+
+// admitted 数据包已入管道
+func (p *pipeline) admitted() {
+	p.mu.Lock()
+	p.admitting--
+	p.queued++
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// acquire 处理协程处理数据包前调用，暂停转发时阻塞
+func (p *pipeline) acquire() {
+	p.mu.Lock()
+	for !p.state.Forwarding() {
+		p.cond.Wait()
+	}
+	p.queued--
+	p.busy++
+	p.mu.Unlock()
+}
+
+// release 数据包处理完成
+func (p *pipeline) release() {
+	p.mu.Lock()
+	p.busy--
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// set 切换状态，调用方持有transition锁
+// 暂停转发时等待处理中的数据包完成后回调(例如断开grpc)，恢复转发时先回调(例如重连grpc)再唤醒处理协程
+func (p *pipeline) set(to State) {
+	p.mu.Lock()
+	from := p.state
+	if to != StateDraining && p.drained != nil {
+		close(p.drained)
+		p.drained = nil
+	}
+	resume := !from.Forwarding() && to.Forwarding()
+	if !resume {
+		p.state = to
+		for from.Forwarding() && !to.Forwarding() && p.busy > 0 {
+			p.cond.Wait()
+		}
+	}
+	p.mu.Unlock()
+
+	if from != to && p.listener != nil {
+		p.listener(from, to)
+	}
+	if resume {
+		p.mu.Lock()
+		p.state = to
+		p.mu.Unlock()
+		p.cond.Broadcast()
+	}
+}
+
+// change 按允许的来源状态切换
+func (p *pipeline) change(to State, allowed ...State) error {
+	p.transition.Lock()
+	defer p.transition.Unlock()
+	from := p.get()
+	if from == to {
+		return nil
+	}
+	for _, s := range allowed {
+		if s == from {
+			p.set(to)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrTransition, from, to)
+}
+
+// State - 数据管道状态
+func (cs *Server) State() State {
+	return cs.pipe.get()
+}
+
+// PauseForward - 暂停处理和转发，继续接收并缓存在管道中，管道满时阻塞接收
+// @return err 当前状态不允许暂停转发
+func (cs *Server) PauseForward() error {
+	return cs.pipe.change(StateForwardPaused, StateRunning, StateIngestPaused)
+}
+
+// PauseIngest - 暂停接收，新数据包丢弃，管道中已缓存的数据继续处理和转发
+// @return err 当前状态不允许暂停接收
+func (cs *Server) PauseIngest() error {
+	return cs.pipe.change(StateIngestPaused, StateRunning, StateForwardPaused)
+}
+
+// Resume - 恢复接收和转发，排空过程中恢复时取消排空
+// @return err 错误信息
+func (cs *Server) Resume() error {
+	return cs.pipe.change(StateRunning, StateForwardPaused, StateIngestPaused, StateDraining, StateStopped)
+}
+
+// Drain - 拒绝接收，处理完管道中全部数据并写出转发目标缓存后停止，不等待排空完成
+// @return err 当前状态不允许排空
+func (cs *Server) Drain() error {
+	cs.pipe.transition.Lock()
+	defer cs.pipe.transition.Unlock()
+	from := cs.pipe.get()
+	switch from {
+	case StateDraining:
+		return nil
+	case StateStopped:
+		return fmt.Errorf("%w: %s -> %s", ErrTransition, from, StateDraining)
+	}
+	drained := make(chan struct{})
+	cs.pipe.mu.Lock()
+	cs.pipe.drained = drained
+	cs.pipe.mu.Unlock()
+	cs.pipe.set(StateDraining)
+	go cs.drain(drained)
+	return nil
+}
+
+// drain 等待入管道、管道中和处理中的数据包全部完成后停止，期间状态被切换时放弃
+func (cs *Server) drain(drained chan struct{}) {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-drained:
+			return
+		case <-ticker.C:
+		}
+		if !cs.empty() {
+			continue
+		}
+		if cs.sinks != nil {
+			if err := cs.sinks.Flush(); err != nil {
+				cs.logger.Warnw("drain flush sinks", "err", err)
+			}
+		}
+		cs.pipe.transition.Lock()
+		if cs.pipe.get() == StateDraining {
+			cs.pipe.set(StateStopped)
+			cs.logger.Infow("pipeline drained")
+		}
+		cs.pipe.transition.Unlock()
+		return
+	}
+}
+
+// empty 入管道、管道中和处理中的数据包均为0
+func (cs *Server) empty() bool {
+	cs.pipe.mu.Lock()
+	defer cs.pipe.mu.Unlock()
+	return cs.pipe.admitting == 0 && cs.pipe.queued == 0 && cs.pipe.busy == 0
+}
+
+// reshard 修改数据处理协程数，排空管道后关闭原协程，恢复原状态后按新协程数重新创建
+// 同一传感器的数据包在原协程全部处理完后才切换协程，不会乱序
+func (cs *Server) reshard(c *Config) error {
+	cs.pipe.transition.Lock()
+	defer cs.pipe.transition.Unlock()
+	from := cs.pipe.get()
+	switch from {
+	case StateForwardPaused, StateDraining:
+		return fmt.Errorf("%s cannot change while pipeline is %s", goroutineCount, from)
+	}
+	if from != StateStopped {
+		cs.pipe.set(StateDraining)
+	}
+	deadline := time.Now().Add(reshardTimeout)
+	for !cs.empty() {
+		if time.Now().After(deadline) {
+			cs.pipe.set(from)
+			return fmt.Errorf("%s change timed out waiting for pipeline to drain", goroutineCount)
+		}
+		time.Sleep(drainInterval)
+	}
+
+	// 管道已排空且拒绝接收，关闭原协程
+	cs.frameChans.Range(func(key, value interface{}) bool {
+		cs.frameChans.Delete(key)
+		close(value.(chan *Package))
+		return true
+	})
+	cs.configMu.Lock()
+	cs.config = c
+	cs.configMu.Unlock()
+	if cs.shards != nil {
+		cs.shards(c.GoroutineCount)
+	}
+	if from != StateStopped {
+		cs.pipe.set(from)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package simulate
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor 等待条件满足
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	var mu sync.Mutex
+	var changes []string
+	h, err := New(
+		WithConfig(&Config{GoroutineCount: 1}),
+		WithStateListener(func(from, to State) {
+			mu.Lock()
+			changes = append(changes, from.String()+">"+to.String())
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := h.(*Server)
+	sensor := &Sensor{id: 1, sid: "000000000001"}
+	processed := func() int64 {
+		status, _ := cs.Sensor(sensor.sid)
+		return status.Errors
+	}
+
+	// 暂停转发时继续接收并缓存
+	if err := cs.PauseForward(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		cs.ToHandle(sensor, make([]byte, 100))
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := processed(); n != 0 {
+		t.Fatalf("frames processed while forward paused: %d", n)
+	}
+
+	// 恢复后处理缓存的数据包
+	if err := cs.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return processed() == 3 })
+
+	// 暂停接收时丢弃新数据包
+	if err := cs.PauseIngest(); err != nil {
+		t.Fatal(err)
+	}
+	cs.ToHandle(sensor, make([]byte, 100))
+	time.Sleep(50 * time.Millisecond)
+	if n := processed(); n != 3 {
+		t.Fatalf("frame accepted while ingest paused: %d", n)
+	}
+
+	// 排空后停止
+	if err := cs.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	cs.ToHandle(sensor, make([]byte, 100))
+	if err := cs.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return cs.State() == StateStopped })
+	if n := processed(); n != 4 {
+		t.Fatalf("frames lost while draining: %d", n)
+	}
+	if err := cs.PauseForward(); !errors.Is(err, ErrTransition) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"running>forward_paused", "forward_paused>running",
+		"running>ingest_paused", "ingest_paused>running",
+		"running>draining", "draining>stopped",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("unexpected changes %v", changes)
+		}
+	}
+}
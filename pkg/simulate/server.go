@@ -2,15 +2,21 @@ package simulate
 
 import (
 	"context"
-	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/kiga-hub/arc/logging"
-	"github.com/kiga-hub/arc/protocols"
+	"go.uber.org/atomic"
 
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
 	"github.com/kiga-hub/arc-consumer/pkg/goss"
-	"github.com/kiga-hub/arc-consumer/pkg/grpc"
+	"github.com/kiga-hub/arc-consumer/pkg/limit"
+	"github.com/kiga-hub/arc-consumer/pkg/liveness"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/registry"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
 )
 
 // Sensor - 传感器结构
@@ -18,24 +24,57 @@ type Sensor struct {
 	id  uint64 // 编号
 	sid string // 字符串编号
 
+	close func() // 断开设备会话，nil不支持断开
 }
 
 // Handler - simulate 接口
 type Handler interface {
 	Start(context.Context) error
 	Stop() error
+	Sensors() []SensorStatus
+	Sensor(sid string) (SensorStatus, bool)
+	Running() bool
+	Shards() []ShardStatus
+	State() State
+	PauseForward() error
+	PauseIngest() error
+	Resume() error
+	Drain() error
+	Disconnect(id uint64, sid string) bool
+	Config() *Config
+	UpdateConfig(*Config) error
+	SetFeatures(*feature.Extractor)
+}
+
+// ShardStatus - 数据处理协程管道状态
+type ShardStatus struct {
+	Index    uint64 `json:"index"`
+	Depth    int    `json:"depth"`    // 管道中待处理数据包数
+	Capacity int    `json:"capacity"` // 管道容量
 }
 
 // Server - 服务结构
 type Server struct {
 	// *simulate.EventServer
 	sensors    *sync.Map
+	sessions   *sync.Map
 	frameChans *sync.Map
 	tmap       *sync.Map
+	configMu   sync.RWMutex
 	config     *Config
 	logger     logging.ILogger
-	grpc       grpc.Handler
+	sinks      sink.Handler
+	features   *feature.Extractor
+	tracing    *tracing.Sampler
 	kvCache    goss.Handler
+	registry   *registry.Registry
+	meta       *meta.Store
+	limiter    *limit.Limiter
+	liveness   *liveness.Tracker
+	running    *atomic.Bool
+	pipe       *pipeline
+	listener   StateListener
+	shards     ShardListener
 }
 
 // New  - 初始化结构
@@ -45,42 +84,113 @@ func New(opts ...Option) (Handler, error) {
 	spew.Dump(srv.config)
 
 	srv.tmap = new(sync.Map)
+	srv.sessions = new(sync.Map)
+	srv.running = atomic.NewBool(false)
+	srv.pipe = newPipeline(srv.listener)
 
-	// 检查goroutine count
-	if srv.config.GoroutineCount <= 0 ||
-		(srv.config.GoroutineCount&(srv.config.GoroutineCount-1)) != 0 {
-		return nil, fmt.Errorf("config goroutine count err")
+	if err := srv.config.Validate(); err != nil {
+		return nil, err
 	}
 
-	if srv.grpc != nil {
-		srv.grpc.SetMask(uint64(srv.config.GoroutineCount - 1))
+	return srv, nil
+}
+
+func (cs *Server) getConfig() *Config {
+	cs.configMu.RLock()
+	defer cs.configMu.RUnlock()
+	return cs.config
+}
+
+// Config - 当前使用的配置，不可修改
+func (cs *Server) Config() *Config {
+	return cs.getConfig()
+}
+
+// UpdateConfig - 更新CRC校验和数据处理协程数，监听地址等参数需重启生效
+// 协程数修改时先排空管道再切换，期间新数据包丢弃，暂停转发或排空中不允许修改
+// @param c *Config 配置
+// @return err 错误信息
+func (cs *Server) UpdateConfig(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
 	}
+	if running := cs.getConfig(); c.GoroutineCount != running.GoroutineCount {
+		if err := cs.reshard(c); err != nil {
+			return err
+		}
+		cs.logger.Infow("simulate config updated", "crc", c.EnableCRCCheck, "goroutine_count", c.GoroutineCount)
+		return nil
+	}
+	cs.configMu.Lock()
+	cs.config = c
+	cs.configMu.Unlock()
+	cs.logger.Infow("simulate config updated", "crc", c.EnableCRCCheck)
+	return nil
+}
 
-	return srv, nil
+// extractor 当前特征提取，未开启为nil
+func (cs *Server) extractor() *feature.Extractor {
+	cs.configMu.RLock()
+	defer cs.configMu.RUnlock()
+	return cs.features
+}
+
+// SetFeatures - 替换特征提取，nil停止提取
+func (cs *Server) SetFeatures(e *feature.Extractor) {
+	cs.configMu.Lock()
+	cs.features = e
+	cs.configMu.Unlock()
 }
 
 // Start - 启动服务
 func (cs *Server) Start(ctx context.Context) error {
-	codec := &protocols.Coder{}
-	codec.IsCrcCheck = cs.config.EnableCRCCheck
-
 	go func() {
 		cs.Producer()
 	}()
 
+	cs.running.Store(true)
 	return nil
 }
 
 // Stop - 停止服务
 func (cs *Server) Stop() error {
+	cs.running.Store(false)
 	return nil
 }
 
+// Running - 接收服务是否已启动
+func (cs *Server) Running() bool {
+	return cs.running.Load()
+}
+
+// Shards - 获取已创建的数据处理协程管道状态，按协程排序
+func (cs *Server) Shards() []ShardStatus {
+	list := []ShardStatus{}
+	cs.frameChans.Range(func(key, value interface{}) bool {
+		ch := value.(chan *Package)
+		list = append(list, ShardStatus{
+			Index:    key.(uint64),
+			Depth:    len(ch),
+			Capacity: cap(ch),
+		})
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Index < list[j].Index
+	})
+	return list
+}
+
 func (cs *Server) Producer() {
 	sensor := &Sensor{
 		id:  1,
 		sid: "94C96000C248",
 	}
+	sensor.close = func() {
+		cs.logger.Infow("simulated session closed", "sensor", sensor.sid)
+	}
+	cs.open(sensor)
+	defer cs.closed(sensor)
 	data := make([]byte, 1024)
 	cs.ToHandle(sensor, data)
 }
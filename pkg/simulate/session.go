@@ -0,0 +1,29 @@
+package simulate
+
+// open 登记设备会话，接收层建立连接后调用
+func (cs *Server) open(sensor *Sensor) {
+	cs.sessions.Store(sensor.sid, sensor)
+}
+
+// closed 注销设备会话，接收层连接断开后调用，已被新会话替换时不注销
+func (cs *Server) closed(sensor *Sensor) {
+	cs.sessions.CompareAndDelete(sensor.sid, sensor)
+}
+
+// Disconnect - 断开传感器设备会话，接收限流disconnect策略拒绝期间调用
+// @param id uint64 传感器编号
+// @param sid string 传感器字符串编号
+// @return bool 是否有可断开的会话
+func (cs *Server) Disconnect(id uint64, sid string) bool {
+	v, ok := cs.sessions.LoadAndDelete(sid)
+	if !ok {
+		return false
+	}
+	sensor := v.(*Sensor)
+	if sensor.close == nil {
+		return false
+	}
+	sensor.close()
+	cs.logger.Infow("sensor session disconnected", "sensor", sid, "id", id)
+	return true
+}
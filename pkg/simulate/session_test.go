@@ -0,0 +1,26 @@
+package simulate
+
+import "testing"
+
+func TestDisconnect(t *testing.T) {
+	h, err := New(WithConfig(&Config{GoroutineCount: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := h.(*Server)
+
+	closed := 0
+	old := &Sensor{id: 1, sid: "000000000001", close: func() { closed++ }}
+	cs.open(old)
+	// 重新连接替换旧会话，旧会话断开后不注销新会话
+	current := &Sensor{id: 1, sid: "000000000001", close: func() { closed += 10 }}
+	cs.open(current)
+	cs.closed(old)
+
+	if !cs.Disconnect(1, "000000000001") || closed != 10 {
+		t.Fatalf("disconnect closed %d", closed)
+	}
+	if cs.Disconnect(1, "000000000001") {
+		t.Fatal("session disconnected twice")
+	}
+}
@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// KeySink 转发目标配置列表，对应配置文件中的 [[sink]]
+const KeySink = "sink"
+
+// Config - 单个转发目标配置
+type Config struct {
+	Name     string                 `toml:"name" mapstructure:"name" json:"name"`
+	Type     string                 `toml:"type" mapstructure:"type" json:"type"`
+	Enable   bool                   `toml:"enable" mapstructure:"enable" json:"enable"`
+	Sensors  []string               `toml:"sensors" mapstructure:"sensors" json:"sensors,omitempty"`    // 传感器编号过滤，空不过滤
+	Segments []int                  `toml:"segments" mapstructure:"segments" json:"segments,omitempty"` // 数据段类型过滤，空不过滤
+	Options  map[string]interface{} `toml:"options" mapstructure:"options" json:"options,omitempty"`    // 转发目标类型相关参数
+}
+
+// GetConfig - 获取转发目标配置列表，未配置enable时默认开启
+func GetConfig() ([]*Config, error) {
+	list := []*Config{}
+	if !viper.IsSet(KeySink) {
+		return list, nil
+	}
+	items := []map[string]interface{}{}
+	if err := viper.UnmarshalKey(KeySink, &items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		c := &Config{Enable: true}
+		if err := mapstructure.WeakDecode(item, c); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// Validate - 检查转发目标配置
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("sink name is empty")
+	}
+	if c.Type == "" {
+		return fmt.Errorf("sink %s type is empty", c.Name)
+	}
+	for _, s := range c.Segments {
+		if s < 0 || s > 255 {
+			return fmt.Errorf("sink %s invalid segment type %d", c.Name, s)
+		}
+	}
+	return nil
+}
+
+// DecodeOptions - 将转发目标参数解析到类型相关配置结构，字段名取toml标签
+// @param options map[string]interface{} 转发目标参数
+// @param out interface{} 配置结构指针，已填充默认值
+// @return err 错误信息
+func DecodeOptions(options map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "toml",
+		WeaklyTypedInput: true,
+		Result:           out,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(options)
+}
@@ -0,0 +1,60 @@
+package sink
+
+import (
+	"strings"
+)
+
+// Filter - 按传感器编号和数据段类型过滤数据包
+type Filter struct {
+	sensors  map[string]struct{}
+	segments map[byte]struct{}
+}
+
+// NewFilter - 创建过滤器，列表为空时不过滤
+// @param sensors []string 传感器字符串编号
+// @param segments []int 数据段类型
+// @return *Filter 过滤器
+func NewFilter(sensors []string, segments []int) *Filter {
+	f := &Filter{}
+	if len(sensors) > 0 {
+		f.sensors = make(map[string]struct{}, len(sensors))
+		for _, s := range sensors {
+			f.sensors[strings.ToUpper(s)] = struct{}{}
+		}
+	}
+	if len(segments) > 0 {
+		f.segments = make(map[byte]struct{}, len(segments))
+		for _, s := range segments {
+			f.segments[byte(s)] = struct{}{}
+		}
+	}
+	return f
+}
+
+// MatchSensor - 传感器是否通过过滤，用于特征和事件
+func (f *Filter) MatchSensor(sid string) bool {
+	if f == nil || f.sensors == nil {
+		return true
+	}
+	_, ok := f.sensors[strings.ToUpper(sid)]
+	return ok
+}
+
+// Match - 数据包是否通过过滤
+func (f *Filter) Match(frame *Frame) bool {
+	if !f.MatchSensor(frame.SID) {
+		return false
+	}
+	if f == nil {
+		return true
+	}
+	if f.segments != nil {
+		for _, st := range frame.STypes {
+			if _, ok := f.segments[st]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"github.com/kiga-hub/arc/logging"
+	"go.uber.org/atomic"
+)
+
+// Option is a function that will set up option.
+type Option func(opts *Registry)
+
+func loadOptions(options ...Option) *Registry {
+	opts := &Registry{
+		factories: map[string]Factory{},
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	if opts.logger == nil {
+		opts.logger = new(logging.NoopLogger)
+	}
+	return opts
+}
+
+// WithLogger -
+func WithLogger(logger logging.ILogger) Option {
+	return func(opts *Registry) {
+		opts.logger = logger
+	}
+}
+
+// WithConfig - 转发目标配置列表
+func WithConfig(c []*Config) Option {
+	return func(opts *Registry) {
+		opts.configs = c
+	}
+}
+
+// WithDefault - 未配置转发目标时使用的默认配置
+func WithDefault(c ...*Config) Option {
+	return func(opts *Registry) {
+		opts.defaults = append(opts.defaults, c...)
+	}
+}
+
+// WithFactory - 注册转发目标类型
+func WithFactory(typ string, f Factory) Option {
+	return func(opts *Registry) {
+		opts.factories[typ] = f
+	}
+}
+
+// WithSink - 添加已创建的转发目标，不受配置影响
+func WithSink(name, typ string, s Sink) Option {
+	return func(opts *Registry) {
+		opts.prebuilt = append(opts.prebuilt, &entry{
+			name:    name,
+			typ:     typ,
+			sink:    s,
+			written: atomic.NewInt64(0),
+			errors:  atomic.NewInt64(0),
+		})
+	}
+}
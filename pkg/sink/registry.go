@@ -0,0 +1,406 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kiga-hub/arc/logging"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/metrics"
+	"github.com/kiga-hub/arc-consumer/pkg/tracing"
+)
+
+// Handler - 转发目标管理接口
+type Handler interface {
+	Start(context.Context)
+	Write(*Frame) error
+	WriteFeature(*feature.Feature) error
+	WriteEvent(*event.Event) error
+	Read(sid string, from, to int64) ([]*Frame, error)
+	Flush() error
+	Close() error
+	Status() []Status
+	Prepare(configs []*Config) (*Update, error)
+}
+
+// Status - 转发目标状态
+type Status struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+	Written int64  `json:"written"`
+	Errors  int64  `json:"errors"`
+}
+
+// entry 已创建的转发目标
+type entry struct {
+	name    string
+	typ     string
+	config  *Config // 预建的转发目标为nil
+	sink    Sink
+	filter  *Filter
+	written *atomic.Int64
+	errors  *atomic.Int64
+	// inflight 正在进行的写入，删除时等待完成后关闭
+	inflight sync.WaitGroup
+
+	writeMetric prometheus.Counter
+	errorMetric prometheus.Counter
+}
+
+// success 记录写入成功
+func (e *entry) success() {
+	e.written.Inc()
+	e.writeMetric.Inc()
+}
+
+// failure 记录写入失败
+func (e *entry) failure() {
+	e.errors.Inc()
+	e.errorMetric.Inc()
+}
+
+// Registry - 转发目标管理，按过滤条件将数据包分发到多个转发目标
+type Registry struct {
+	mu        sync.RWMutex
+	entries   []*entry
+	factories map[string]Factory
+	configs   []*Config
+	defaults  []*Config
+	prebuilt  []*entry
+	logger    logging.ILogger
+}
+
+// New - 根据配置创建全部转发目标
+// @param opts Option 设置选项的函数，可变参数
+// @return Handler 转发目标管理接口
+// @return err 错误信息
+func New(opts ...Option) (Handler, error) {
+	r := loadOptions(opts...)
+
+	for _, e := range r.prebuilt {
+		e.writeMetric = metrics.SinkWrites.WithLabelValues(e.name, e.typ)
+		e.errorMetric = metrics.SinkErrors.WithLabelValues(e.name, e.typ)
+	}
+	entries, err := r.build(r.configs, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.entries = entries
+	return r, nil
+}
+
+// build 创建配置的转发目标，配置未修改的复用 current 中的转发目标
+// 返回全部转发目标，创建失败时关闭本次新建的转发目标
+func (r *Registry) build(configs []*Config, current map[string]*entry) ([]*entry, error) {
+	if len(configs) == 0 {
+		configs = r.defaults
+	}
+
+	names := map[string]struct{}{}
+	for _, e := range r.prebuilt {
+		names[e.name] = struct{}{}
+	}
+	entries := append([]*entry(nil), r.prebuilt...)
+	var created []*entry
+	fail := func(err error) ([]*entry, error) {
+		for _, e := range created {
+			_ = e.sink.Close()
+		}
+		return nil, err
+	}
+	for _, c := range configs {
+		if err := c.Validate(); err != nil {
+			return fail(err)
+		}
+		if _, ok := names[c.Name]; ok {
+			return fail(fmt.Errorf("duplicate sink name %s", c.Name))
+		}
+		names[c.Name] = struct{}{}
+		if !c.Enable {
+			continue
+		}
+		if e, ok := current[c.Name]; ok && reflect.DeepEqual(e.config, c) {
+			entries = append(entries, e)
+			continue
+		}
+		factory, ok := r.factories[c.Type]
+		if !ok {
+			return fail(fmt.Errorf("sink %s unsupported type %s", c.Name, c.Type))
+		}
+		s, err := factory(c)
+		if err != nil {
+			return fail(fmt.Errorf("sink %s: %w", c.Name, err))
+		}
+		e := &entry{
+			name:    c.Name,
+			typ:     c.Type,
+			config:  c,
+			sink:    s,
+			filter:  NewFilter(c.Sensors, c.Segments),
+			written: atomic.NewInt64(0),
+			errors:  atomic.NewInt64(0),
+		}
+		e.writeMetric = metrics.SinkWrites.WithLabelValues(e.name, e.typ)
+		e.errorMetric = metrics.SinkErrors.WithLabelValues(e.name, e.typ)
+		entries = append(entries, e)
+		created = append(created, e)
+		r.logger.Infow("sink created", "name", c.Name, "type", c.Type)
+	}
+	return entries, nil
+}
+
+// Update - 已创建好的转发目标修改，提交后替换，放弃时关闭新建的转发目标
+type Update struct {
+	registry *Registry
+	entries  []*entry
+	created  []*entry
+	removed  []*entry
+}
+
+// Prepare - 按新配置创建转发目标，配置未修改的复用，不影响当前写入
+// @param configs []*Config 转发目标配置列表
+// @return *Update 待提交的修改，配置均未修改时为nil
+// @return err 配置错误或创建失败，已新建的转发目标会关闭
+func (r *Registry) Prepare(configs []*Config) (*Update, error) {
+	current := map[string]*entry{}
+	for _, e := range r.list() {
+		if e.config != nil {
+			current[e.name] = e
+		}
+	}
+	entries, err := r.build(configs, current)
+	if err != nil {
+		return nil, err
+	}
+	u := &Update{registry: r, entries: entries}
+	kept := map[*entry]struct{}{}
+	for _, e := range entries {
+		if e.config == nil {
+			continue
+		}
+		if current[e.name] == e {
+			kept[e] = struct{}{}
+		} else {
+			u.created = append(u.created, e)
+		}
+	}
+	for _, e := range current {
+		if _, ok := kept[e]; !ok {
+			u.removed = append(u.removed, e)
+		}
+	}
+	if len(u.created) == 0 && len(u.removed) == 0 {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// Commit - 替换转发目标，等待删除和被替换的转发目标上正在进行的写入完成后关闭，写出其缓存数据
+// 替换不等待写入，其他转发目标的写入不受影响
+func (u *Update) Commit() {
+	u.registry.mu.Lock()
+	u.registry.entries = u.entries
+	u.registry.mu.Unlock()
+	for _, e := range u.removed {
+		e.inflight.Wait()
+		if err := e.sink.Close(); err != nil {
+			u.registry.logger.Warnw("close sink", "name", e.name, "err", err)
+		}
+		u.registry.logger.Infow("sink removed", "name", e.name, "type", e.typ)
+	}
+}
+
+// Discard - 放弃修改，关闭新建的转发目标
+func (u *Update) Discard() {
+	for _, e := range u.created {
+		_ = e.sink.Close()
+	}
+}
+
+// list 当前转发目标
+func (r *Registry) list() []*entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.entries
+}
+
+// acquire 当前转发目标快照，使用完成后调用 release，写入期间不持有锁
+func (r *Registry) acquire() []*entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range r.entries {
+		e.inflight.Add(1)
+	}
+	return r.entries
+}
+
+// release 释放 acquire 获取的转发目标
+func release(entries []*entry) {
+	for _, e := range entries {
+		e.inflight.Done()
+	}
+}
+
+// Start - 定时写出各转发目标缓存数据
+func (r *Registry) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				r.logger.Warnw("sink flush", "err", err)
+			}
+		}
+	}
+}
+
+// Write - 数据包分发到过滤条件匹配的转发目标
+// @param frame *Frame 数据包
+// @return err 各转发目标的错误
+func (r *Registry) Write(frame *Frame) error {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		if !e.filter.Match(frame) {
+			continue
+		}
+		span := tracing.StartChild(frame.Span, "sink.write",
+			opentracing.Tag{Key: "sink.name", Value: e.name},
+			opentracing.Tag{Key: "sink.type", Value: e.typ})
+		err := e.sink.Write(frame)
+		tracing.Finish(span, err)
+		if err != nil {
+			e.failure()
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+			continue
+		}
+		e.success()
+	}
+	return errors.Join(errs...)
+}
+
+// WriteFeature - 特征分发到支持特征的转发目标
+// @param f *feature.Feature 特征
+// @return err 各转发目标的错误
+func (r *Registry) WriteFeature(f *feature.Feature) error {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		w, ok := e.sink.(FeatureWriter)
+		if !ok || !e.filter.MatchSensor(f.SID) {
+			continue
+		}
+		if err := w.WriteFeature(f); err != nil {
+			e.failure()
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteEvent - 事件分发到支持事件的转发目标，无传感器的事件不过滤
+// @param ev *event.Event 事件
+// @return err 各转发目标的错误
+func (r *Registry) WriteEvent(ev *event.Event) error {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		w, ok := e.sink.(EventWriter)
+		if !ok || (ev.SID != "" && !e.filter.MatchSensor(ev.SID)) {
+			continue
+		}
+		if err := w.WriteEvent(ev); err != nil {
+			e.failure()
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Read - 从支持读取的转发目标读取数据包，按配置顺序返回第一个有数据的结果
+// @param sid string 传感器字符串编号
+// @param from int64 开始时间(毫秒)
+// @param to int64 结束时间(毫秒)
+// @return []*Frame 数据包
+// @return err 全部转发目标均无数据时的错误
+func (r *Registry) Read(sid string, from, to int64) ([]*Frame, error) {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		reader, ok := e.sink.(Reader)
+		if !ok || !e.filter.MatchSensor(sid) {
+			continue
+		}
+		frames, err := reader.Read(sid, from, to)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+			continue
+		}
+		if len(frames) > 0 {
+			return frames, nil
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Flush - 写出各转发目标缓存数据
+func (r *Registry) Flush() error {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		if err := e.sink.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close - 关闭全部转发目标
+func (r *Registry) Close() error {
+	entries := r.acquire()
+	defer release(entries)
+	var errs []error
+	for _, e := range entries {
+		if err := e.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Status - 各转发目标状态
+func (r *Registry) Status() []Status {
+	entries := r.list()
+	list := make([]Status, 0, len(entries))
+	for _, e := range entries {
+		h := e.sink.Health()
+		list = append(list, Status{
+			Name:    e.name,
+			Type:    e.typ,
+			Healthy: h.Healthy,
+			Message: h.Message,
+			Written: e.written.Load(),
+			Errors:  e.errors.Load(),
+		})
+	}
+	return list
+}
@@ -0,0 +1,306 @@
+package sink
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	frames []*Frame
+	fail   bool
+	closed bool
+}
+
+func (m *memorySink) Write(f *Frame) error {
+	if m.fail {
+		return errors.New("write failed")
+	}
+	m.frames = append(m.frames, f)
+	return nil
+}
+
+func (m *memorySink) Flush() error { return nil }
+
+func (m *memorySink) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *memorySink) Health() Health { return Health{Healthy: !m.fail} }
+
+func TestRegistryFanOut(t *testing.T) {
+	sinks := map[string]*memorySink{}
+	factory := func(c *Config) (Sink, error) {
+		s := &memorySink{fail: c.Options["fail"] == true}
+		sinks[c.Name] = s
+		return s, nil
+	}
+
+	h, err := New(
+		WithFactory("memory", factory),
+		WithConfig([]*Config{
+			{Name: "all", Type: "memory", Enable: true},
+			{Name: "one", Type: "memory", Enable: true, Sensors: []string{"94c96000c248"}},
+			{Name: "other-segment", Type: "memory", Enable: true, Segments: []int{11}},
+			{Name: "disabled", Type: "memory", Enable: false},
+			{Name: "broken", Type: "memory", Enable: true, Options: map[string]interface{}{"fail": true}},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := []*Frame{
+		{ID: 1, SID: "94C96000C248", STypes: []byte{10}},
+		{ID: 2, SID: "94C96000C249", STypes: []byte{10}},
+	}
+	for _, f := range frames {
+		if err := h.Write(f); err == nil {
+			t.Fatal("expected error from broken sink")
+		}
+	}
+
+	want := map[string]int{"all": 2, "one": 1, "other-segment": 0}
+	for name, n := range want {
+		if got := len(sinks[name].frames); got != n {
+			t.Fatalf("sink %s got %d frames, want %d", name, got, n)
+		}
+	}
+	if _, ok := sinks["disabled"]; ok {
+		t.Fatal("disabled sink should not be created")
+	}
+
+	for _, st := range h.Status() {
+		if st.Name == "broken" && (st.Healthy || st.Errors != 2) {
+			t.Fatalf("unexpected broken sink status %+v", st)
+		}
+	}
+}
+
+func TestRegistryDefault(t *testing.T) {
+	factory := func(c *Config) (Sink, error) { return &memorySink{}, nil }
+	h, err := New(
+		WithFactory("memory", factory),
+		WithDefault(&Config{Name: "default", Type: "memory", Enable: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := h.Status(); len(st) != 1 || st[0].Name != "default" {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	if _, err := New(WithConfig([]*Config{{Name: "x", Type: "unknown", Enable: true}})); err == nil {
+		t.Fatal("expected unsupported type error")
+	}
+}
+
+// Read 按时间范围读回已写入的数据包
+func (m *memorySink) Read(sid string, from, to int64) ([]*Frame, error) {
+	var list []*Frame
+	for _, f := range m.frames {
+		if f.SID == sid && f.Timestamp >= from && f.Timestamp <= to {
+			list = append(list, f)
+		}
+	}
+	return list, nil
+}
+
+func TestRegistryRead(t *testing.T) {
+	h, err := New(
+		WithFactory("memory", func(c *Config) (Sink, error) { return &memorySink{}, nil }),
+		WithConfig([]*Config{
+			{Name: "one", Type: "memory", Enable: true, Sensors: []string{"94C96000C248"}},
+			{Name: "all", Type: "memory", Enable: true},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 3; i++ {
+		for _, sid := range []string{"94C96000C248", "94C96000C249"} {
+			if err := h.Write(&Frame{SID: sid, Timestamp: i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	frames, err := h.Read("94C96000C249", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || frames[0].Timestamp != 1 {
+		t.Fatalf("unexpected frames %+v", frames)
+	}
+	if frames, _ := h.Read("94C96000C249", 5, 6); len(frames) != 0 {
+		t.Fatalf("expected no frames, got %d", len(frames))
+	}
+}
+
+func TestRegistryPrebuilt(t *testing.T) {
+	live := &memorySink{}
+	h, err := New(
+		WithFactory("memory", func(c *Config) (Sink, error) { return &memorySink{}, nil }),
+		WithDefault(&Config{Name: "default", Type: "memory", Enable: true}),
+		WithSink("live", "websocket", live),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Write(&Frame{SID: "94C96000C248"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(live.frames) != 1 || len(h.Status()) != 2 {
+		t.Fatalf("unexpected frames %d, status %+v", len(live.frames), h.Status())
+	}
+
+	if _, err := New(
+		WithFactory("memory", func(c *Config) (Sink, error) { return &memorySink{}, nil }),
+		WithConfig([]*Config{{Name: "live", Type: "memory", Enable: true}}),
+		WithSink("live", "websocket", live),
+	); err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+}
+
+func TestRegistryPrepare(t *testing.T) {
+	created := map[string][]*memorySink{}
+	factory := func(c *Config) (Sink, error) {
+		if c.Options["fail"] == true {
+			return nil, errors.New("connect failed")
+		}
+		s := &memorySink{}
+		created[c.Name] = append(created[c.Name], s)
+		return s, nil
+	}
+	h, err := New(
+		WithFactory("memory", factory),
+		WithConfig([]*Config{
+			{Name: "keep", Type: "memory", Enable: true},
+			{Name: "change", Type: "memory", Enable: true},
+			{Name: "remove", Type: "memory", Enable: true},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未修改
+	if u, err := h.Prepare([]*Config{
+		{Name: "keep", Type: "memory", Enable: true},
+		{Name: "change", Type: "memory", Enable: true},
+		{Name: "remove", Type: "memory", Enable: true},
+	}); err != nil || u != nil {
+		t.Fatalf("unexpected update %v %v", u, err)
+	}
+
+	// 创建失败时关闭已新建的，当前转发目标不变
+	if _, err := h.Prepare([]*Config{
+		{Name: "change", Type: "memory", Enable: true, Sensors: []string{"94C96000C248"}},
+		{Name: "broken", Type: "memory", Enable: true, Options: map[string]interface{}{"fail": true}},
+	}); err == nil || !created["change"][1].closed || len(h.Status()) != 3 {
+		t.Fatalf("prepare error %v", err)
+	}
+
+	u, err := h.Prepare([]*Config{
+		{Name: "keep", Type: "memory", Enable: true},
+		{Name: "change", Type: "memory", Enable: true, Sensors: []string{"94C96000C248"}},
+		{Name: "add", Type: "memory", Enable: true},
+	})
+	if err != nil || u == nil {
+		t.Fatalf("prepare %v %v", u, err)
+	}
+	if err := h.Write(&Frame{SID: "94C96000C249"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(created["remove"][0].frames) != 1 {
+		t.Fatal("prepare affected current sinks")
+	}
+	u.Commit()
+	if err := h.Write(&Frame{SID: "94C96000C249"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(created["keep"]) != 1 || len(created["keep"][0].frames) != 2 || len(created["change"][2].frames) != 0 ||
+		!created["change"][0].closed || !created["remove"][0].closed || len(created["add"][0].frames) != 1 {
+		t.Fatalf("unexpected sinks after commit %+v", created)
+	}
+}
+
+// blockingSink 写入时阻塞，直到 release 关闭
+type blockingSink struct {
+	memorySink
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSink) Write(f *Frame) error {
+	close(b.entered)
+	<-b.release
+	return nil
+}
+
+func TestRegistryCommitDuringWrite(t *testing.T) {
+	stalled := &blockingSink{entered: make(chan struct{}), release: make(chan struct{})}
+	removed := &memorySink{}
+	h, err := New(
+		WithFactory("blocking", func(c *Config) (Sink, error) { return stalled, nil }),
+		WithFactory("memory", func(c *Config) (Sink, error) { return removed, nil }),
+		WithConfig([]*Config{
+			{Name: "stalled", Type: "blocking", Enable: true, Sensors: []string{"94C96000C248"}},
+			{Name: "remove", Type: "memory", Enable: true},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error, 1)
+	go func() { written <- h.Write(&Frame{SID: "94C96000C248"}) }()
+	<-stalled.entered
+
+	u, err := h.Prepare([]*Config{
+		{Name: "stalled", Type: "blocking", Enable: true, Sensors: []string{"94C96000C248"}},
+	})
+	if err != nil || u == nil {
+		t.Fatalf("prepare %v %v", u, err)
+	}
+	committed := make(chan struct{})
+	go func() {
+		u.Commit()
+		close(committed)
+	}()
+
+	// 替换不等待阻塞的写入，其他传感器的写入不受影响
+	deadline := time.Now().Add(time.Second)
+	for len(h.Status()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("commit blocked by stalled write")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	done := make(chan error, 1)
+	go func() { done <- h.Write(&Frame{SID: "94C96000C249"}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write blocked by stalled sink during commit")
+	}
+
+	// 删除的转发目标等待正在进行的写入完成后关闭
+	select {
+	case <-committed:
+		t.Fatal("removed sink closed during write")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(stalled.release)
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	<-committed
+	if !removed.closed || len(removed.frames) != 1 {
+		t.Fatalf("unexpected removed sink %+v", removed)
+	}
+}
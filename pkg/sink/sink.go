@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+)
+
+// Frame - 转发的数据包
+type Frame struct {
+	ID        uint64 // 传感器编号
+	SID       string // 传感器字符串编号
+	Timestamp int64  // Frame时间戳(毫秒)
+	STypes    []byte // 数据段类型
+	Data      []byte // 原始二进制Frame
+	Arc       []byte // SegmentArc样本数据
+
+	Meta *meta.Metadata   // 传感器元数据，未设置为nil
+	Span opentracing.Span // 采样跟踪，未采样为nil
+}
+
+// Health - 转发目标健康状态
+type Health struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// Sink - 数据转发目标
+type Sink interface {
+	// Write 写入数据包，不应长时间阻塞数据处理协程
+	Write(*Frame) error
+	// Flush 将缓存数据写出
+	Flush() error
+	// Close 写出缓存数据并释放资源
+	Close() error
+	// Health 健康状态
+	Health() Health
+}
+
+// FeatureWriter - 可选接口，转发目标同时接收特征数据
+type FeatureWriter interface {
+	WriteFeature(*feature.Feature) error
+}
+
+// EventWriter - 可选接口，转发目标同时接收事件
+type EventWriter interface {
+	WriteEvent(*event.Event) error
+}
+
+// Reader - 可选接口，转发目标可按时间范围读回数据包
+type Reader interface {
+	// Read 读取传感器 [from, to] 毫秒时间范围内的数据包，按时间排序，Arc需填充
+	Read(sid string, from, to int64) ([]*Frame, error)
+}
+
+// Factory - 根据配置创建转发目标
+type Factory func(*Config) (Sink, error)
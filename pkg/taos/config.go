@@ -0,0 +1,153 @@
+package taos
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyURL           = "taos.url"
+	keyUser          = "taos.user"
+	keyPassword      = "taos.password"
+	keyDatabase      = "taos.database"
+	keyFeatureStable = "taos.feature_stable"
+	keyWaveStable    = "taos.wave_stable"
+	keyWaveEnable    = "taos.wave_enable"
+	keyWaveRate      = "taos.wave_rate"
+	keyBatchSize     = "taos.batch_size"
+	keyMaxBuffer     = "taos.max_buffer"
+	keyTimeout       = "taos.timeout"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	URL:           "http://localhost:6041",
+	User:          "root",
+	Password:      "taosdata",
+	Database:      "arc",
+	FeatureStable: "feature",
+	WaveStable:    "wave",
+	WaveEnable:    false,
+	WaveRate:      100,
+	BatchSize:     1000,
+	MaxBuffer:     100000,
+	Timeout:       5000,
+}
+
+// identifier 库名、超级表名只允许字母数字下划线
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config - TDengine存储配置，[taos] 为默认值，[[sink]] options 可逐项覆盖
+type Config struct {
+	// URL taosAdapter REST地址
+	URL      string `toml:"url" json:"url"`
+	User     string `toml:"user" json:"user"`
+	Password string `toml:"password" json:"-"`
+	Database string `toml:"database" json:"database"`
+	// FeatureStable 特征超级表，子表按传感器自动创建
+	FeatureStable string `toml:"feature_stable" json:"feature_stable"`
+	// WaveStable 降采样波形超级表
+	WaveStable string `toml:"wave_stable" json:"wave_stable"`
+	// WaveEnable 写入降采样波形
+	WaveEnable bool `toml:"wave_enable" json:"wave_enable"`
+	// WaveRate 降采样后每秒点数，每个点记录区间内最小、最大、平均值
+	WaveRate int `toml:"wave_rate" json:"wave_rate"`
+	// BatchSize 单条INSERT语句最大行数，缓存达到后立即写入
+	BatchSize int `toml:"batch_size" json:"batch_size"`
+	// MaxBuffer 最多缓存行数，写入慢或失败时超过后丢弃最早数据
+	MaxBuffer int `toml:"max_buffer" json:"max_buffer"`
+	// Timeout 请求超时(毫秒)
+	Timeout int `toml:"timeout" json:"timeout"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyURL, defaultConfig.URL)
+	viper.SetDefault(keyUser, defaultConfig.User)
+	viper.SetDefault(keyPassword, defaultConfig.Password)
+	viper.SetDefault(keyDatabase, defaultConfig.Database)
+	viper.SetDefault(keyFeatureStable, defaultConfig.FeatureStable)
+	viper.SetDefault(keyWaveStable, defaultConfig.WaveStable)
+	viper.SetDefault(keyWaveEnable, defaultConfig.WaveEnable)
+	viper.SetDefault(keyWaveRate, defaultConfig.WaveRate)
+	viper.SetDefault(keyBatchSize, defaultConfig.BatchSize)
+	viper.SetDefault(keyMaxBuffer, defaultConfig.MaxBuffer)
+	viper.SetDefault(keyTimeout, defaultConfig.Timeout)
+}
+
+// GetConfig - 获取 [taos] 配置
+func GetConfig() *Config {
+	return &Config{
+		URL:           viper.GetString(keyURL),
+		User:          viper.GetString(keyUser),
+		Password:      viper.GetString(keyPassword),
+		Database:      viper.GetString(keyDatabase),
+		FeatureStable: viper.GetString(keyFeatureStable),
+		WaveStable:    viper.GetString(keyWaveStable),
+		WaveEnable:    viper.GetBool(keyWaveEnable),
+		WaveRate:      viper.GetInt(keyWaveRate),
+		BatchSize:     viper.GetInt(keyBatchSize),
+		MaxBuffer:     viper.GetInt(keyMaxBuffer),
+		Timeout:       viper.GetInt(keyTimeout),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [taos] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("%s is empty", keyURL)
+	}
+	for _, id := range []struct {
+		name  string
+		value string
+	}{
+		{keyDatabase, c.Database},
+		{keyFeatureStable, c.FeatureStable},
+		{keyWaveStable, c.WaveStable},
+	} {
+		if !identifier.MatchString(id.value) {
+			return fmt.Errorf("%s invalid identifier %q", id.name, id.value)
+		}
+	}
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{keyWaveRate, c.WaveRate},
+		{keyBatchSize, c.BatchSize},
+		{keyMaxBuffer, c.MaxBuffer},
+		{keyTimeout, c.Timeout},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", p.name, p.value)
+		}
+	}
+	// 时间戳精度为毫秒
+	if c.WaveRate > 1000 {
+		return fmt.Errorf("%s must not exceed 1000, got %d", keyWaveRate, c.WaveRate)
+	}
+	if c.MaxBuffer < c.BatchSize {
+		return fmt.Errorf("%s(%d) less than %s(%d)", keyMaxBuffer, c.MaxBuffer, keyBatchSize, c.BatchSize)
+	}
+	return nil
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,332 @@
+package taos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType TDengine转发目标类型
+const SinkType = "taos"
+
+// tableChar 子表名只保留字母数字
+var tableChar = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// row 一行待写入数据，已转换为SQL片段
+type row struct {
+	table  string // 子表名
+	stable string // 超级表名
+	tags   string // 子表标签
+	values string // (ts, ...)
+}
+
+// response taosAdapter REST返回，兼容2.x(status)与3.x(code)
+type response struct {
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+	Desc   string `json:"desc"`
+}
+
+// Sink - 将特征和降采样波形写入TDengine超级表
+type Sink struct {
+	config *Config
+	sample *sample.Config
+	client *http.Client
+
+	mu      sync.Mutex
+	rows    []row
+	lastErr error
+
+	sendMu sync.Mutex // 保证批次顺序写入
+	ready  bool       // 库和超级表已创建
+
+	flushCh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	written *atomic.Int64
+	dropped *atomic.Int64
+}
+
+// NewSink - 根据转发目标配置创建TDengine存储
+// @param c *sink.Config 转发目标配置，options覆盖 [taos] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, sample.GetConfig())
+}
+
+// New - 创建TDengine存储，库和超级表在首次写入时创建
+// @param cfg *Config TDengine配置
+// @param s *sample.Config 采样格式
+// @return *Sink 转发目标
+// @return err 错误信息
+func New(cfg *Config, s *sample.Config) (*Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	t := &Sink{
+		config:  cfg,
+		sample:  s,
+		client:  &http.Client{Timeout: millisecond(cfg.Timeout)},
+		flushCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		written: atomic.NewInt64(0),
+		dropped: atomic.NewInt64(0),
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t, nil
+}
+
+// run 缓存达到批次大小时写入
+func (t *Sink) run() {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-t.flushCh:
+			_ = t.Flush()
+		}
+	}
+}
+
+// tags 子表标签
+func tags(sid string, id uint64) string {
+	return fmt.Sprintf("('%s', %d)", strings.ReplaceAll(sid, "'", ""), id)
+}
+
+// table 子表名，前缀区分超级表
+func table(prefix, sid string) string {
+	return prefix + "_" + strings.ToLower(tableChar.ReplaceAllString(sid, "_"))
+}
+
+// append 缓存待写入行，达到批次大小时通知写入，写入慢时超过上限丢弃最早数据
+func (t *Sink) append(rows ...row) {
+	t.mu.Lock()
+	t.rows = append(t.rows, rows...)
+	t.trim()
+	n := len(t.rows)
+	t.mu.Unlock()
+	if n >= t.config.BatchSize {
+		select {
+		case t.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Write - 开启波形时写入降采样后的最小、最大、平均值
+func (t *Sink) Write(f *sink.Frame) error {
+	if !t.config.WaveEnable {
+		return nil
+	}
+	samples := sample.Decode(f.Arc, t.sample.BitsPerSample)
+	channels := t.sample.Channels
+	rate := meta.Rate(f.Meta, t.sample.SampleRate)
+	step := rate / t.config.WaveRate
+	if step < 1 {
+		step = 1
+	}
+	interval := 1000 / float64(rate)
+
+	var rows []row
+	for start := 0; start*channels < len(samples); start += step {
+		end := (start + step) * channels
+		if end > len(samples) {
+			end = len(samples)
+		}
+		bucket := samples[start*channels : end]
+		min, max, sum := bucket[0], bucket[0], int64(0)
+		for _, v := range bucket {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+			sum += int64(v)
+		}
+		rows = append(rows, row{
+			table:  table("w", f.SID),
+			stable: t.config.WaveStable,
+			tags:   tags(f.SID, f.ID),
+			values: fmt.Sprintf("(%d, %d, %d, %s)", f.Timestamp+int64(float64(start)*interval), min, max,
+				strconv.FormatFloat(float64(sum)/float64(len(bucket)), 'f', -1, 64)),
+		})
+	}
+	t.append(rows...)
+	return nil
+}
+
+// WriteFeature - 写入特征
+func (t *Sink) WriteFeature(f *feature.Feature) error {
+	t.append(row{
+		table:  table("f", f.SID),
+		stable: t.config.FeatureStable,
+		tags:   tags(f.SID, f.ID),
+		values: fmt.Sprintf("(%d, %d, %d, %d, %s, %s, %d, %s, %d)",
+			f.Timestamp, f.Samples, f.Min, f.Max,
+			strconv.FormatFloat(f.Mean, 'f', -1, 64),
+			strconv.FormatFloat(f.RMS, 'f', -1, 64),
+			f.Peak,
+			strconv.FormatFloat(f.CrestFactor, 'f', -1, 64),
+			f.ZeroCrossings),
+	})
+	return nil
+}
+
+// schema 建库建表语句
+func (t *Sink) schema() []string {
+	db := t.config.Database
+	return []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s PRECISION 'ms'", db),
+		fmt.Sprintf("CREATE STABLE IF NOT EXISTS %s.%s (ts TIMESTAMP, samples INT, min_v INT, max_v INT, mean_v DOUBLE, rms DOUBLE, peak INT, crest_factor DOUBLE, zero_crossings INT) TAGS (sid BINARY(32), sensor_id BIGINT)",
+			db, t.config.FeatureStable),
+		fmt.Sprintf("CREATE STABLE IF NOT EXISTS %s.%s (ts TIMESTAMP, min_v INT, max_v INT, mean_v DOUBLE) TAGS (sid BINARY(32), sensor_id BIGINT)",
+			db, t.config.WaveStable),
+	}
+}
+
+// insert 多表批量写入语句，子表不存在时按超级表自动创建
+func (t *Sink) insert(batch []row) string {
+	// 同一子表合并，保持各子表内时间顺序
+	rows := append([]row(nil), batch...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].table < rows[j].table
+	})
+	db := t.config.Database
+	var b strings.Builder
+	b.WriteString("INSERT INTO")
+	for i, r := range rows {
+		if i == 0 || r.table != rows[i-1].table {
+			fmt.Fprintf(&b, " %s.%s USING %s.%s TAGS %s VALUES", db, r.table, db, r.stable, r.tags)
+		}
+		b.WriteString(" ")
+		b.WriteString(r.values)
+	}
+	return b.String()
+}
+
+// exec 通过REST接口执行SQL
+func (t *Sink) exec(sql string) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(t.config.URL, "/")+"/rest/sql", strings.NewReader(sql))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.config.User, t.config.Password)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("taos http %d: %s", resp.StatusCode, body)
+	}
+	if resp.StatusCode != http.StatusOK || r.Status == "error" || r.Code != 0 {
+		return fmt.Errorf("taos http %d code %d: %s", resp.StatusCode, r.Code, r.Desc)
+	}
+	return nil
+}
+
+// Flush - 按批次写入缓存，失败的数据保留到下次写入
+func (t *Sink) Flush() error {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+
+	t.mu.Lock()
+	rows := t.rows
+	t.rows = nil
+	t.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := t.send(rows)
+	t.mu.Lock()
+	t.lastErr = err
+	t.mu.Unlock()
+	return err
+}
+
+// send 创建库表后分批写入，失败时剩余数据放回缓存
+func (t *Sink) send(rows []row) error {
+	if !t.ready {
+		for _, sql := range t.schema() {
+			if err := t.exec(sql); err != nil {
+				t.requeue(rows)
+				return err
+			}
+		}
+		t.ready = true
+	}
+	for len(rows) > 0 {
+		n := t.config.BatchSize
+		if n > len(rows) {
+			n = len(rows)
+		}
+		if err := t.exec(t.insert(rows[:n])); err != nil {
+			t.requeue(rows)
+			return err
+		}
+		t.written.Add(int64(n))
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// requeue 未写入的数据放回缓存头部，超过上限丢弃最早数据
+func (t *Sink) requeue(rows []row) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = append(rows, t.rows...)
+	t.trim()
+}
+
+// trim 缓存超过上限时丢弃最早数据，调用方持有锁
+func (t *Sink) trim() {
+	if over := len(t.rows) - t.config.MaxBuffer; over > 0 {
+		t.rows = t.rows[over:]
+		t.dropped.Add(int64(over))
+	}
+}
+
+// Close - 写入剩余缓存
+func (t *Sink) Close() error {
+	close(t.done)
+	t.wg.Wait()
+	return t.Flush()
+}
+
+// Health - 最近一次写入成功时健康
+func (t *Sink) Health() sink.Health {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastErr != nil {
+		return sink.Health{Healthy: false, Message: fmt.Sprintf("%s, buffered %d, dropped %d", t.lastErr.Error(), len(t.rows), t.dropped.Load())}
+	}
+	return sink.Health{Healthy: true, Message: fmt.Sprintf("written %d, buffered %d, dropped %d", t.written.Load(), len(t.rows), t.dropped.Load())}
+}
@@ -0,0 +1,180 @@
+package taos
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kiga-hub/arc-consumer/pkg/feature"
+	"github.com/kiga-hub/arc-consumer/pkg/meta"
+	"github.com/kiga-hub/arc-consumer/pkg/sample"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// stub taosAdapter REST接口，记录收到的SQL
+type stub struct {
+	mu   sync.Mutex
+	sqls []string
+	fail bool
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, _ := r.BasicAuth()
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path != "/rest/sql" || user != "root" || password != "taosdata" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":65535,"desc":"unauthorized"}`))
+		return
+	}
+	if s.fail {
+		_, _ = w.Write([]byte(`{"code":9750,"desc":"table does not exist"}`))
+		return
+	}
+	s.sqls = append(s.sqls, string(body))
+	_, _ = w.Write([]byte(`{"code":0,"column_meta":[["affected_rows","INT",4]],"data":[[1]],"rows":1}`))
+}
+
+func (s *stub) statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sqls...)
+}
+
+func newSink(t *testing.T, st *stub) (*Sink, *httptest.Server) {
+	srv := httptest.NewServer(st)
+	cfg := defaultConfig
+	cfg.URL = srv.URL
+	cfg.WaveEnable = true
+	cfg.WaveRate = 2
+	s, err := New(&cfg, &sample.Config{SampleRate: 4, BitsPerSample: 16, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, srv
+}
+
+func TestSinkInsert(t *testing.T) {
+	st := &stub{}
+	s, srv := newSink(t, st)
+	defer srv.Close()
+
+	arc, _ := sample.Encode([]int32{1, 3, -2, 6}, 16)
+	if err := s.Write(&sink.Frame{ID: 1, SID: "94C96000C248", Timestamp: 1000, Arc: arc}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteFeature(&feature.Feature{ID: 2, SID: "94C96000C249", Timestamp: 1000, Samples: 4, RMS: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sqls := st.statements()
+	if len(sqls) != 4 {
+		t.Fatalf("got %d statements: %q", len(sqls), sqls)
+	}
+	if !strings.HasPrefix(sqls[0], "CREATE DATABASE IF NOT EXISTS arc") ||
+		!strings.Contains(sqls[1], "arc.feature") || !strings.Contains(sqls[2], "arc.wave") {
+		t.Fatalf("unexpected schema %q", sqls[:3])
+	}
+	want := "INSERT INTO" +
+		" arc.f_94c96000c249 USING arc.feature TAGS ('94C96000C249', 2) VALUES (1000, 4, 0, 0, 0, 1.5, 0, 0, 0)" +
+		" arc.w_94c96000c248 USING arc.wave TAGS ('94C96000C248', 1) VALUES (1000, 1, 3, 2) (1500, -2, 6, 2)"
+	if sqls[3] != want {
+		t.Fatalf("got  %s\nwant %s", sqls[3], want)
+	}
+}
+
+func TestSinkMetaRate(t *testing.T) {
+	st := &stub{}
+	s, srv := newSink(t, st)
+	defer srv.Close()
+
+	// 元数据采样率8，波形2点/秒，每4个样本一行，间隔125毫秒
+	arc, _ := sample.Encode([]int32{1, 3, -2, 6, 4, 4, 4, 4}, 16)
+	f := &sink.Frame{ID: 1, SID: "94C96000C248", Timestamp: 1000, Arc: arc, Meta: &meta.Metadata{SID: "94C96000C248", SampleRate: 8}}
+	if err := s.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := st.statements()
+	want := "INSERT INTO arc.w_94c96000c248 USING arc.wave TAGS ('94C96000C248', 1) VALUES (1000, -2, 6, 2) (1500, 4, 4, 4)"
+	if len(sqls) == 0 || sqls[len(sqls)-1] != want {
+		t.Fatalf("got %q\nwant %s", sqls, want)
+	}
+}
+
+func TestSinkRetry(t *testing.T) {
+	st := &stub{fail: true}
+	s, srv := newSink(t, st)
+	defer srv.Close()
+
+	if err := s.WriteFeature(&feature.Feature{SID: "94C96000C248", Timestamp: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+	if h := s.Health(); h.Healthy {
+		t.Fatal("expected unhealthy")
+	}
+
+	// 恢复后保留的数据重新写入
+	st.mu.Lock()
+	st.fail = false
+	st.mu.Unlock()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := st.statements()
+	if len(sqls) != 4 || !strings.Contains(sqls[3], "arc.f_94c96000c248") {
+		t.Fatalf("unexpected statements %q", sqls)
+	}
+	if h := s.Health(); !h.Healthy {
+		t.Fatalf("expected healthy: %s", h.Message)
+	}
+}
+
+func TestSinkMaxBuffer(t *testing.T) {
+	st := &stub{}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+	cfg := defaultConfig
+	cfg.URL = srv.URL
+	cfg.BatchSize = 2
+	cfg.MaxBuffer = 3
+	s, err := New(&cfg, &sample.Config{SampleRate: 4, BitsPerSample: 16, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入进行中时继续缓存，超过上限丢弃最早数据
+	s.sendMu.Lock()
+	for i := int64(0); i < 5; i++ {
+		if err := s.WriteFeature(&feature.Feature{SID: "94C96000C248", Timestamp: 1000 + i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.mu.Lock()
+	buffered := len(s.rows)
+	s.mu.Unlock()
+	if buffered != 3 || s.dropped.Load() != 2 {
+		t.Fatalf("buffered %d dropped %d, want 3/2", buffered, s.dropped.Load())
+	}
+	s.sendMu.Unlock()
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := strings.Join(st.statements(), "\n")
+	if strings.Contains(sqls, "(1001,") || !strings.Contains(sqls, "(1002,") || !strings.Contains(sqls, "(1004,") || s.written.Load() != 3 {
+		t.Fatalf("unexpected statements %q", sqls)
+	}
+}
@@ -0,0 +1,133 @@
+package tcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyFraming          = "tcp.framing"
+	keyDialTimeout      = "tcp.dial_timeout"
+	keyWriteTimeout     = "tcp.write_timeout"
+	keyBufferSize       = "tcp.buffer_size"
+	keyBackoffBaseDelay = "tcp.backoff_base_delay"
+	keyBackoffMaxDelay  = "tcp.backoff_max_delay"
+)
+
+const (
+	// FramingFrame 原样转发Frame，与设备上报格式一致
+	FramingFrame = "frame"
+	// FramingLength 4字节大端长度 + Frame
+	FramingLength = "length"
+	// FramingArc 4字节大端长度 + SegmentArc样本数据
+	FramingArc = "arc"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Framing:          FramingFrame,
+	DialTimeout:      3000,
+	WriteTimeout:     3000,
+	BufferSize:       10240,
+	BackoffBaseDelay: 1000,
+	BackoffMaxDelay:  30000,
+}
+
+// Endpoint - 下游地址
+type Endpoint struct {
+	Addr string `toml:"addr" json:"addr"`
+	// Sensors 传感器编号过滤，为空不过滤
+	Sensors []string `toml:"sensors" json:"sensors,omitempty"`
+}
+
+// Config - tcp转发配置，[tcp] 为默认值，[[sink]] options 可逐项覆盖
+type Config struct {
+	Endpoints []Endpoint `toml:"endpoints" json:"endpoints"`
+	// Framing 转发格式: frame, length, arc
+	Framing string `toml:"framing" json:"framing"`
+	// DialTimeout 建立连接超时(毫秒)
+	DialTimeout int `toml:"dial_timeout" json:"dial_timeout"`
+	// WriteTimeout 单次写入超时(毫秒)
+	WriteTimeout int `toml:"write_timeout" json:"write_timeout"`
+	// BufferSize 每个下游缓存的数据包数量，满时丢弃
+	BufferSize int `toml:"buffer_size" json:"buffer_size"`
+	// BackoffBaseDelay 首次重连等待(毫秒)，每次失败翻倍
+	BackoffBaseDelay int `toml:"backoff_base_delay" json:"backoff_base_delay"`
+	// BackoffMaxDelay 重连最大等待(毫秒)
+	BackoffMaxDelay int `toml:"backoff_max_delay" json:"backoff_max_delay"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyFraming, defaultConfig.Framing)
+	viper.SetDefault(keyDialTimeout, defaultConfig.DialTimeout)
+	viper.SetDefault(keyWriteTimeout, defaultConfig.WriteTimeout)
+	viper.SetDefault(keyBufferSize, defaultConfig.BufferSize)
+	viper.SetDefault(keyBackoffBaseDelay, defaultConfig.BackoffBaseDelay)
+	viper.SetDefault(keyBackoffMaxDelay, defaultConfig.BackoffMaxDelay)
+}
+
+// GetConfig - 获取 [tcp] 配置
+func GetConfig() *Config {
+	return &Config{
+		Framing:          viper.GetString(keyFraming),
+		DialTimeout:      viper.GetInt(keyDialTimeout),
+		WriteTimeout:     viper.GetInt(keyWriteTimeout),
+		BufferSize:       viper.GetInt(keyBufferSize),
+		BackoffBaseDelay: viper.GetInt(keyBackoffBaseDelay),
+		BackoffMaxDelay:  viper.GetInt(keyBackoffMaxDelay),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [tcp] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("tcp endpoints is empty")
+	}
+	for _, e := range c.Endpoints {
+		if e.Addr == "" {
+			return fmt.Errorf("tcp endpoint addr is empty")
+		}
+	}
+	switch c.Framing {
+	case FramingFrame, FramingLength, FramingArc:
+	default:
+		return fmt.Errorf("unsupported tcp framing %q", c.Framing)
+	}
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{keyDialTimeout, c.DialTimeout},
+		{keyWriteTimeout, c.WriteTimeout},
+		{keyBufferSize, c.BufferSize},
+		{keyBackoffBaseDelay, c.BackoffBaseDelay},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", p.name, p.value)
+		}
+	}
+	if c.BackoffMaxDelay < c.BackoffBaseDelay {
+		return fmt.Errorf("%s(%d) less than %s(%d)", keyBackoffMaxDelay, c.BackoffMaxDelay, keyBackoffBaseDelay, c.BackoffBaseDelay)
+	}
+	return nil
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
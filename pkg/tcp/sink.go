@@ -0,0 +1,269 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/backoff"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType tcp转发目标类型
+const SinkType = "tcp"
+
+// errQueueFull 下游缓存已满，丢弃数据
+var errQueueFull = errors.New("tcp endpoint buffer full")
+
+// errClosedByPeer 下游关闭连接
+var errClosedByPeer = errors.New("connection closed by peer")
+
+// endpoint 单个下游连接
+type endpoint struct {
+	addr    string
+	filter  *sink.Filter
+	queue   chan []byte
+	config  *Config
+	done    chan struct{}
+	online  *atomic.Bool
+	sent    *atomic.Int64
+	dropped *atomic.Int64
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// Sink - 将数据包转发到多个下游tcp地址
+type Sink struct {
+	config    *Config
+	endpoints []*endpoint
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewSink - 根据转发目标配置创建tcp转发
+// @param c *sink.Config 转发目标配置，options覆盖 [tcp] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// New - 创建tcp转发目标，后台连接各下游地址
+// @param cfg *Config tcp配置
+// @return *Sink 转发目标
+// @return err 错误信息
+func New(cfg *Config) (*Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		config: cfg,
+		done:   make(chan struct{}),
+	}
+	for _, e := range cfg.Endpoints {
+		ep := &endpoint{
+			addr:    e.Addr,
+			filter:  sink.NewFilter(e.Sensors, nil),
+			queue:   make(chan []byte, cfg.BufferSize),
+			config:  cfg,
+			done:    s.done,
+			online:  atomic.NewBool(false),
+			sent:    atomic.NewInt64(0),
+			dropped: atomic.NewInt64(0),
+		}
+		s.endpoints = append(s.endpoints, ep)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			ep.run()
+		}()
+	}
+	return s, nil
+}
+
+// encode 按转发格式打包
+func (s *Sink) encode(f *sink.Frame) []byte {
+	switch s.config.Framing {
+	case FramingLength:
+		return withLength(f.Data)
+	case FramingArc:
+		return withLength(f.Arc)
+	}
+	return f.Data
+}
+
+// withLength 4字节大端长度前缀
+func withLength(data []byte) []byte {
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	return buf
+}
+
+// Write - 数据包放入匹配下游的缓存，缓存满时丢弃
+func (s *Sink) Write(f *sink.Frame) error {
+	var data []byte
+	var full []string
+	for _, ep := range s.endpoints {
+		if !ep.filter.Match(f) {
+			continue
+		}
+		if data == nil {
+			data = s.encode(f)
+		}
+		select {
+		case ep.queue <- data:
+		default:
+			ep.dropped.Inc()
+			full = append(full, ep.addr)
+		}
+	}
+	if len(full) > 0 {
+		return fmt.Errorf("%w: %s", errQueueFull, strings.Join(full, ","))
+	}
+	return nil
+}
+
+// Flush - 后台协程持续写出
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Close - 写出已连接下游的缓存后断开
+func (s *Sink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// Health - 全部下游已连接时健康
+func (s *Sink) Health() sink.Health {
+	var offline []string
+	for _, ep := range s.endpoints {
+		if ep.online.Load() {
+			continue
+		}
+		msg := ep.addr
+		ep.mu.Lock()
+		if ep.lastErr != nil {
+			msg += ": " + ep.lastErr.Error()
+		}
+		ep.mu.Unlock()
+		offline = append(offline, msg)
+	}
+	if len(offline) > 0 {
+		return sink.Health{Healthy: false, Message: strings.Join(offline, "; ")}
+	}
+	return sink.Health{Healthy: true}
+}
+
+// fail 记录错误
+func (ep *endpoint) fail(err error) {
+	ep.online.Store(false)
+	ep.mu.Lock()
+	ep.lastErr = err
+	ep.mu.Unlock()
+}
+
+// run 连接下游并写出缓存，失败后退避重连，未写出的数据包重连后重发
+func (ep *endpoint) run() {
+	var conn net.Conn
+	var broken chan struct{}
+	var pending []byte
+	attempt := 0
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+		ep.online.Store(false)
+	}()
+
+	for {
+		if conn == nil {
+			c, err := net.DialTimeout("tcp", ep.addr, millisecond(ep.config.DialTimeout))
+			if err != nil {
+				ep.fail(err)
+				d := backoff.Delay(millisecond(ep.config.BackoffBaseDelay), millisecond(ep.config.BackoffMaxDelay), attempt)
+				select {
+				case <-ep.done:
+					return
+				case <-time.After(d):
+				}
+				attempt++
+				continue
+			}
+			conn = c
+			attempt = 0
+			ep.online.Store(true)
+			// 下游只接收数据，读到关闭或错误时重连
+			broken = make(chan struct{})
+			go func(c net.Conn, broken chan struct{}) {
+				io.Copy(io.Discard, c)
+				close(broken)
+			}(c, broken)
+		}
+
+		if pending == nil {
+			select {
+			case pending = <-ep.queue:
+			case <-broken:
+				ep.fail(errClosedByPeer)
+				conn.Close()
+				conn = nil
+				continue
+			case <-ep.done:
+				ep.drain(conn)
+				return
+			}
+		}
+		if err := ep.write(conn, pending); err != nil {
+			ep.fail(err)
+			conn.Close()
+			conn = nil
+			continue
+		}
+		pending = nil
+	}
+}
+
+// write 带超时写入
+func (ep *endpoint) write(conn net.Conn, data []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(millisecond(ep.config.WriteTimeout))); err != nil {
+		return err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	ep.sent.Inc()
+	return nil
+}
+
+// drain 关闭前写出剩余缓存，写入失败放弃
+func (ep *endpoint) drain(conn net.Conn) {
+	for {
+		select {
+		case data := <-ep.queue:
+			if err := ep.write(conn, data); err != nil {
+				ep.fail(err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
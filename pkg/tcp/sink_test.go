@@ -0,0 +1,147 @@
+package tcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+func testConfig(endpoints ...Endpoint) *Config {
+	cfg := defaultConfig
+	cfg.Endpoints = endpoints
+	cfg.BackoffBaseDelay = 10
+	cfg.BackoffMaxDelay = 50
+	return &cfg
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// readLength 读取长度前缀数据包
+func readLength(t *testing.T, conn net.Conn) []byte {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(head))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func accept(t *testing.T, l net.Listener) net.Conn {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestSinkFilterAndFraming(t *testing.T) {
+	all, one := listen(t), listen(t)
+	defer all.Close()
+	defer one.Close()
+
+	cfg := testConfig(
+		Endpoint{Addr: all.Addr().String()},
+		Endpoint{Addr: one.Addr().String(), Sensors: []string{"94c96000c249"}},
+	)
+	cfg.Framing = FramingLength
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	allConn, oneConn := accept(t, all), accept(t, one)
+	defer allConn.Close()
+	defer oneConn.Close()
+
+	frames := []*sink.Frame{
+		{SID: "94C96000C248", Data: []byte{1, 2, 3}},
+		{SID: "94C96000C249", Data: []byte{4, 5}},
+	}
+	for _, f := range frames {
+		if err := s.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, f := range frames {
+		if got := readLength(t, allConn); !bytes.Equal(got, f.Data) {
+			t.Fatalf("all got %v, want %v", got, f.Data)
+		}
+	}
+	if got := readLength(t, oneConn); !bytes.Equal(got, frames[1].Data) {
+		t.Fatalf("filtered got %v, want %v", got, frames[1].Data)
+	}
+}
+
+func TestSinkReconnect(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+
+	s, err := New(testConfig(Endpoint{Addr: l.Addr().String()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 下游断开后自动重连，继续转发
+	accept(t, l).Close()
+	conn := accept(t, l)
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Health().Healthy && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	frame := &sink.Frame{SID: "94C96000C248", Data: []byte{0xFC, 0xFC, 0xFC, 0xFC}}
+	if err := s.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got := make([]byte, len(frame.Data))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, frame.Data) {
+		t.Fatalf("got %v, want %v", got, frame.Data)
+	}
+}
+
+func TestSinkBufferFull(t *testing.T) {
+	cfg := testConfig(Endpoint{Addr: "127.0.0.1:1"})
+	cfg.BufferSize = 1
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	frame := &sink.Frame{SID: "94C96000C248", Data: []byte{1}}
+	var full bool
+	for i := 0; i < 3; i++ {
+		if err := s.Write(frame); err != nil {
+			full = true
+		}
+	}
+	if !full {
+		t.Fatal("expected buffer full error")
+	}
+	if s.Health().Healthy {
+		t.Fatal("expected unhealthy without connection")
+	}
+}
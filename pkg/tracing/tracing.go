@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"fmt"
+	"math/rand"
+
+	arcTracing "github.com/kiga-hub/arc/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/spf13/viper"
+)
+
+const (
+	keyFrameSampleRate = "trace.frame_sample_rate"
+)
+
+const (
+	// TagSID 传感器字符串编号
+	TagSID = "sensor.sid"
+	// TagShard 数据处理协程
+	TagShard = "frame.shard"
+	// TagTimestamp Frame时间戳(毫秒)
+	TagTimestamp = "frame.timestamp"
+)
+
+// 配置默认值
+var defaultConfig = Config{
+	FrameSampleRate: 0.001,
+}
+
+// Config - 数据包跟踪配置，跟踪上报地址使用 [trace] 配置
+type Config struct {
+	// FrameSampleRate 数据包采样比例 0~1，0 不跟踪
+	FrameSampleRate float64 `toml:"frame_sample_rate" json:"frame_sample_rate"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyFrameSampleRate, defaultConfig.FrameSampleRate)
+}
+
+// GetConfig - 获取配置
+func GetConfig() *Config {
+	return &Config{
+		FrameSampleRate: viper.GetFloat64(keyFrameSampleRate),
+	}
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if c.FrameSampleRate < 0 || c.FrameSampleRate > 1 {
+		return fmt.Errorf("%s must be in [0, 1], got %v", keyFrameSampleRate, c.FrameSampleRate)
+	}
+	return nil
+}
+
+// Sampler - 按比例为数据包创建跟踪
+type Sampler struct {
+	tracer opentracing.Tracer
+	rate   float64
+}
+
+// New - 创建数据包采样，未配置跟踪或采样比例为0时返回nil
+// @param tracer opentracing.Tracer 跟踪上报
+// @param c *Config 配置
+// @return *Sampler 采样
+func New(tracer opentracing.Tracer, c *Config) *Sampler {
+	if tracer == nil || c.FrameSampleRate <= 0 {
+		return nil
+	}
+	if _, ok := tracer.(opentracing.NoopTracer); ok {
+		return nil
+	}
+	return &Sampler{tracer: tracer, rate: c.FrameSampleRate}
+}
+
+// Tracer - 跟踪上报，nil 采样返回nil
+func (s *Sampler) Tracer() opentracing.Tracer {
+	if s == nil {
+		return nil
+	}
+	return s.tracer
+}
+
+// StartFrame - 接收数据包时按比例创建根跟踪，未采样返回nil
+// @param sid string 传感器字符串编号
+// @return opentracing.Span 跟踪
+func (s *Sampler) StartFrame(sid string) opentracing.Span {
+	if s == nil || rand.Float64() >= s.rate {
+		return nil
+	}
+	return s.tracer.StartSpan("frame", ext.SpanKindConsumer, opentracing.Tag{Key: TagSID, Value: sid})
+}
+
+// StartChild - 创建子跟踪，上级为nil时返回nil
+// @param parent opentracing.Span 上级跟踪
+// @param name string 操作名
+// @param opts ...opentracing.StartSpanOption 跟踪参数
+// @return opentracing.Span 子跟踪
+func StartChild(parent opentracing.Span, name string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	if parent == nil {
+		return nil
+	}
+	// 上级引用放在最前，存在其他引用时仍以上级为父跟踪
+	opts = append([]opentracing.StartSpanOption{opentracing.ChildOf(parent.Context())}, opts...)
+	return parent.Tracer().StartSpan(name, opts...)
+}
+
+// Finish - 记录错误并结束跟踪，跟踪为nil时忽略
+func Finish(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	arcTracing.ErrorToSpan(span, err)
+	span.Finish()
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestSamplerDisabled(t *testing.T) {
+	if s := New(nil, &Config{FrameSampleRate: 1}); s != nil {
+		t.Fatal("nil tracer should disable sampling")
+	}
+	if s := New(opentracing.NoopTracer{}, &Config{FrameSampleRate: 1}); s != nil {
+		t.Fatal("noop tracer should disable sampling")
+	}
+	if s := New(mocktracer.New(), &Config{FrameSampleRate: 0}); s != nil {
+		t.Fatal("zero rate should disable sampling")
+	}
+
+	var s *Sampler
+	if s.Tracer() != nil || s.StartFrame("A1") != nil {
+		t.Fatal("nil sampler should not trace")
+	}
+	if StartChild(nil, "child") != nil {
+		t.Fatal("nil parent should not trace")
+	}
+	Finish(nil, errors.New("ignored"))
+
+	if err := (&Config{FrameSampleRate: 1.5}).Validate(); err == nil {
+		t.Fatal("rate above 1 should be invalid")
+	}
+}
+
+func TestSamplerFrame(t *testing.T) {
+	tracer := mocktracer.New()
+	s := New(tracer, &Config{FrameSampleRate: 1})
+
+	root := s.StartFrame("A1")
+	if root == nil {
+		t.Fatal("frame not sampled")
+	}
+	child := StartChild(root, "frame.decode")
+	Finish(child, errors.New("bad frame"))
+	Finish(root, nil)
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("finished spans %d", len(spans))
+	}
+	decode, frame := spans[0], spans[1]
+	if frame.OperationName != "frame" || frame.Tag(TagSID) != "A1" {
+		t.Fatalf("unexpected root span %s %v", frame.OperationName, frame.Tags())
+	}
+	if decode.ParentID != frame.SpanContext.SpanID || decode.Tag("error") != true {
+		t.Fatalf("unexpected child span %+v", decode.Tags())
+	}
+}
@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+const (
+	keyTypes            = "webhook.types"
+	keyTemplate         = "webhook.template"
+	keySecret           = "webhook.secret"
+	keyTimeout          = "webhook.timeout"
+	keyRetryMax         = "webhook.retry_max"
+	keyBackoffBaseDelay = "webhook.backoff_base_delay"
+	keyBackoffMaxDelay  = "webhook.backoff_max_delay"
+	keyBufferSize       = "webhook.buffer_size"
+	keyRate             = "webhook.rate"
+	keyBurst            = "webhook.burst"
+	keyDeadLetter       = "webhook.dead_letter"
+)
+
+// 配置默认值，时间单位为毫秒
+var defaultConfig = Config{
+	Types:            []string{"arc"},
+	Template:         "",
+	Secret:           "",
+	Timeout:          5000,
+	RetryMax:         5,
+	BackoffBaseDelay: 1000,
+	BackoffMaxDelay:  30000,
+	BufferSize:       1024,
+	Rate:             0.2,
+	Burst:            5,
+	DeadLetter:       "./data/webhook-dead-letter.jsonl",
+}
+
+// Endpoint - 通知地址
+type Endpoint struct {
+	URL string `toml:"url" json:"url"`
+	// Sensors 传感器编号过滤，为空不过滤
+	Sensors []string `toml:"sensors" json:"sensors,omitempty"`
+	// Template 请求体模板，为空使用 [webhook] template
+	Template string `toml:"template" json:"template,omitempty"`
+}
+
+// Config - webhook通知配置，[webhook] 为默认值，[[sink]] options 可逐项覆盖
+type Config struct {
+	Endpoints []Endpoint `toml:"endpoints" json:"endpoints"`
+	// Types 通知的事件类型，为空全部通知
+	Types []string `toml:"types" json:"types"`
+	// Template 请求体模板(text/template)，数据为事件，可用 {{json .}}，为空发送事件JSON
+	Template string `toml:"template" json:"template,omitempty"`
+	// Secret HMAC-SHA256签名密钥，为空不签名
+	Secret string `toml:"secret" json:"-"`
+	// Timeout 单次请求超时(毫秒)
+	Timeout int `toml:"timeout" json:"timeout"`
+	// RetryMax 网络错误、5xx和429最多重试次数
+	RetryMax int `toml:"retry_max" json:"retry_max"`
+	// BackoffBaseDelay 首次重试等待(毫秒)，每次失败翻倍
+	BackoffBaseDelay int `toml:"backoff_base_delay" json:"backoff_base_delay"`
+	// BackoffMaxDelay 重试最大等待(毫秒)
+	BackoffMaxDelay int `toml:"backoff_max_delay" json:"backoff_max_delay"`
+	// BufferSize 每个地址待发送通知数量，满时写入死信
+	BufferSize int `toml:"buffer_size" json:"buffer_size"`
+	// Rate 每个传感器每秒通知数，0 不限制
+	Rate float64 `toml:"rate" json:"rate"`
+	// Burst 每个传感器突发通知数
+	Burst int `toml:"burst" json:"burst"`
+	// DeadLetter 无法送达的通知追加写入的文件(JSON Lines)，为空丢弃
+	DeadLetter string `toml:"dead_letter" json:"dead_letter"`
+}
+
+// SetDefaultConfig - 设置默认配置
+func SetDefaultConfig() {
+	viper.SetDefault(keyTypes, defaultConfig.Types)
+	viper.SetDefault(keyTemplate, defaultConfig.Template)
+	viper.SetDefault(keySecret, defaultConfig.Secret)
+	viper.SetDefault(keyTimeout, defaultConfig.Timeout)
+	viper.SetDefault(keyRetryMax, defaultConfig.RetryMax)
+	viper.SetDefault(keyBackoffBaseDelay, defaultConfig.BackoffBaseDelay)
+	viper.SetDefault(keyBackoffMaxDelay, defaultConfig.BackoffMaxDelay)
+	viper.SetDefault(keyBufferSize, defaultConfig.BufferSize)
+	viper.SetDefault(keyRate, defaultConfig.Rate)
+	viper.SetDefault(keyBurst, defaultConfig.Burst)
+	viper.SetDefault(keyDeadLetter, defaultConfig.DeadLetter)
+}
+
+// GetConfig - 获取 [webhook] 配置
+func GetConfig() *Config {
+	return &Config{
+		Types:            viper.GetStringSlice(keyTypes),
+		Template:         viper.GetString(keyTemplate),
+		Secret:           viper.GetString(keySecret),
+		Timeout:          viper.GetInt(keyTimeout),
+		RetryMax:         viper.GetInt(keyRetryMax),
+		BackoffBaseDelay: viper.GetInt(keyBackoffBaseDelay),
+		BackoffMaxDelay:  viper.GetInt(keyBackoffMaxDelay),
+		BufferSize:       viper.GetInt(keyBufferSize),
+		Rate:             viper.GetFloat64(keyRate),
+		Burst:            viper.GetInt(keyBurst),
+		DeadLetter:       viper.GetString(keyDeadLetter),
+	}
+}
+
+// getSinkConfig 转发目标参数覆盖 [webhook] 默认值
+func getSinkConfig(c *sink.Config) (*Config, error) {
+	cfg := GetConfig()
+	if err := sink.DecodeOptions(c.Options, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 检查配置
+func (c *Config) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("webhook endpoints is empty")
+	}
+	for _, e := range c.Endpoints {
+		if e.URL == "" {
+			return fmt.Errorf("webhook endpoint url is empty")
+		}
+		if _, err := parseTemplate(e.Template); err != nil {
+			return fmt.Errorf("webhook endpoint %s template: %w", e.URL, err)
+		}
+	}
+	if _, err := parseTemplate(c.Template); err != nil {
+		return fmt.Errorf("%s: %w", keyTemplate, err)
+	}
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{keyTimeout, c.Timeout},
+		{keyBufferSize, c.BufferSize},
+		{keyBackoffBaseDelay, c.BackoffBaseDelay},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", p.name, p.value)
+		}
+	}
+	if c.RetryMax < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", keyRetryMax, c.RetryMax)
+	}
+	if c.BackoffMaxDelay < c.BackoffBaseDelay {
+		return fmt.Errorf("%s(%d) less than %s(%d)", keyBackoffMaxDelay, c.BackoffMaxDelay, keyBackoffBaseDelay, c.BackoffBaseDelay)
+	}
+	if c.Rate < 0 {
+		return fmt.Errorf("%s must not be negative, got %v", keyRate, c.Rate)
+	}
+	if c.Rate > 0 && c.Burst <= 0 {
+		return fmt.Errorf("%s must be positive when %s is set, got %d", keyBurst, keyRate, c.Burst)
+	}
+	return nil
+}
+
+// parseTemplate 解析请求体模板，为空返回nil
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+}
+
+// millisecond 毫秒配置转换为时间
+func millisecond(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record - 无法送达的通知
+type Record struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url"`
+	Type     string    `json:"type"`
+	SID      string    `json:"sid,omitempty"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Body     string    `json:"body"`
+}
+
+// deadLetter 死信文件，按行追加JSON
+type deadLetter struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// write 追加一条记录，首次写入时创建文件
+func (d *deadLetter) write(r *Record) error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+			return err
+		}
+		if d.file, err = os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return err
+		}
+	}
+	_, err = d.file.Write(append(data, '\n'))
+	return err
+}
+
+// close 关闭文件
+func (d *deadLetter) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
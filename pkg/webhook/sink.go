@@ -0,0 +1,369 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	"github.com/kiga-hub/arc-consumer/pkg/backoff"
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+	"github.com/kiga-hub/arc-consumer/pkg/sink"
+)
+
+// SinkType webhook通知转发目标类型
+const SinkType = "webhook"
+
+const (
+	// HeaderEvent 事件类型
+	HeaderEvent = "X-Arc-Event"
+	// HeaderTimestamp 签名时间(秒)
+	HeaderTimestamp = "X-Arc-Timestamp"
+	// HeaderSignature 签名 sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+	HeaderSignature = "X-Arc-Signature"
+)
+
+// errQueueFull 通知队列已满
+var errQueueFull = errors.New("webhook queue full")
+
+// errClosed 转发目标已关闭
+var errClosed = errors.New("webhook sink closed")
+
+// notification 待发送通知
+type notification struct {
+	event *event.Event
+	body  []byte
+}
+
+// endpoint 单个通知地址
+type endpoint struct {
+	url      string
+	filter   *sink.Filter
+	template *template.Template
+	queue    chan *notification
+	sent     *atomic.Int64
+	failed   *atomic.Int64
+
+	mu          sync.Mutex
+	lastErr     error
+	lastErrAt   time.Time
+	lastSuccess time.Time
+}
+
+// Sink - 将事件通过HTTP POST通知到多个地址，失败重试，无法送达写入死信
+type Sink struct {
+	config    *Config
+	client    *http.Client
+	endpoints []*endpoint
+	types     map[string]struct{}
+	dead      *deadLetter
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	queueMu sync.RWMutex // 保护关闭，关闭后不再入队
+	closed  bool
+
+	limitMu  sync.Mutex
+	limiters map[string]*rate.Limiter // 传感器 -> 限流
+
+	limited *atomic.Int64
+}
+
+// NewSink - 根据转发目标配置创建webhook通知
+// @param c *sink.Config 转发目标配置，options覆盖 [webhook] 默认值
+// @return sink.Sink 转发目标
+// @return err 错误信息
+func NewSink(c *sink.Config) (sink.Sink, error) {
+	cfg, err := getSinkConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// New - 创建webhook通知，每个地址一个发送协程
+// @param cfg *Config webhook配置
+// @return *Sink 转发目标
+// @return err 错误信息
+func New(cfg *Config) (*Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	tmpl, _ := parseTemplate(cfg.Template)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sink{
+		config:   cfg,
+		client:   &http.Client{Timeout: millisecond(cfg.Timeout)},
+		types:    map[string]struct{}{},
+		dead:     &deadLetter{path: cfg.DeadLetter},
+		ctx:      ctx,
+		cancel:   cancel,
+		limiters: map[string]*rate.Limiter{},
+		limited:  atomic.NewInt64(0),
+	}
+	for _, typ := range cfg.Types {
+		s.types[typ] = struct{}{}
+	}
+	for _, e := range cfg.Endpoints {
+		ep := &endpoint{
+			url:      e.URL,
+			filter:   sink.NewFilter(e.Sensors, nil),
+			template: tmpl,
+			queue:    make(chan *notification, cfg.BufferSize),
+			sent:     atomic.NewInt64(0),
+			failed:   atomic.NewInt64(0),
+		}
+		if e.Template != "" {
+			ep.template, _ = parseTemplate(e.Template)
+		}
+		s.endpoints = append(s.endpoints, ep)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.run(ep)
+		}()
+	}
+	return s, nil
+}
+
+// Write - 原始数据包不通知
+func (s *Sink) Write(*sink.Frame) error {
+	return nil
+}
+
+// WriteEvent - 按类型、传感器限流后放入各地址队列
+func (s *Sink) WriteEvent(ev *event.Event) error {
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	if s.closed {
+		return errClosed
+	}
+	if len(s.types) > 0 {
+		if _, ok := s.types[ev.Type]; !ok {
+			return nil
+		}
+	}
+	if !s.allow(ev.SID) {
+		s.limited.Inc()
+		return nil
+	}
+
+	var full []string
+	for _, ep := range s.endpoints {
+		if ev.SID != "" && !ep.filter.MatchSensor(ev.SID) {
+			continue
+		}
+		body, err := render(ep.template, ev)
+		if err != nil {
+			s.deadLetter(ep, ev, body, 0, err)
+			continue
+		}
+		select {
+		case ep.queue <- &notification{event: ev, body: body}:
+		default:
+			s.deadLetter(ep, ev, body, 0, errQueueFull)
+			full = append(full, ep.url)
+		}
+	}
+	if len(full) > 0 {
+		return fmt.Errorf("%w: %s", errQueueFull, strings.Join(full, ","))
+	}
+	return nil
+}
+
+// allow 传感器令牌桶限流，防止频繁跳变的传感器刷屏
+func (s *Sink) allow(sid string) bool {
+	if s.config.Rate <= 0 {
+		return true
+	}
+	s.limitMu.Lock()
+	defer s.limitMu.Unlock()
+	l, ok := s.limiters[sid]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(s.config.Rate), s.config.Burst)
+		s.limiters[sid] = l
+	}
+	return l.Allow()
+}
+
+// render 生成请求体，必须是合法JSON
+func render(tmpl *template.Template, ev *event.Event) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(ev)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, ev); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return buf.Bytes(), fmt.Errorf("template output is not valid json")
+	}
+	return buf.Bytes(), nil
+}
+
+// toJSON 模板函数，输出JSON
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// run 依次发送队列中的通知，关闭后剩余通知写入死信
+func (s *Sink) run(ep *endpoint) {
+	for {
+		select {
+		case n := <-ep.queue:
+			s.deliver(ep, n)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case n := <-ep.queue:
+					s.deadLetter(ep, n.event, n.body, 0, errClosed)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver 发送通知，网络错误、5xx和429退避重试
+func (s *Sink) deliver(ep *endpoint, n *notification) {
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ep, n)
+		if err == nil {
+			ep.sent.Inc()
+			ep.mu.Lock()
+			ep.lastSuccess = time.Now()
+			ep.mu.Unlock()
+			return
+		}
+		ep.mu.Lock()
+		ep.lastErr = err
+		ep.lastErrAt = time.Now()
+		ep.mu.Unlock()
+		if !retry || attempt > s.config.RetryMax {
+			s.deadLetter(ep, n.event, n.body, attempt, err)
+			return
+		}
+		d := backoff.Delay(millisecond(s.config.BackoffBaseDelay), millisecond(s.config.BackoffMaxDelay), attempt-1)
+		select {
+		case <-time.After(d):
+		case <-s.ctx.Done():
+			s.deadLetter(ep, n.event, n.body, attempt, err)
+			return
+		}
+	}
+}
+
+// post 发送一次请求，返回是否可重试
+func (s *Sink) post(ep *endpoint, n *notification) (bool, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, ep.url, bytes.NewReader(n.body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, n.event.Type)
+	req.Header.Set(HeaderTimestamp, ts)
+	if s.config.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(s.config.Secret, ts, n.body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook %s response %s", ep.url, resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// Sign - 计算签名，接收方按相同方式校验
+// @param secret string 密钥
+// @param timestamp string X-Arc-Timestamp 请求头
+// @param body []byte 请求体
+// @return string X-Arc-Signature 请求头
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetter 记录无法送达的通知
+func (s *Sink) deadLetter(ep *endpoint, ev *event.Event, body []byte, attempts int, err error) {
+	ep.failed.Inc()
+	r := &Record{
+		Time:     time.Now(),
+		URL:      ep.url,
+		Type:     ev.Type,
+		SID:      ev.SID,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Body:     string(body),
+	}
+	if werr := s.dead.write(r); werr != nil {
+		ep.mu.Lock()
+		ep.lastErr = fmt.Errorf("dead letter: %w", werr)
+		ep.lastErrAt = time.Now()
+		ep.mu.Unlock()
+	}
+}
+
+// Flush - 后台协程持续发送
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Close - 停止发送，未送达的通知写入死信
+func (s *Sink) Close() error {
+	s.queueMu.Lock()
+	if s.closed {
+		s.queueMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.queueMu.Unlock()
+	s.cancel()
+	s.wg.Wait()
+	return s.dead.close()
+}
+
+// Health - 各地址最近一次失败之后有成功发送时健康
+func (s *Sink) Health() sink.Health {
+	var sent, failed int64
+	var unhealthy []string
+	for _, ep := range s.endpoints {
+		sent += ep.sent.Load()
+		failed += ep.failed.Load()
+		ep.mu.Lock()
+		if ep.lastErr != nil && !ep.lastSuccess.After(ep.lastErrAt) {
+			unhealthy = append(unhealthy, ep.lastErr.Error())
+		}
+		ep.mu.Unlock()
+	}
+	if len(unhealthy) > 0 {
+		return sink.Health{Healthy: false, Message: strings.Join(unhealthy, "; ")}
+	}
+	return sink.Health{Healthy: true, Message: fmt.Sprintf("sent %d, failed %d, rate limited %d", sent, failed, s.limited.Load())}
+}
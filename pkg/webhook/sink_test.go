@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/kiga-hub/arc-consumer/pkg/event"
+)
+
+// receiver 记录收到的通知，按顺序返回状态码
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	r.headers = append(r.headers, req.Header.Clone())
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func testConfig(t *testing.T, url string) *Config {
+	SetDefaultConfig()
+	cfg := GetConfig()
+	cfg.Endpoints = []Endpoint{{URL: url}}
+	cfg.BackoffBaseDelay = 10
+	cfg.BackoffMaxDelay = 20
+	cfg.RetryMax = 2
+	cfg.Rate = 0
+	cfg.DeadLetter = filepath.Join(t.TempDir(), "dead.jsonl")
+	return cfg
+}
+
+// readDead 读取死信记录
+func readDead(t *testing.T, path string) []*Record {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var list []*Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		r := &Record{}
+		if err := json.Unmarshal(sc.Bytes(), r); err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, r)
+	}
+	return list
+}
+
+func waitCount(t *testing.T, r *receiver, n int) {
+	deadline := time.Now().Add(3 * time.Second)
+	for r.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d requests, got %d", n, r.count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.Secret = "s3cret"
+	cfg.Template = `{"text":"arc on {{.SID}}","event":{{json .}}}`
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeSensorOnline, SID: "94C96000C248"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeArc, SID: "94C96000C248", Timestamp: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 首次503重试成功，非arc类型不通知
+	waitCount(t, r, 2)
+	r.mu.Lock()
+	body, h := r.bodies[1], r.headers[1]
+	r.mu.Unlock()
+	if body != `{"text":"arc on 94C96000C248","event":{"type":"arc","sid":"94C96000C248","timestamp":5}}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+	if h.Get(HeaderEvent) != event.TypeArc || h.Get(HeaderSignature) != Sign("s3cret", h.Get(HeaderTimestamp), []byte(body)) {
+		t.Fatalf("unexpected headers %v", h)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.Health(); !h.Healthy {
+		t.Fatalf("unexpected health %+v", h)
+	}
+	if _, err := os.Stat(cfg.DeadLetter); !os.IsNotExist(err) {
+		t.Fatal("unexpected dead letter")
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusBadRequest, 500, 500, 500}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 400 不重试，500 重试 retry_max 次
+	for i := int64(0); i < 2; i++ {
+		if err := s.WriteEvent(&event.Event{Type: event.TypeArc, SID: "94C96000C248", Timestamp: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitCount(t, r, 4)
+	time.Sleep(50 * time.Millisecond)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dead := readDead(t, cfg.DeadLetter)
+	if len(dead) != 2 || dead[0].Attempts != 1 || dead[1].Attempts != 3 || dead[1].Body == "" {
+		t.Fatalf("unexpected dead letters %+v", dead)
+	}
+	if h := s.Health(); h.Healthy {
+		t.Fatal("expected unhealthy")
+	}
+	if err := s.WriteEvent(&event.Event{Type: event.TypeArc}); err != errClosed {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+}
+
+func TestWebhookCloseDuringWrite(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.BufferSize = 10000
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 关闭前入队的通知送达或写入死信，关闭后拒绝
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int64(0); i < 500; i++ {
+				err := s.WriteEvent(&event.Event{Type: event.TypeArc, SID: "94C96000C248", Timestamp: i})
+				if err == nil {
+					accepted.Inc()
+				} else if err != errClosed {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	got := int64(r.count())
+	if _, err := os.Stat(cfg.DeadLetter); err == nil {
+		got += int64(len(readDead(t, cfg.DeadLetter)))
+	}
+	if got < accepted.Load() {
+		t.Fatalf("lost notifications: accepted %d, delivered or dead %d", accepted.Load(), got)
+	}
+}
+
+func TestWebhookRateLimit(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cfg := testConfig(t, srv.URL)
+	cfg.Rate = 0.001
+	cfg.Burst = 2
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 0; i < 5; i++ {
+		for _, sid := range []string{"94C96000C248", "94C96000C249"} {
+			if err := s.WriteEvent(&event.Event{Type: event.TypeArc, SID: sid}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	waitCount(t, r, 4)
+	time.Sleep(50 * time.Millisecond)
+	if n := r.count(); n != 4 || s.limited.Load() != 6 {
+		t.Fatalf("got %d requests, %d limited", n, s.limited.Load())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := testConfig(t, "http://localhost")
+	cfg.Template = "{{.Missing"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected template error")
+	}
+	cfg = testConfig(t, "http://localhost")
+	cfg.Endpoints = nil
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected endpoints error")
+	}
+}